@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+
+	"github.com/spf13/cobra"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// benchNetwork is reserved for network device benchmarking (RFC 2544), so test routes never shadow real traffic
+var benchNetwork = net.IPNet{IP: net.IPv4(198, 18, 0, 0).To4(), Mask: net.CIDRMask(15, 32)}
+
+var (
+	benchRoutes  int
+	benchGateway string
+)
+
+// runBench adds and then deletes host routes in the benchmarking range and reports throughput
+func runBench(_ *cobra.Command, _ []string) {
+	logLevel := "error"
+	if verboseMode {
+		logLevel = "debug"
+	}
+
+	cfg := newConfig()
+	log := logger.New(logLevel)
+
+	if os.Getuid() != 0 {
+		fmt.Fprintln(os.Stderr, "❌ Root privileges required for route operations")
+		os.Exit(1)
+	}
+
+	size, _ := benchNetwork.Mask.Size()
+	if capacity := 1 << (32 - size); benchRoutes <= 0 || benchRoutes > capacity {
+		fmt.Fprintf(os.Stderr, "❌ --routes must be between 1 and %d\n", capacity)
+		os.Exit(1)
+	}
+
+	rm, err := routing.NewPlatformRouteManager(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create route manager: %v\n", err)
+		os.Exit(1)
+	}
+	defer rm.Close()
+
+	uplink := types.Uplink{Gateway: net.ParseIP(benchGateway)}
+	if uplink.Gateway == nil {
+		uplink.Gateway, uplink.Interface, err = rm.GetPhysicalGateway(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to get default gateway: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	routes := benchRouteSet(benchRoutes, uplink)
+	fmt.Printf("Benchmarking %d routes via %s (concurrency %d, adaptive %t, batch size %d)\n",
+		len(routes), uplink, cfg.ConcurrencyLimit, cfg.AdaptiveConcurrency, cfg.BatchSize)
+
+	// Ctrl-C stops adding; the delete pass still runs so no test routes are left behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	added, err := rm.BatchAddRoutes(ctx, routes, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Add failed: %v\n", err)
+	} else {
+		printBenchResult(added)
+	}
+
+	deleted, err := rm.BatchDeleteRoutes(context.Background(), routes, log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Delete failed: %v\n", err)
+		os.Exit(1)
+	}
+	printBenchResult(deleted)
+}
+
+// benchRouteSet builds n host routes from the start of the benchmarking range
+func benchRouteSet(n int, uplink types.Uplink) []*types.Route {
+	base := binary.BigEndian.Uint32(benchNetwork.IP.To4())
+	routes := make([]*types.Route, 0, n)
+	for i := 0; i < n; i++ {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, base+uint32(i))
+		routes = append(routes, uplink.Route(net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}))
+	}
+	return routes
+}
+
+// printBenchResult prints the throughput and outcome counts of one pass
+func printBenchResult(result *types.BatchResult) {
+	var rate float64
+	if seconds := result.Duration.Seconds(); seconds > 0 {
+		rate = float64(result.Succeeded+result.Skipped) / seconds
+	}
+
+	fmt.Printf("%-6s %6d routes in %8.3fs  %9.1f routes/s  (succeeded %d, skipped %d, failed %d)\n",
+		result.Action, result.Total, result.Duration.Seconds(), rate, result.Succeeded, result.Skipped, result.Failed)
+	for errorType, failures := range result.Failures {
+		fmt.Printf("       %s: %d (e.g. %v)\n", errorType, len(failures), failures[0].Err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesleywu/smart-route/internal/control"
+	"github.com/wesleywu/smart-route/internal/daemon"
+	"github.com/wesleywu/smart-route/internal/history"
+)
+
+var (
+	eventsFollow bool
+	eventsSince  string
+	eventsJSON   bool
+)
+
+// runEvents prints the event history of the running daemon
+func runEvents(_ *cobra.Command, _ []string) {
+	since, err := parseSince(eventsSince, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid --since: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	args := daemon.EventsArgs{Since: since, Follow: eventsFollow}
+	err = control.Call(ctx, daemon.ControlSocketPath(newConfig()), daemon.CommandEvents, args, func(result json.RawMessage) error {
+		if eventsJSON {
+			fmt.Println(string(result))
+			return nil
+		}
+		var record history.Record
+		if err := json.Unmarshal(result, &record); err != nil {
+			return err
+		}
+		fmt.Println(formatRecord(record))
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseSince accepts a duration before now, e.g. "1h", or an RFC 3339 time; empty means everything
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration nor an RFC 3339 time", value)
+	}
+	return t, nil
+}
+
+// formatRecord renders a history record as a single line
+func formatRecord(record history.Record) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %-5s  %-24s", record.Time.Local().Format("2006-01-02 15:04:05.000"), record.Kind, record.Type)
+
+	vpn := "off"
+	if record.VPNConnected {
+		vpn = "on (" + record.VPNInterface + ")"
+	}
+	fmt.Fprintf(&b, "  vpn=%s", vpn)
+	if record.PhysicalInterface != "" {
+		gateway := record.PhysicalGateway
+		if gateway == "" {
+			gateway = "none"
+		}
+		fmt.Fprintf(&b, " uplink=%s via %s", record.PhysicalInterface, gateway)
+	}
+
+	if record.Kind == history.KindApply {
+		if record.VerifyOnly {
+			b.WriteString(" verify")
+		}
+		fmt.Fprintf(&b, " routes=+%d/-%d took=%s", record.RoutesAdded, record.RoutesRemoved, record.Duration.Round(time.Millisecond))
+	}
+	if record.Error != "" {
+		fmt.Fprintf(&b, " error=%q", record.Error)
+	}
+	return b.String()
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/control"
 	"github.com/wesleywu/smart-route/internal/daemon"
 	"github.com/wesleywu/smart-route/internal/logger"
 	"github.com/wesleywu/smart-route/internal/routing"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+	"github.com/wesleywu/smart-route/internal/routing/types"
 )
 
 var (
@@ -22,8 +30,30 @@ var (
 	verboseMode bool  
 	routeFile  string
 	dnsFile    string
+	backend    string
+	hookDir    string
+	webhooks   []string
+	historyFile   string
+	controlSocket string
+	recordFile    string
+	profilesFile  string
+	uplinkPriority []string
+	uplinkLists    []string
+
+	// Direct path health flags, defaulting to the configuration defaults
+	pathHealth              bool
+	pathHealthTargets       []string
+	pathHealthInterval      time.Duration
+	pathHealthMaxLatency    time.Duration
+	pathHealthMaxLoss       float64
+	pathHealthDegradeRounds int
+	pathHealthRecoverRounds int
 )
 
+// webhookSecretEnv names the environment variable holding the webhook signing key,
+// kept off the command line so it does not show up in the process list
+const webhookSecretEnv = "SMARTROUTE_WEBHOOK_SECRET"
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "smartroute",
@@ -74,10 +104,80 @@ func main() {
 		Run:   testConfiguration,
 	}
 
+	eventsCmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show the daemon event history",
+		Long:  `Show the network events and route applies recorded by the running daemon, read through its control socket.`,
+		Run:   runEvents,
+	}
+
+	triggerCmd := &cobra.Command{
+		Use:   "trigger",
+		Short: "Make the daemon check for network changes",
+		Long:  `Make the running daemon check for network changes now, or report a network state to it, e.g. from a VPN client's up/down script.`,
+		Run:   runTrigger,
+	}
+
+	pauseCmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause split routing",
+		Long:  `Make the running daemon withdraw the managed routes so that all traffic goes through the VPN, until resumed or until the --for duration expires. The pause survives daemon restarts.`,
+		Run:   runPause,
+	}
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume split routing",
+		Long:  `Make the running daemon re-apply the managed routes after a pause.`,
+		Run:   runResume,
+	}
+
+	replayCmd := &cobra.Command{
+		Use:   "replay <trace.jsonl>",
+		Short: "Replay a recorded network trace",
+		Long:  `Run the observations of a trace recorded with daemon --record through the monitor and the service against a fake route manager, and check that the same decisions are made.`,
+		Args:  cobra.ExactArgs(1),
+		Run:   runReplay,
+	}
+
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Benchmark route programming throughput",
+		Long:  `Add and delete test routes in the 198.18.0.0/15 benchmarking range and report throughput.`,
+		Run:   runBench,
+	}
+	daemonCmd.Flags().StringVar(&hookDir, "hook-dir", "", "Directory with one subdirectory of hook executables per event name, e.g. VPNConnected or ApplyFailed")
+	daemonCmd.Flags().StringVar(&historyFile, "history-file", "", "JSONL file to persist the event history to (kept in memory only by default)")
+	daemonCmd.Flags().StringVar(&profilesFile, "profiles", "", "JSON file with per-network profiles selected by gateway MAC, subnet, interface or DHCP domain")
+	daemonCmd.Flags().StringVar(&recordFile, "record", "", "JSONL file to record every network observation and decision to, for replay")
+	defaults := config.NewConfig()
+	daemonCmd.Flags().BoolVar(&pathHealth, "path-health", false, "Probe targets inside the managed networks directly and through the VPN, and withdraw direct routes that perform worse")
+	daemonCmd.Flags().StringArrayVar(&pathHealthTargets, "path-health-target", defaults.PathHealthTargets, "TCP endpoint as ip:port to probe for path health (repeatable)")
+	daemonCmd.Flags().DurationVar(&pathHealthInterval, "path-health-interval", defaults.PathHealthInterval, "Time between path health rounds")
+	daemonCmd.Flags().DurationVar(&pathHealthMaxLatency, "path-health-max-latency", defaults.PathHealthMaxLatency, "Direct path latency above which a target is degraded")
+	daemonCmd.Flags().Float64Var(&pathHealthMaxLoss, "path-health-max-loss", defaults.PathHealthMaxLoss, "Fraction of failed direct connects above which a target is degraded")
+	daemonCmd.Flags().IntVar(&pathHealthDegradeRounds, "path-health-degrade-rounds", defaults.PathHealthDegradeRounds, "Consecutive bad rounds before the direct path to a target is withdrawn")
+	daemonCmd.Flags().IntVar(&pathHealthRecoverRounds, "path-health-recover-rounds", defaults.PathHealthRecoverRounds, "Consecutive good rounds before the direct path to a target is restored")
+	daemonCmd.Flags().StringArrayVar(&webhooks, "webhook", nil, "URL to POST state transition notifications to (repeatable), signed with $"+webhookSecretEnv)
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "Keep printing new events as they happen")
+	eventsCmd.Flags().StringVar(&eventsSince, "since", "", "Only show events since a duration ago (e.g. 1h) or an RFC 3339 time")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Print the raw JSON records")
+	triggerCmd.Flags().StringVar(&triggerPhysicalGateway, "physical-gateway", "", "Gateway of the physical uplink in the reported state")
+	triggerCmd.Flags().StringVar(&triggerPhysicalInterface, "physical-interface", "", "Physical uplink interface in the reported state")
+	triggerCmd.Flags().StringVar(&triggerDefaultGateway, "default-gateway", "", "Default route gateway in the reported state")
+	triggerCmd.Flags().StringVar(&triggerDefaultInterface, "default-interface", "", "Default route interface in the reported state, e.g. utun3 while the VPN is up")
+	pauseCmd.Flags().DurationVar(&pauseFor, "for", 0, "Resume automatically after this long, e.g. 30m (pauses until resumed by default)")
+	benchCmd.Flags().IntVar(&benchRoutes, "routes", 1000, "Number of test routes to add and delete")
+	benchCmd.Flags().StringVar(&benchGateway, "gateway", "", "Gateway for the test routes (defaults to the physical gateway)")
+
 	rootCmd.PersistentFlags().BoolVarP(&silentMode, "silent", "s", false, "Silent mode (no output)")
 	rootCmd.PersistentFlags().BoolVarP(&verboseMode, "verbose", "v", false, "Verbose mode (debug level logging)")
 	rootCmd.PersistentFlags().StringVar(&routeFile, "route-file", "", "External routes file path (defaults to embedded data)")
 	rootCmd.PersistentFlags().StringVar(&dnsFile, "dns-file", "", "External DNS file path (defaults to embedded data)")
+	rootCmd.PersistentFlags().StringVar(&controlSocket, "socket", "", "Control socket of the daemon (defaults to the platform location)")
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", "", "Route backend to use (defaults to the best available, see version)")
+	rootCmd.PersistentFlags().StringSliceVar(&uplinkPriority, "uplink-priority", nil, "Physical interfaces in order of preference, e.g. eth0,wlan0 (unlisted interfaces follow)")
+	rootCmd.PersistentFlags().StringArrayVar(&uplinkLists, "uplink-list", nil, "Route a list through specific uplinks, as name=route-file:interface[,interface...] (repeatable); falls back to the preferred uplink")
 
 	rootCmd.AddCommand(daemonCmd)
 	rootCmd.AddCommand(installCmd)
@@ -85,6 +185,12 @@ func main() {
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(triggerCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(benchCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -92,6 +198,58 @@ func main() {
 	}
 }
 
+// newConfig creates the configuration with command line overrides applied
+func newConfig() *config.Config {
+	cfg := config.NewConfig()
+	if backend != "" {
+		cfg.RouteBackend = backend
+	}
+	if hookDir != "" {
+		cfg.HookDir = hookDir
+	}
+	if historyFile != "" {
+		cfg.EventHistoryFile = historyFile
+	}
+	if controlSocket != "" {
+		cfg.ControlSocket = controlSocket
+	}
+	if recordFile != "" {
+		cfg.TraceFile = recordFile
+	}
+	if profilesFile != "" {
+		cfg.ProfilesFile = profilesFile
+	}
+	if len(uplinkPriority) > 0 {
+		cfg.UplinkPriority = uplinkPriority
+	}
+	for _, spec := range uplinkLists {
+		list, err := config.ParseUplinkList(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		cfg.UplinkLists = append(cfg.UplinkLists, list)
+	}
+	if pathHealth {
+		if pathHealthInterval <= 0 || pathHealthMaxLoss < 0 || pathHealthMaxLoss > 1 {
+			fmt.Fprintf(os.Stderr, "❌ invalid path health settings: the interval must be positive and the max loss between 0 and 1\n")
+			os.Exit(1)
+		}
+		cfg.PathHealth = true
+		cfg.PathHealthTargets = pathHealthTargets
+		cfg.PathHealthInterval = pathHealthInterval
+		cfg.PathHealthMaxLatency = pathHealthMaxLatency
+		cfg.PathHealthMaxLoss = pathHealthMaxLoss
+		cfg.PathHealthDegradeRounds = pathHealthDegradeRounds
+		cfg.PathHealthRecoverRounds = pathHealthRecoverRounds
+	}
+	if len(webhooks) > 0 {
+		cfg.WebhookURLs = webhooks
+		cfg.WebhookSecret = os.Getenv(webhookSecretEnv)
+	}
+	return cfg
+}
+
 func runOnce(_ *cobra.Command, _ []string) {
 	// Determine log level based on command line flags
 	logLevel := "info"
@@ -101,7 +259,7 @@ func runOnce(_ *cobra.Command, _ []string) {
 		logLevel = "error"
 	}
 
-	cfg := config.NewConfig()
+	cfg := newConfig()
 
 	log := logger.New(logLevel)
 	log.Info("Route setup started", "version", version)
@@ -115,7 +273,7 @@ func runOnce(_ *cobra.Command, _ []string) {
 	log.Info("Configuration loaded", "ip_set_size", ipSet.Size())
 
 	// Create platform specific route manager
-	rm, err := routing.NewPlatformRouteManager(cfg.ConcurrencyLimit, cfg.RetryAttempts)
+	rm, err := routing.NewPlatformRouteManager(cfg)
 	if err != nil {
 		log.Error("Failed to create route manager", "error", err)
 		os.Exit(1)
@@ -128,9 +286,21 @@ func runOnce(_ *cobra.Command, _ []string) {
 		log.Error("Failed to create route switch", "error", err)
 		os.Exit(1)
 	}
+	routeSwitch.SetRetryPolicy(batch.NewRetryPolicy(cfg))
+	if cfg.GatewayProbe {
+		routeSwitch.SetGatewayProber(routing.NewGatewayProber(cfg, log))
+	}
+	if len(cfg.UplinkLists) > 0 {
+		assignments, err := routing.LoadUplinkAssignments(cfg.UplinkLists)
+		if err != nil {
+			log.Error("Failed to load uplink lists", "error", err)
+			os.Exit(1)
+		}
+		routeSwitch.SetUplinkAssignments(assignments)
+	}
 
 	// Always use the unified logic: setup routes only if VPN is connected, or clean up routes if VPN is not connected
-	if err := routeSwitch.InitRoutes(); err != nil {
+	if err := routeSwitch.InitRoutes(context.Background()); err != nil {
 		log.Error("Failed to setup routes", "error", err)
 		os.Exit(1)
 	}
@@ -147,7 +317,7 @@ func runDaemon(_ *cobra.Command, _ []string) {
 		logLevel = "error"
 	}
 
-	cfg := config.NewConfig()
+	cfg := newConfig()
 
 	log := logger.New(logLevel)
 
@@ -265,6 +435,60 @@ func showStatus(_ *cobra.Command, _ []string) {
 	}
 	fmt.Printf("Service status: %s\n", status)
 	fmt.Printf("Service installed: %t\n", service.IsInstalled())
+	showConnectionState()
+}
+
+// statusTransitions is the number of recent state transitions shown by status
+const statusTransitions = 5
+
+// showConnectionState prints the connection state of the running daemon, if it is reachable
+func showConnectionState() {
+	var status struct {
+		Connection struct {
+			State       string                   `json:"state"`
+			StateSince  time.Time                `json:"state_since"`
+			Transitions []daemon.StateTransition `json:"transitions"`
+		} `json:"connection"`
+		PausedUntil     time.Time `json:"paused_until"`
+		RouteOperations *struct {
+			Operations int64 `json:"route_operations"`
+			Failed     int64 `json:"failed_ops"`
+			Retries    int64 `json:"retries"`
+		} `json:"route_operations"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := control.Call(ctx, daemon.ControlSocketPath(newConfig()), daemon.CommandStatus, nil, func(result json.RawMessage) error {
+		return json.Unmarshal(result, &status)
+	})
+	if err != nil {
+		fmt.Printf("Connection state: unknown (%v)\n", err)
+		return
+	}
+
+	fmt.Printf("Connection state: %s since %s\n", status.Connection.State, status.Connection.StateSince.Format(time.RFC3339))
+	if status.Connection.State == daemon.StatePaused.String() {
+		if status.PausedUntil.IsZero() {
+			fmt.Println("Paused until resumed")
+		} else {
+			fmt.Printf("Paused until %s\n", status.PausedUntil.Format(time.RFC3339))
+		}
+	}
+	if ops := status.RouteOperations; ops != nil {
+		fmt.Printf("Route operations: %d, %d failed, %d retries\n", ops.Operations, ops.Failed, ops.Retries)
+	}
+	transitions := status.Connection.Transitions
+	if len(transitions) > statusTransitions {
+		transitions = transitions[len(transitions)-statusTransitions:]
+	}
+	for _, t := range transitions {
+		cause := t.Input
+		if t.Reason != "" {
+			cause += ", " + t.Reason
+		}
+		fmt.Printf("  %s  %s -> %s (%s)\n", t.Time.Format(time.RFC3339), t.From, t.To, cause)
+	}
 }
 
 func showVersion(_ *cobra.Command, _ []string) {
@@ -273,13 +497,37 @@ func showVersion(_ *cobra.Command, _ []string) {
 	fmt.Printf("Platform: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 
 	// Try to show current gateway information
-	rm, err := routing.NewPlatformRouteManager(1, 1) // minimal settings for quick check
+	// Use minimal settings for quick check
+	cfg := newConfig()
+	cfg.ConcurrencyLimit = 1
+	cfg.RetryAttempts = 1
+	rm, err := routing.NewPlatformRouteManager(cfg)
 	if err == nil {
 		defer rm.Close()
-		gateway, iface, err := rm.GetPhysicalGateway()
+		gateway, iface, err := rm.GetPhysicalGateway(context.Background())
 		if err == nil {
-			fmt.Printf("Current Gateway: %s (%s)\n", gateway.String(), iface)
+			fmt.Printf("Current Gateway: %s\n", describeGateway(gateway, iface))
 		}
+	} else {
+		fmt.Printf("Route backend: %v\n", err)
+	}
+
+	selected := ""
+	if reporter, ok := rm.(types.CapabilityReporter); ok {
+		selected = reporter.Backend()
+	}
+
+	fmt.Println("Route backends:")
+	for _, b := range platform.Backends() {
+		marker := " "
+		if b.Name == selected {
+			marker = "*"
+		}
+		status := "available"
+		if !b.IsAvailable() {
+			status = "unavailable"
+		}
+		fmt.Printf(" %s %-13s %-11s [%s] %s\n", marker, b.Name, status, b.Capabilities, b.Description)
 	}
 }
 
@@ -292,7 +540,7 @@ func testConfiguration(_ *cobra.Command, _ []string) {
 		logLevel = "error"
 	}
 
-	cfg := config.NewConfig()
+	cfg := newConfig()
 
 	log := logger.New(logLevel)
 	log.Debug("Starting configuration test")
@@ -306,20 +554,20 @@ func testConfiguration(_ *cobra.Command, _ []string) {
 	log.Debug("Chinese routes loading details", "file", routeFile, "networks", ipSet.Size())
 	fmt.Printf("✅ Chinese routes loaded: %d networks\n", ipSet.Size())
 
-	rm, err := routing.NewPlatformRouteManager(cfg.ConcurrencyLimit, cfg.RetryAttempts)
+	rm, err := routing.NewPlatformRouteManager(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to create route manager: %v\n", err)
 		os.Exit(1)
 	}
 	defer rm.Close()
 
-	gateway, iface, err := rm.GetPhysicalGateway()
+	gateway, iface, err := rm.GetPhysicalGateway(context.Background())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "❌ Failed to get default gateway: %v\n", err)
 		os.Exit(1)
 	}
 	log.Debug("Gateway detection details", "gateway", gateway.String(), "interface", iface)
-	fmt.Printf("✅ Default gateway: %s (%s)\n", gateway.String(), iface)
+	fmt.Printf("✅ Default gateway: %s\n", describeGateway(gateway, iface))
 
 	if os.Getuid() != 0 {
 		fmt.Println("⚠️  Root privileges required for route operations")
@@ -347,3 +595,11 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(destFile, sourceFile)
 	return err
 }
+
+// describeGateway formats a detected physical gateway, which is nil for a point-to-point uplink
+func describeGateway(gateway net.IP, iface string) string {
+	if gateway == nil {
+		return fmt.Sprintf("none, point-to-point uplink %s", iface)
+	}
+	return fmt.Sprintf("%s (%s)", gateway.String(), iface)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesleywu/smart-route/internal/control"
+	"github.com/wesleywu/smart-route/internal/daemon"
+)
+
+var pauseFor time.Duration
+
+// runPause makes the running daemon withdraw the managed routes, so that all traffic goes through the VPN
+func runPause(_ *cobra.Command, _ []string) {
+	if pauseFor < 0 {
+		fmt.Fprintf(os.Stderr, "❌ --for must not be negative\n")
+		os.Exit(1)
+	}
+
+	reply := callPause(daemon.CommandPause, daemon.PauseArgs{Duration: pauseFor})
+	if silentMode {
+		return
+	}
+	if reply.Until.IsZero() {
+		fmt.Println("⏸️  Split routing paused until resumed")
+	} else {
+		fmt.Printf("⏸️  Split routing paused until %s\n", reply.Until.Local().Format(time.DateTime))
+	}
+}
+
+// runResume makes the running daemon re-apply the managed routes after a pause
+func runResume(_ *cobra.Command, _ []string) {
+	callPause(daemon.CommandResume, nil)
+	if !silentMode {
+		fmt.Println("▶️  Split routing resumed")
+	}
+}
+
+// callPause sends a pause or resume command to the daemon and exits on failure
+func callPause(command string, args interface{}) daemon.PauseReply {
+	var reply daemon.PauseReply
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := control.Call(ctx, daemon.ControlSocketPath(newConfig()), command, args, func(result json.RawMessage) error {
+		return json.Unmarshal(result, &reply)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	return reply
+}
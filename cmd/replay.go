@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesleywu/smart-route/internal/daemon"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// replayContext is the number of decisions shown before the first mismatch
+const replayContext = 3
+
+// runReplay replays a recorded trace against the fake backend and compares the decisions made
+func runReplay(_ *cobra.Command, args []string) {
+	logLevel := "error"
+	if verboseMode {
+		logLevel = "debug"
+	}
+	log := logger.New(logLevel)
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to open trace: %v\n", err)
+		os.Exit(1)
+	}
+	entries, err := routing.ReadTrace(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to read trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if !silentMode && len(entries) > 0 {
+		fmt.Printf("Replaying %d trace entries over %s...\n", len(entries), entries[len(entries)-1].Offset.Round(time.Millisecond))
+	}
+	result, err := daemon.Replay(ctx, newConfig(), log, entries, routeFile, dnsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Replay failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	mismatch := result.Mismatch()
+	if mismatch < 0 {
+		if !silentMode {
+			fmt.Printf("✅ %d inputs replayed, all %d decisions match the recording\n", result.Inputs, len(result.Recorded))
+		}
+		return
+	}
+
+	fmt.Printf("❌ Decision %d differs from the recording (%d recorded, %d replayed)\n",
+		mismatch+1, len(result.Recorded), len(result.Replayed))
+	for i := max(mismatch-replayContext, 0); i < mismatch; i++ {
+		fmt.Printf("    %s\n", result.Recorded[i])
+	}
+	fmt.Printf("  recorded: %s\n", decisionAt(result.Recorded, mismatch))
+	fmt.Printf("  replayed: %s\n", decisionAt(result.Replayed, mismatch))
+	os.Exit(1)
+}
+
+// decisionAt returns the decision at index i, or a placeholder past the end
+func decisionAt(decisions []string, i int) string {
+	if i >= len(decisions) {
+		return "(none)"
+	}
+	return decisions[i]
+}
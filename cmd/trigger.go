@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesleywu/smart-route/internal/control"
+	"github.com/wesleywu/smart-route/internal/daemon"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+var (
+	triggerPhysicalGateway   string
+	triggerPhysicalInterface string
+	triggerDefaultGateway    string
+	triggerDefaultInterface  string
+)
+
+// runTrigger makes the running daemon check for network changes now, or report a given state,
+// e.g. from a VPN client's up/down script
+func runTrigger(_ *cobra.Command, _ []string) {
+	var args daemon.TriggerArgs
+	if triggerPhysicalInterface != "" || triggerDefaultInterface != "" {
+		state, err := triggerState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		args.State = state
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := control.Call(ctx, daemon.ControlSocketPath(newConfig()), daemon.CommandTrigger, args, func(json.RawMessage) error {
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	if !silentMode {
+		fmt.Println("✅ Network change check triggered")
+	}
+}
+
+// triggerState builds the reported state from the command line flags
+func triggerState() (*routing.NetworkState, error) {
+	if triggerPhysicalInterface == "" || triggerDefaultInterface == "" {
+		return nil, fmt.Errorf("--physical-interface and --default-interface are both needed to report a state")
+	}
+
+	state := &routing.NetworkState{
+		PhysicalInterface: triggerPhysicalInterface,
+		DefaultInterface:  triggerDefaultInterface,
+	}
+	for _, gateway := range []struct {
+		flag  string
+		value string
+		ip    *net.IP
+	}{
+		{"--physical-gateway", triggerPhysicalGateway, &state.PhysicalGateway},
+		{"--default-gateway", triggerDefaultGateway, &state.DefaultGateway},
+	} {
+		if gateway.value == "" {
+			continue
+		}
+		if *gateway.ip = net.ParseIP(gateway.value); *gateway.ip == nil {
+			return nil, fmt.Errorf("invalid %s %q", gateway.flag, gateway.value)
+		}
+	}
+	return state, nil
+}
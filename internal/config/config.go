@@ -11,9 +11,93 @@ type Config struct {
 	RetryAttempts   int
 	RouteTimeout    time.Duration
 
+	// 重试策略配置 - 硬编码默认值
+	RetryBaseDelay  time.Duration
+	RetryMaxDelay   time.Duration
+	RetryJitter     float64  // fraction of each delay randomized, 0 disables jitter
+	RetryableErrors []string // route error type names, e.g. "Network"
+
 	// 性能配置 - 硬编码默认值
 	ConcurrencyLimit int
 	BatchSize        int
+	BatchTimeout     time.Duration
+
+	// 路由后端配置 - 硬编码默认值
+	RouteBackend string // registered backend name, e.g. "ip-batch"; empty selects the best available one
+
+	// 自适应并发配置 - 硬编码默认值
+	AdaptiveConcurrency bool
+	MinConcurrency      int // lower bound for the adaptive worker count, ConcurrencyLimit is the upper bound
+
+	// 漂移修复配置 - 硬编码默认值
+	ReconcileInterval time.Duration
+	ReconcileJitter   time.Duration
+	TimeJumpThreshold time.Duration
+
+	// 网关探测配置 - 硬编码默认值
+	GatewayProbe         bool          // confirm the physical gateway answers before routing through it
+	GatewayProbeTimeout  time.Duration // per attempt
+	GatewayProbeAttempts int
+	GatewayProbeICMP     bool // also require an ICMP echo reply, not just a resolved neighbor entry
+
+	// 直连质量监控配置 - 硬编码默认值
+	PathHealth              bool
+	PathHealthInterval      time.Duration
+	PathHealthTargets       []string // "ip:port" TCP endpoints inside the managed networks
+	PathHealthSamples       int      // connects per target and path in each round
+	PathHealthTimeout       time.Duration
+	PathHealthMaxLatency    time.Duration
+	PathHealthMaxLoss       float64 // fraction of failed connects
+	PathHealthDegradeRounds int     // consecutive bad rounds before the direct path is withdrawn
+	PathHealthRecoverRounds int     // consecutive good rounds before it is restored
+	PathHealthWithdrawAll   bool    // withdraw all managed routes once every target is degraded
+
+	// 钩子脚本配置 - 硬编码默认值
+	Hooks           map[string][]string // executables keyed by event name, e.g. "VPNConnected" or "ApplyFailed"
+	HookDir         string              // holds one subdirectory of executables per event name
+	HookTimeout     time.Duration
+	HookConcurrency int
+
+	// Webhook通知配置 - 硬编码默认值
+	WebhookURLs           []string
+	WebhookSecret         string   // HMAC-SHA256 key for the signature header, empty disables signing
+	WebhookEvents         []string // event names to notify about, empty sends every event
+	WebhookTimeout        time.Duration
+	WebhookAttempts       int
+	WebhookRetryBaseDelay time.Duration
+	WebhookRetryMaxDelay  time.Duration
+	WebhookQueueSize      int // per URL, the oldest notification is dropped when full
+
+	// 事件历史配置 - 硬编码默认值
+	EventHistorySize     int    // events and apply results kept in memory
+	EventHistoryFile     string // JSONL file the history is appended to, empty keeps it in memory only
+	EventHistoryMaxBytes int64  // the file is rotated once it grows beyond this size
+	EventHistoryBackups  int
+
+	// 控制套接字配置 - 硬编码默认值
+	ControlSocket string // empty uses the platform default
+
+	// 事件追踪配置 - 硬编码默认值
+	TraceFile string // JSONL file to record monitor inputs and decisions to for replay, empty disables recording
+
+	// 暂停状态配置 - 硬编码默认值
+	PauseStateFile string // empty uses the platform default
+
+	// 网络配置档配置 - 硬编码默认值
+	ProfilesFile string    // JSON file of per-network profiles, replaces Profiles when set
+	Profiles     []Profile // the first matching profile applies, split routing is enabled on unmatched networks
+
+	// 上行链路配置 - 硬编码默认值
+	UplinkPriority []string     // physical interfaces in order of preference, e.g. wired before Wi-Fi; unlisted ones follow
+	UplinkLists    []UplinkList // named lists routed through specific uplinks instead of the preferred one
+
+	// 事件防抖配置 - 硬编码默认值
+	EventDebounce   map[string]time.Duration // keyed by event type name, e.g. "VPNConnected"
+	VPNLossHoldDown time.Duration
+	FlapWindow      time.Duration
+	FlapThreshold   int
+	FlapBackoffBase time.Duration
+	FlapBackoffMax  time.Duration
 }
 
 // NewConfig creates a new config with default values
@@ -23,7 +107,87 @@ func NewConfig() *Config {
 		MonitorInterval:  2 * time.Second,
 		RetryAttempts:    3,
 		RouteTimeout:     30 * time.Second,
+		RetryBaseDelay:   500 * time.Millisecond,
+		RetryMaxDelay:    10 * time.Second,
+		RetryJitter:      0.2,
+		RetryableErrors:  []string{"Network", "Timeout", "GatewayUnreachable"},
 		ConcurrencyLimit: 50,
 		BatchSize:        100,
+		BatchTimeout:     2 * time.Minute,
+
+		RouteBackend: "",
+
+		AdaptiveConcurrency: true,
+		MinConcurrency:      4,
+
+		ReconcileInterval: 5 * time.Minute,
+		ReconcileJitter:   30 * time.Second,
+		TimeJumpThreshold: 30 * time.Second,
+
+		GatewayProbe:         true,
+		GatewayProbeTimeout:  1 * time.Second,
+		GatewayProbeAttempts: 3,
+		GatewayProbeICMP:     false,
+
+		PathHealth:              false,
+		PathHealthInterval:      30 * time.Second,
+		PathHealthTargets:       []string{"223.5.5.5:53", "119.29.29.29:53"},
+		PathHealthSamples:       3,
+		PathHealthTimeout:       2 * time.Second,
+		PathHealthMaxLatency:    300 * time.Millisecond,
+		PathHealthMaxLoss:       0.3,
+		PathHealthDegradeRounds: 2,
+		PathHealthRecoverRounds: 3,
+		PathHealthWithdrawAll:   true,
+
+		Hooks:           map[string][]string{},
+		HookDir:         "",
+		HookTimeout:     30 * time.Second,
+		HookConcurrency: 4,
+
+		WebhookURLs:   nil,
+		WebhookSecret: "",
+		WebhookEvents: []string{
+			"VPNConnected",
+			"VPNDisconnected",
+			"PhysicalGatewayChanged",
+			"ApplyFailed",
+			"DriftRepaired",
+			"PollFallbackEnabled",
+		},
+		WebhookTimeout:        10 * time.Second,
+		WebhookAttempts:       5,
+		WebhookRetryBaseDelay: 1 * time.Second,
+		WebhookRetryMaxDelay:  1 * time.Minute,
+		WebhookQueueSize:      100,
+
+		EventHistorySize:     1000,
+		EventHistoryFile:     "",
+		EventHistoryMaxBytes: 10 * 1024 * 1024,
+		EventHistoryBackups:  3,
+
+		ControlSocket: "",
+
+		TraceFile: "",
+
+		PauseStateFile: "",
+
+		ProfilesFile: "",
+		Profiles:     nil,
+
+		UplinkPriority: nil,
+		UplinkLists:    nil,
+
+		EventDebounce: map[string]time.Duration{
+			"PhysicalGatewayChanged": 1 * time.Second,
+			"VPNConnected":           500 * time.Millisecond,
+			"VPNDisconnected":        0, // covered by VPNLossHoldDown
+			"NetworkAddressChanged":  500 * time.Millisecond,
+		},
+		VPNLossHoldDown: 5 * time.Second,
+		FlapWindow:      60 * time.Second,
+		FlapThreshold:   3,
+		FlapBackoffBase: 2 * time.Second,
+		FlapBackoffMax:  60 * time.Second,
 	}
 }
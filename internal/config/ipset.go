@@ -95,6 +95,31 @@ func (is *IPSet) Add(ipNet *net.IPNet) bool {
 	return true
 }
 
+// Merge adds every network of other to the set
+func (is *IPSet) Merge(other *IPSet) {
+	for hash, ipNet := range other.ipNets {
+		is.ipNets[hash] = ipNet
+	}
+}
+
+// Without returns a copy of the set without the networks that overlap any of excluded
+func (is *IPSet) Without(excluded []*net.IPNet) *IPSet {
+	result := NewIPSet()
+	for hash, ipNet := range is.ipNets {
+		overlaps := false
+		for _, exclusion := range excluded {
+			if ipNet.Contains(exclusion.IP) || exclusion.Contains(ipNet.IP) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			result.ipNets[hash] = ipNet
+		}
+	}
+	return result
+}
+
 // ContainsIPNet checks if the set contains a network
 func (is *IPSet) ContainsIPNet(ipNet net.IPNet) bool {
 	hash := hashIPNet(ipNet)
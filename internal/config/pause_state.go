@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// PauseState records that split routing was paused, so that a restarted daemon stays paused
+type PauseState struct {
+	Paused bool      `json:"paused"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"` // zero until resumed explicitly
+}
+
+// DefaultPauseStateFile returns the platform location of the pause state
+func DefaultPauseStateFile() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "smartroute", "pause.json")
+	}
+	return "/var/lib/smartroute/pause.json"
+}
+
+// LoadPauseState loads the pause state from a file; a missing file means not paused
+func LoadPauseState(stateFile string) (*PauseState, error) {
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &PauseState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read pause state: %w", err)
+	}
+
+	var state PauseState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse pause state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// Save saves the pause state to a file
+func (ps *PauseState) Save(stateFile string) error {
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(ps, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal pause state: %w", err)
+	}
+
+	if err := os.WriteFile(stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write pause state: %w", err)
+	}
+
+	return nil
+}
+
+// ClearPauseState removes the pause state file
+func ClearPauseState(stateFile string) error {
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove pause state: %w", err)
+	}
+	return nil
+}
+
+// Active reports whether the pause is still in effect at now
+func (ps *PauseState) Active(now time.Time) bool {
+	return ps.Paused && (ps.Until.IsZero() || now.Before(ps.Until))
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+)
+
+// ProfileMatch identifies the networks a profile applies to. Every criterion that is set
+// must match; a match without criteria applies to every network.
+type ProfileMatch struct {
+	GatewayMAC string `json:"gateway_mac,omitempty"` // hardware address of the physical gateway
+	Subnet     string `json:"subnet,omitempty"`      // CIDR holding the physical gateway or an interface address
+	Interface  string `json:"interface,omitempty"`   // name of the physical interface
+	DHCPDomain string `json:"dhcp_domain,omitempty"` // domain handed out by DHCP, case-insensitive
+}
+
+// Profile holds the split routing settings of the networks it matches
+type Profile struct {
+	Name      string       `json:"name"`
+	Match     ProfileMatch `json:"match"`
+	Enabled   *bool        `json:"enabled,omitempty"`    // nil means enabled
+	RouteFile string       `json:"route_file,omitempty"` // empty uses the daemon's route list
+	DNSFile   string       `json:"dns_file,omitempty"`   // empty uses the daemon's DNS list
+	Exclude   []string     `json:"exclude,omitempty"`    // CIDRs left to the VPN; overlapping managed networks are dropped
+}
+
+// profileFile is the layout of a profiles file
+type profileFile struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+// IsEnabled reports whether split routing is enabled on the profile's networks
+func (p Profile) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// Exclusions returns the parsed exclusions of the profile
+func (p Profile) Exclusions() ([]*net.IPNet, error) {
+	exclusions := make([]*net.IPNet, 0, len(p.Exclude))
+	for _, cidr := range p.Exclude {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: invalid exclusion %q: %w", p.Name, cidr, err)
+		}
+		exclusions = append(exclusions, network)
+	}
+	return exclusions, nil
+}
+
+// LoadProfiles loads the network profiles from a JSON file:
+//
+//	{"profiles": [{"name": "office", "match": {"gateway_mac": "00:11:22:33:44:55"}, "enabled": false}]}
+func LoadProfiles(file string) ([]Profile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+
+	var parsed profileFile
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	if err := ValidateProfiles(parsed.Profiles); err != nil {
+		return nil, err
+	}
+	return parsed.Profiles, nil
+}
+
+// ValidateProfiles checks that profiles have unique names and well-formed criteria and exclusions
+func ValidateProfiles(profiles []Profile) error {
+	names := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("profile without a name")
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("duplicate profile %q", profile.Name)
+		}
+		names[profile.Name] = true
+
+		if profile.Match.GatewayMAC != "" {
+			if _, err := net.ParseMAC(profile.Match.GatewayMAC); err != nil {
+				return fmt.Errorf("profile %q: invalid gateway MAC: %w", profile.Name, err)
+			}
+		}
+		if profile.Match.Subnet != "" {
+			if _, _, err := net.ParseCIDR(profile.Match.Subnet); err != nil {
+				return fmt.Errorf("profile %q: invalid subnet: %w", profile.Name, err)
+			}
+		}
+		if _, err := profile.Exclusions(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
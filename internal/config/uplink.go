@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UplinkList routes the networks of a route file through specific physical uplinks,
+// e.g. an education network list through a second ISP
+type UplinkList struct {
+	Name      string   `json:"name"`
+	RouteFile string   `json:"route_file"`
+	Uplinks   []string `json:"uplinks"` // interfaces in order of preference; the preferred uplink is used if none is up
+}
+
+// ParseUplinkList parses a list given as name=route-file:iface[,iface...]
+func ParseUplinkList(spec string) (UplinkList, error) {
+	name, rest, ok := strings.Cut(spec, "=")
+	if !ok {
+		return UplinkList{}, fmt.Errorf("invalid uplink list %q: expected name=route-file:interface[,interface...]", spec)
+	}
+	separator := strings.LastIndex(rest, ":")
+	if separator < 0 {
+		return UplinkList{}, fmt.Errorf("invalid uplink list %q: missing interfaces", spec)
+	}
+
+	list := UplinkList{Name: name, RouteFile: rest[:separator]}
+	for _, iface := range strings.Split(rest[separator+1:], ",") {
+		if iface = strings.TrimSpace(iface); iface != "" {
+			list.Uplinks = append(list.Uplinks, iface)
+		}
+	}
+	if err := ValidateUplinkLists([]UplinkList{list}); err != nil {
+		return UplinkList{}, err
+	}
+	return list, nil
+}
+
+// ValidateUplinkLists checks that uplink lists have unique names, a route file and at least one uplink
+func ValidateUplinkLists(lists []UplinkList) error {
+	names := make(map[string]bool, len(lists))
+	for _, list := range lists {
+		if list.Name == "" {
+			return fmt.Errorf("uplink list without a name")
+		}
+		if names[list.Name] {
+			return fmt.Errorf("duplicate uplink list %q", list.Name)
+		}
+		names[list.Name] = true
+
+		if list.RouteFile == "" {
+			return fmt.Errorf("uplink list %q: missing route file", list.Name)
+		}
+		if len(list.Uplinks) == 0 {
+			return fmt.Errorf("uplink list %q: missing uplinks", list.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+// Package control implements the local control socket through which CLI commands talk to the
+// running daemon. A client sends one JSON request line; the server answers with one or more JSON
+// reply lines and closes the connection, or keeps streaming until the client goes away.
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+)
+
+// Request is a command sent to the daemon
+type Request struct {
+	Command string          `json:"command"`
+	Args    json.RawMessage `json:"args,omitempty"`
+}
+
+// reply is one line written back to the client
+type reply struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Handler serves one command. It calls send for every result it streams back and returns when
+// done; ctx is cancelled when the client disconnects or the server stops.
+type Handler func(ctx context.Context, args json.RawMessage, send func(result interface{}) error) error
+
+// Server accepts control connections on a Unix domain socket
+type Server struct {
+	path     string
+	handlers map[string]Handler
+	logger   *logger.Logger
+	listener net.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewServer creates a server listening on path once started
+func NewServer(path string, log *logger.Logger) *Server {
+	return &Server{
+		path:     path,
+		handlers: make(map[string]Handler),
+		logger:   log,
+	}
+}
+
+// Handle registers the handler for a command; it must be called before Start
+func (s *Server) Handle(command string, handler Handler) {
+	s.handlers[command] = handler
+}
+
+// Start listens on the socket and serves connections until ctx is cancelled or Stop is called.
+// A socket left behind by a previous run is replaced.
+func (s *Server) Start(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale control socket: %w", err)
+	}
+	listener, err := listenSocket(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+
+	s.listener = listener
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.acceptLoop(ctx)
+	}()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	return nil
+}
+
+// Stop closes the socket and waits for the open connections to finish
+func (s *Server) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// acceptLoop serves every connection in its own goroutine
+func (s *Server) acceptLoop(ctx context.Context) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("control socket accept failed", "error", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(ctx, conn)
+		}()
+	}
+}
+
+// serve reads one request from conn and runs its handler
+func (s *Server) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.logger.Debug("failed to read control request", "error", err)
+		return
+	}
+
+	encoder := json.NewEncoder(conn)
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		_ = encoder.Encode(reply{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	handler, ok := s.handlers[req.Command]
+	if !ok {
+		_ = encoder.Encode(reply{Error: fmt.Sprintf("unknown command %q", req.Command)})
+		return
+	}
+
+	// The client sends nothing after its request, so a read returning means it went away
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_, _ = reader.ReadByte()
+		cancel()
+	}()
+
+	send := func(result interface{}) error {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(reply{Result: data})
+	}
+	s.logger.Debug("Control request", "command", req.Command)
+	if err := handler(ctx, req.Args, send); err != nil && ctx.Err() == nil {
+		_ = encoder.Encode(reply{Error: err.Error()})
+	}
+}
+
+// Call sends a command to the daemon listening on path and passes every result to onResult
+// until the daemon closes the connection or ctx is cancelled
+func Call(ctx context.Context, path, command string, args interface{}, onResult func(result json.RawMessage) error) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the daemon (is it running?): %w", err)
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	req := Request{Command: command}
+	if args != nil {
+		if req.Args, err = json.Marshal(args); err != nil {
+			return err
+		}
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send control request: %w", err)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r reply
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("invalid control reply: %w", err)
+		}
+		if r.Error != "" {
+			return errors.New(r.Error)
+		}
+		if err := onResult(r.Result); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+)
+
+func TestServerCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "control.sock")
+	server := NewServer(path, logger.New("error"))
+	server.Handle("count", func(_ context.Context, args json.RawMessage, send func(interface{}) error) error {
+		var n int
+		if err := json.Unmarshal(args, &n); err != nil {
+			return err
+		}
+		for i := 1; i <= n; i++ {
+			if err := send(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	server.Handle("fail", func(context.Context, json.RawMessage, func(interface{}) error) error {
+		return errors.New("boom")
+	})
+	server.Handle("stream", func(ctx context.Context, _ json.RawMessage, send func(interface{}) error) error {
+		if err := send("ready"); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer server.Stop()
+
+	t.Run("permissions", func(t *testing.T) {
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm() != 0o660 {
+			t.Errorf("Expected the socket to be created with mode 0660, got %v, %v", info, err)
+		}
+	})
+
+	t.Run("results", func(t *testing.T) {
+		var got []int
+		err := Call(ctx, path, "count", 3, func(result json.RawMessage) error {
+			var n int
+			json.Unmarshal(result, &n)
+			got = append(got, n)
+			return nil
+		})
+		if err != nil || len(got) != 3 || got[2] != 3 {
+			t.Errorf("Expected results 1..3, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		ignore := func(json.RawMessage) error { return nil }
+		if err := Call(ctx, path, "fail", nil, ignore); err == nil || err.Error() != "boom" {
+			t.Errorf("Expected the handler error, got %v", err)
+		}
+		if err := Call(ctx, path, "missing", nil, ignore); err == nil {
+			t.Error("Expected an error for an unknown command")
+		}
+	})
+
+	t.Run("client disconnect ends stream", func(t *testing.T) {
+		callCtx, callCancel := context.WithTimeout(ctx, 5*time.Second)
+		defer callCancel()
+		err := Call(callCtx, path, "stream", nil, func(json.RawMessage) error {
+			return errStop
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("Expected the stream to be stopped by the client, got %v", err)
+		}
+	})
+}
+
+var errStop = errors.New("stop")
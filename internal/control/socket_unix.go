@@ -0,0 +1,21 @@
+//go:build !windows
+
+package control
+
+import (
+	"net"
+	"syscall"
+)
+
+// DefaultSocketPath is where the daemon listens unless configured otherwise
+func DefaultSocketPath() string {
+	return "/var/run/smartroute.sock"
+}
+
+// listenSocket listens on the socket with access limited to root and the daemon's group.
+// The socket is created under a restrictive umask, so it is never open to others.
+func listenSocket(path string) (net.Listener, error) {
+	mask := syscall.Umask(0o117)
+	defer syscall.Umask(mask)
+	return net.Listen("unix", path)
+}
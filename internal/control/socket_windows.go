@@ -0,0 +1,23 @@
+//go:build windows
+
+package control
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// DefaultSocketPath is where the daemon listens unless configured otherwise
+func DefaultSocketPath() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "smartroute", "smartroute.sock")
+}
+
+// listenSocket listens on the socket, which inherits the ACL of its ProgramData directory
+func listenSocket(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,225 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// vpnFlapKey is the flap tracking key for VPN events; VPNDisconnected carries no interface name
+const vpnFlapKey = "vpn"
+
+// pendingEvent is an event waiting for its debounce window to elapse
+type pendingEvent struct {
+	timer *time.Timer
+	seq   uint64
+}
+
+// eventDebouncer delays network events so that bursts and flapping settle before routes are touched.
+// Each event type has its own debounce window and only the latest event of a type is delivered.
+// VPN loss is additionally held down: a reconnect within the hold-down cancels the pending cleanup.
+// Interfaces that flap repeatedly get an exponentially growing extra delay.
+type eventDebouncer struct {
+	windows  map[routing.EventType]time.Duration
+	holdDown time.Duration
+
+	flapWindow      time.Duration
+	flapThreshold   int
+	flapBackoffBase time.Duration
+	flapBackoffMax  time.Duration
+
+	logger *logger.Logger
+	out    chan routing.NetworkEvent
+	stop   chan struct{}
+
+	mutex   sync.Mutex
+	seq     uint64
+	pending map[routing.EventType]*pendingEvent
+	flaps   map[string][]time.Time
+	stopped bool
+}
+
+// newEventDebouncer creates a debouncer from the event timing settings in cfg
+func newEventDebouncer(cfg *config.Config, log *logger.Logger) *eventDebouncer {
+	windows := make(map[routing.EventType]time.Duration)
+	for _, eventType := range []routing.EventType{
+		routing.PhysicalGatewayChanged,
+		routing.VPNConnected,
+		routing.VPNDisconnected,
+		routing.NetworkInterfaceUp,
+		routing.NetworkInterfaceDown,
+		routing.NetworkAddressChanged,
+	} {
+		if window, ok := cfg.EventDebounce[eventType.String()]; ok {
+			windows[eventType] = window
+		}
+	}
+
+	return &eventDebouncer{
+		windows:         windows,
+		holdDown:        cfg.VPNLossHoldDown,
+		flapWindow:      cfg.FlapWindow,
+		flapThreshold:   cfg.FlapThreshold,
+		flapBackoffBase: cfg.FlapBackoffBase,
+		flapBackoffMax:  cfg.FlapBackoffMax,
+		logger:          log,
+		out:             make(chan routing.NetworkEvent, 100),
+		stop:            make(chan struct{}),
+		pending:         make(map[routing.EventType]*pendingEvent),
+		flaps:           make(map[string][]time.Time),
+	}
+}
+
+// Events returns the channel of settled events
+func (d *eventDebouncer) Events() <-chan routing.NetworkEvent {
+	return d.out
+}
+
+// Submit schedules an event for delivery once its debounce window has elapsed
+func (d *eventDebouncer) Submit(event routing.NetworkEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.stopped {
+		return
+	}
+
+	eventType := event.EventType
+	delay := d.windows[eventType]
+
+	switch eventType {
+	case routing.VPNConnected:
+		if d.cancelLocked(routing.VPNDisconnected) {
+			d.logger.Info("VPN reconnected within hold-down, keeping routes",
+				"vpn_interface", event.VPNInterface)
+		}
+	case routing.VPNDisconnected:
+		if d.cancelLocked(routing.VPNConnected) {
+			d.logger.Debug("VPN disconnected before connection settled, dropping pending VPNConnected")
+		}
+		if d.holdDown > delay {
+			delay = d.holdDown
+			d.logger.Debug("Holding down VPN loss before cleaning routes", "hold_down", d.holdDown)
+		}
+	}
+
+	if key := flapKey(event); key != "" {
+		if backoff := d.flapBackoffLocked(key, event.Timestamp); backoff > 0 {
+			delay += backoff
+			d.logger.Debug("Interface flapping, backing off",
+				"type", eventType.String(),
+				"key", key,
+				"backoff", backoff)
+		}
+	}
+
+	if d.cancelLocked(eventType) {
+		d.logger.Debug("Debouncing event, superseding pending one", "type", eventType.String(), "delay", delay)
+	} else if delay > 0 {
+		d.logger.Debug("Debouncing event", "type", eventType.String(), "delay", delay)
+	}
+
+	d.seq++
+	seq := d.seq
+	d.pending[eventType] = &pendingEvent{
+		seq:   seq,
+		timer: time.AfterFunc(delay, func() { d.fire(event, seq) }),
+	}
+}
+
+// Stop cancels all pending events
+func (d *eventDebouncer) Stop() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	for eventType := range d.pending {
+		d.cancelLocked(eventType)
+	}
+	close(d.stop)
+}
+
+// fire delivers an event whose window elapsed, unless it was superseded in the meantime
+func (d *eventDebouncer) fire(event routing.NetworkEvent, seq uint64) {
+	d.mutex.Lock()
+	current, ok := d.pending[event.EventType]
+	if !ok || current.seq != seq {
+		d.mutex.Unlock()
+		return
+	}
+	delete(d.pending, event.EventType)
+	d.mutex.Unlock()
+
+	select {
+	case d.out <- event:
+	case <-d.stop:
+	}
+}
+
+// cancelLocked cancels a pending event of the given type and reports whether there was one
+func (d *eventDebouncer) cancelLocked(eventType routing.EventType) bool {
+	pending, ok := d.pending[eventType]
+	if !ok {
+		return false
+	}
+	pending.timer.Stop()
+	delete(d.pending, eventType)
+	return true
+}
+
+// flapBackoffLocked records a state change for key and returns the extra delay it has earned
+func (d *eventDebouncer) flapBackoffLocked(key string, now time.Time) time.Duration {
+	if d.flapWindow <= 0 || d.flapThreshold <= 0 || d.flapBackoffBase <= 0 {
+		return 0
+	}
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	cutoff := now.Add(-d.flapWindow)
+	recent := d.flaps[key][:0]
+	for _, ts := range d.flaps[key] {
+		if ts.After(cutoff) {
+			recent = append(recent, ts)
+		}
+	}
+	recent = append(recent, now)
+	d.flaps[key] = recent
+
+	excess := len(recent) - d.flapThreshold
+	if excess <= 0 {
+		return 0
+	}
+
+	backoff := d.flapBackoffBase
+	for i := 1; i < excess; i++ {
+		backoff *= 2
+		if d.flapBackoffMax > 0 && backoff >= d.flapBackoffMax {
+			return d.flapBackoffMax
+		}
+	}
+	if d.flapBackoffMax > 0 && backoff > d.flapBackoffMax {
+		backoff = d.flapBackoffMax
+	}
+	return backoff
+}
+
+// flapKey returns the interface an event is attributed to for flap tracking, or "" for
+// events that do not change interface or gateway state and so are not flaps
+func flapKey(event routing.NetworkEvent) string {
+	switch event.EventType {
+	case routing.VPNConnected, routing.VPNDisconnected:
+		return vpnFlapKey
+	case routing.PhysicalGatewayChanged, routing.NetworkInterfaceUp,
+		routing.NetworkInterfaceDown, routing.NetworkAddressChanged:
+		return event.PhysicalInterface
+	default:
+		return ""
+	}
+}
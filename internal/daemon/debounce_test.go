@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+func newTestDebouncer() *eventDebouncer {
+	cfg := config.NewConfig()
+	cfg.EventDebounce = map[string]time.Duration{
+		"PhysicalGatewayChanged": 50 * time.Millisecond,
+		"VPNConnected":           20 * time.Millisecond,
+	}
+	cfg.VPNLossHoldDown = 100 * time.Millisecond
+	cfg.FlapThreshold = 3
+	cfg.FlapBackoffBase = time.Second
+	cfg.FlapBackoffMax = 4 * time.Second
+	return newEventDebouncer(cfg, logger.New("error"))
+}
+
+func expectEvent(t *testing.T, d *eventDebouncer, within time.Duration) (routing.NetworkEvent, bool) {
+	t.Helper()
+	select {
+	case event := <-d.Events():
+		return event, true
+	case <-time.After(within):
+		return routing.NetworkEvent{}, false
+	}
+}
+
+func TestDebouncerCoalescesBursts(t *testing.T) {
+	d := newTestDebouncer()
+	defer d.Stop()
+
+	for _, iface := range []string{"en0", "en1", "en2"} {
+		d.Submit(routing.NetworkEvent{EventType: routing.PhysicalGatewayChanged, PhysicalInterface: iface})
+	}
+
+	event, ok := expectEvent(t, d, time.Second)
+	if !ok {
+		t.Fatal("Expected a debounced event")
+	}
+	if event.PhysicalInterface != "en2" {
+		t.Errorf("Expected the latest event to win, got %s", event.PhysicalInterface)
+	}
+	if _, ok := expectEvent(t, d, 150*time.Millisecond); ok {
+		t.Error("Expected the burst to be delivered only once")
+	}
+}
+
+func TestDebouncerVPNHoldDown(t *testing.T) {
+	d := newTestDebouncer()
+	defer d.Stop()
+
+	d.Submit(routing.NetworkEvent{EventType: routing.VPNDisconnected})
+	time.Sleep(30 * time.Millisecond)
+	d.Submit(routing.NetworkEvent{EventType: routing.VPNConnected, VPNInterface: "utun4"})
+
+	event, ok := expectEvent(t, d, time.Second)
+	if !ok {
+		t.Fatal("Expected the reconnect to be delivered")
+	}
+	if event.EventType != routing.VPNConnected {
+		t.Errorf("Expected VPNConnected, got %s", event.EventType)
+	}
+	if _, ok := expectEvent(t, d, 200*time.Millisecond); ok {
+		t.Error("The VPN loss should have been cancelled by the reconnect within hold-down")
+	}
+}
+
+func TestDebouncerFlapBackoff(t *testing.T) {
+	d := newTestDebouncer()
+	now := time.Now()
+
+	expected := []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, want := range expected {
+		got := d.flapBackoffLocked("en0", now.Add(time.Duration(i)*time.Second))
+		if got != want {
+			t.Errorf("Flap %d: expected backoff %v, got %v", i+1, want, got)
+		}
+	}
+
+	if got := d.flapBackoffLocked("en0", now.Add(10*time.Minute)); got != 0 {
+		t.Errorf("Expected flap history to expire outside the window, got backoff %v", got)
+	}
+}
+
+func TestDebouncerIgnoresSignallingEventsForFlaps(t *testing.T) {
+	d := newTestDebouncer()
+	defer d.Stop()
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		d.Submit(routing.NetworkEvent{
+			EventType:         routing.PollFallbackEnabled,
+			PhysicalInterface: "en0",
+			Timestamp:         now.Add(time.Duration(i) * time.Second),
+		})
+	}
+	if len(d.flaps["en0"]) != 0 {
+		t.Errorf("Expected poll fallback events not to count as flaps, got %d", len(d.flaps["en0"]))
+	}
+
+	d.mutex.Lock()
+	backoff := d.flapBackoffLocked("en0", now.Add(5*time.Second))
+	d.mutex.Unlock()
+	if backoff != 0 {
+		t.Errorf("Expected no backoff for the first interface change, got %v", backoff)
+	}
+}
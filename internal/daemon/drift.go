@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// timeJumpCheckInterval is how often the drift loop samples the clocks to detect suspend/resume
+const timeJumpCheckInterval = 5 * time.Second
+
+// driftLoop periodically compares the desired and actual route state and repairs drift.
+// Routes may be flushed or overridden behind our back (NetworkManager, DHCP renewal, VPN clients),
+// and no NetworkEvent is generated for that, so events alone cannot keep the table correct.
+func (sm *ServiceManager) driftLoop() {
+	if sm.config.ReconcileInterval <= 0 {
+		sm.logger.Debug("Drift reconciliation disabled")
+		return
+	}
+
+	timer := time.NewTimer(sm.nextDriftInterval())
+	defer timer.Stop()
+
+	clock := time.NewTicker(timeJumpCheckInterval)
+	defer clock.Stop()
+	lastTick := time.Now()
+
+	for {
+		select {
+		case <-sm.ctx.Done():
+			return
+		case <-timer.C:
+			sm.checkDrift("interval")
+			timer.Reset(sm.nextDriftInterval())
+		case now := <-clock.C:
+			jumped := isTimeJump(lastTick, now, sm.config.TimeJumpThreshold)
+			lastTick = now
+			if !jumped {
+				continue
+			}
+
+			sm.logger.Info("Time jump detected, reconciling routes immediately")
+			sm.checkDrift("time_jump")
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(sm.nextDriftInterval())
+		}
+	}
+}
+
+// checkDrift queues a drift verification of the current network state on the reconciler
+func (sm *ServiceManager) checkDrift(reason string) {
+	if sm.isPaused() {
+		return
+	}
+
+	desired, err := sm.currentDesiredState()
+	if err != nil {
+		sm.logger.Debug("Skipping drift check", "reason", reason, "error", err)
+		return
+	}
+
+	sm.reconciler.Submit(applyRequest{desired: desired, reason: reason, verifyOnly: true})
+}
+
+// repairDrift runs a single drift reconciliation pass and returns the routes it changed;
+// it is called from the reconciler goroutine
+func (sm *ServiceManager) repairDrift(ctx context.Context, desired routing.DesiredState, reason string) (routing.RouteDiff, error) {
+	report, err := sm.routeSwitch.Reconcile(ctx, desired.Uplink(), desired.RoutesWanted(), desired.Withdrawn...)
+	if report != nil {
+		sm.metrics.RecordDriftCheck(report.Missing, report.Stale, report.Repaired)
+	}
+	if err != nil {
+		return routing.RouteDiff{}, fmt.Errorf("failed to reconcile route drift: %w", err)
+	}
+
+	if !report.HasDrift() {
+		return routing.RouteDiff{}, nil
+	}
+
+	sm.logger.Info("Route drift repaired",
+		"reason", reason,
+		"missing", report.Missing,
+		"stale", report.Stale,
+		"vpn_connected", desired.VPNConnected)
+	sm.notify(notificationDriftRepaired, reason, desired, map[string]interface{}{
+		"missing": report.Missing,
+		"stale":   report.Stale,
+	})
+	return routing.RouteDiff{Added: report.Missing, Removed: report.Stale}, nil
+}
+
+// nextDriftInterval returns the reconcile interval with a random jitter applied
+func (sm *ServiceManager) nextDriftInterval() time.Duration {
+	interval := sm.config.ReconcileInterval
+	if jitter := sm.config.ReconcileJitter; jitter > 0 {
+		interval += time.Duration(rand.Int64N(int64(jitter)))
+	}
+	return interval
+}
+
+// isTimeJump reports whether the wall clock and the monotonic clock diverged by more than threshold
+// between two samples. The monotonic clock stops while the machine is suspended, so a large
+// divergence means the system was asleep (or the wall clock was stepped).
+func isTimeJump(prev, now time.Time, threshold time.Duration) bool {
+	if threshold <= 0 {
+		return false
+	}
+
+	monotonic := now.Sub(prev)
+	wall := now.Round(0).Sub(prev.Round(0))
+
+	diff := wall - monotonic
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > threshold
+}
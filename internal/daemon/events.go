@@ -0,0 +1,191 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/control"
+	"github.com/wesleywu/smart-route/internal/history"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// Control socket commands
+const (
+	CommandEvents  = "events"  // returns the event history
+	CommandTrigger = "trigger" // makes the monitor check for network changes now
+	CommandStatus  = "status"  // returns the service status, including the connection state
+	CommandPause   = "pause"   // withdraws the managed routes until resumed
+	CommandResume  = "resume"  // re-applies the managed routes after a pause
+)
+
+// EventsArgs are the arguments of CommandEvents
+type EventsArgs struct {
+	Since  time.Time `json:"since"`            // only records at or after this time
+	Follow bool      `json:"follow,omitempty"` // keep streaming new records
+}
+
+// TriggerArgs are the arguments of CommandTrigger
+type TriggerArgs struct {
+	// State is reported to the monitor instead of querying the routing table, if set
+	State *routing.NetworkState `json:"state,omitempty"`
+}
+
+// ControlSocketPath returns the control socket the daemon listens on for cfg
+func ControlSocketPath(cfg *config.Config) string {
+	if cfg.ControlSocket != "" {
+		return cfg.ControlSocket
+	}
+	return control.DefaultSocketPath()
+}
+
+// newHistory creates the event history configured in cfg
+func newHistory(cfg *config.Config) (*history.History, error) {
+	if cfg.EventHistoryFile == "" {
+		return history.New(cfg.EventHistorySize), nil
+	}
+	return history.Open(cfg.EventHistoryFile, cfg.EventHistorySize, cfg.EventHistoryMaxBytes, cfg.EventHistoryBackups)
+}
+
+// recordEvent adds a handled network event to the history
+func (sm *ServiceManager) recordEvent(eventType string, desired routing.DesiredState) {
+	sm.addHistory(sm.historyRecord(history.KindEvent, eventType, desired))
+}
+
+// recordApply adds an apply result to the history. Drift verifications that found nothing to
+// repair are left out, they would crowd out everything else.
+func (sm *ServiceManager) recordApply(req applyRequest, duration time.Duration, diff routing.RouteDiff, err error) {
+	sm.traceApply(req, duration, diff, err)
+	if req.verifyOnly && err == nil && !diff.Changed() {
+		return
+	}
+
+	record := sm.historyRecord(history.KindApply, req.reason, req.desired)
+	record.VerifyOnly = req.verifyOnly
+	record.Duration = duration
+	record.RoutesAdded = diff.Added
+	record.RoutesRemoved = diff.Removed
+	if err != nil {
+		record.Error = err.Error()
+	}
+	sm.addHistory(record)
+}
+
+// traceApply records an apply decision to the trace, if one is being recorded
+func (sm *ServiceManager) traceApply(req applyRequest, duration time.Duration, diff routing.RouteDiff, err error) {
+	if sm.trace == nil {
+		return
+	}
+
+	apply := routing.TraceApply{
+		Reason:            req.reason,
+		VerifyOnly:        req.verifyOnly,
+		VPNConnected:      req.desired.VPNConnected,
+		PhysicalGateway:   req.desired.PhysicalGateway,
+		PhysicalInterface: req.desired.PhysicalInterface,
+		VPNInterface:      req.desired.VPNInterface,
+		RoutesAdded:       diff.Added,
+		RoutesRemoved:     diff.Removed,
+	}
+	if err != nil {
+		apply.Error = err.Error()
+	}
+	sm.trace.RecordApply(apply, duration)
+}
+
+// historyRecord creates a record describing desired
+func (sm *ServiceManager) historyRecord(kind, recordType string, desired routing.DesiredState) history.Record {
+	record := history.Record{
+		Kind:              kind,
+		Type:              recordType,
+		VPNConnected:      desired.VPNConnected,
+		PhysicalInterface: desired.PhysicalInterface,
+		VPNInterface:      desired.VPNInterface,
+	}
+	if desired.PhysicalGateway != nil {
+		record.PhysicalGateway = desired.PhysicalGateway.String()
+	}
+	return record
+}
+
+// addHistory adds a record; failing to persist it does not affect the in-memory history
+func (sm *ServiceManager) addHistory(record history.Record) {
+	if err := sm.history.Add(record); err != nil {
+		sm.logger.Warn("failed to persist event history", "error", err)
+	}
+}
+
+// registerControlHandlers registers the control socket commands served by the daemon
+func (sm *ServiceManager) registerControlHandlers(server *control.Server) {
+	server.Handle(CommandEvents, sm.handleEvents)
+	server.Handle(CommandTrigger, sm.handleTrigger)
+	server.Handle(CommandStatus, sm.handleStatus)
+	server.Handle(CommandPause, sm.handlePause)
+	server.Handle(CommandResume, sm.handleResume)
+}
+
+// handleStatus returns the service status
+func (sm *ServiceManager) handleStatus(_ context.Context, _ json.RawMessage, send func(interface{}) error) error {
+	return send(sm.GetStatus())
+}
+
+// handleTrigger feeds an external signal to the monitor
+func (sm *ServiceManager) handleTrigger(ctx context.Context, rawArgs json.RawMessage, send func(interface{}) error) error {
+	var args TriggerArgs
+	if len(rawArgs) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return fmt.Errorf("invalid trigger arguments: %w", err)
+		}
+	}
+
+	if args.State == nil {
+		sm.external.Trigger()
+	} else {
+		if args.State.PhysicalInterface == "" || args.State.DefaultInterface == "" {
+			return fmt.Errorf("a reported state needs the physical and the default interface")
+		}
+		if err := sm.external.Observe(ctx, *args.State); err != nil {
+			return err
+		}
+	}
+	return send("ok")
+}
+
+// handleEvents streams the history since the requested time, followed by new records if asked to
+func (sm *ServiceManager) handleEvents(ctx context.Context, rawArgs json.RawMessage, send func(interface{}) error) error {
+	var args EventsArgs
+	if len(rawArgs) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return fmt.Errorf("invalid events arguments: %w", err)
+		}
+	}
+
+	if !args.Follow {
+		for _, record := range sm.history.Since(args.Since) {
+			if err := send(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	records, updates, cancel := sm.history.Follow(args.Since)
+	defer cancel()
+	for _, record := range records {
+		if err := send(record); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case record := <-updates:
+			if err := send(record); err != nil {
+				return err
+			}
+		}
+	}
+}
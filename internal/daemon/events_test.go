@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/history"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+func TestEventHistory(t *testing.T) {
+	sm := &ServiceManager{logger: logger.New("error"), history: history.New(10)}
+	desired := routing.DesiredState{
+		VPNConnected:      true,
+		PhysicalGateway:   net.ParseIP("192.168.1.1"),
+		PhysicalInterface: "en0",
+		VPNInterface:      "utun3",
+	}
+
+	sm.recordEvent(routing.VPNConnected.String(), desired)
+	sm.recordApply(applyRequest{desired: desired, reason: "vpn_connected"}, time.Second, routing.RouteDiff{Added: 5}, nil)
+	// A clean drift verification is not worth recording
+	sm.recordApply(applyRequest{desired: desired, reason: "periodic", verifyOnly: true}, time.Millisecond, routing.RouteDiff{}, nil)
+	sm.recordApply(applyRequest{desired: desired, reason: "periodic", verifyOnly: true}, time.Millisecond, routing.RouteDiff{}, errors.New("boom"))
+
+	var records []history.Record
+	err := sm.handleEvents(context.Background(), json.RawMessage(`{}`), func(result interface{}) error {
+		records = append(records, result.(history.Record))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("handleEvents failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %+v", records)
+	}
+
+	if event := records[0]; event.Kind != history.KindEvent || event.Type != "VPNConnected" || event.PhysicalGateway != "192.168.1.1" {
+		t.Errorf("Unexpected event record %+v", event)
+	}
+	if apply := records[1]; apply.Kind != history.KindApply || apply.RoutesAdded != 5 || apply.Duration != time.Second {
+		t.Errorf("Unexpected apply record %+v", apply)
+	}
+	if failed := records[2]; !failed.VerifyOnly || failed.Error != "boom" {
+		t.Errorf("Expected the failed verification to be recorded, got %+v", failed)
+	}
+}
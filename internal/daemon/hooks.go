@@ -0,0 +1,275 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// Hook names for apply results; network event hooks are named after their routing.EventType
+const (
+	hookApplySucceeded = "ApplySucceeded"
+	hookApplyFailed    = "ApplyFailed"
+)
+
+const (
+	// hookOutputLimit caps how much of a hook's output is kept for the log
+	hookOutputLimit = 4096
+	// hookWaitDelay bounds the wait for output pipes held open by a killed hook's children
+	hookWaitDelay = time.Second
+)
+
+// hookEvent describes an event to hook executables. It is written to their stdin as JSON
+// and passed as SMARTROUTE_* environment variables.
+type hookEvent struct {
+	Event             string    `json:"event"`
+	Timestamp         time.Time `json:"timestamp"`
+	Reason            string    `json:"reason,omitempty"`
+	VPNConnected      bool      `json:"vpn_connected"`
+	PhysicalGateway   string    `json:"physical_gateway,omitempty"`
+	PhysicalInterface string    `json:"physical_interface,omitempty"`
+	VPNInterface      string    `json:"vpn_interface,omitempty"`
+	ManagedRoutes     int       `json:"managed_routes"`   // Networks in the managed set
+	ActiveRoutes      int       `json:"active_routes"`    // Managed routes wanted through the physical uplink
+	WithdrawnRoutes   int       `json:"withdrawn_routes"` // Managed routes kept on the VPN by path health
+	Error             string    `json:"error,omitempty"`
+}
+
+// environ returns the event as environment variables
+func (e hookEvent) environ() []string {
+	return []string{
+		"SMARTROUTE_EVENT=" + e.Event,
+		"SMARTROUTE_TIMESTAMP=" + e.Timestamp.Format(time.RFC3339),
+		"SMARTROUTE_REASON=" + e.Reason,
+		"SMARTROUTE_VPN_CONNECTED=" + strconv.FormatBool(e.VPNConnected),
+		"SMARTROUTE_PHYSICAL_GATEWAY=" + e.PhysicalGateway,
+		"SMARTROUTE_PHYSICAL_INTERFACE=" + e.PhysicalInterface,
+		"SMARTROUTE_VPN_INTERFACE=" + e.VPNInterface,
+		"SMARTROUTE_MANAGED_ROUTES=" + strconv.Itoa(e.ManagedRoutes),
+		"SMARTROUTE_ACTIVE_ROUTES=" + strconv.Itoa(e.ActiveRoutes),
+		"SMARTROUTE_WITHDRAWN_ROUTES=" + strconv.Itoa(e.WithdrawnRoutes),
+		"SMARTROUTE_ERROR=" + e.Error,
+	}
+}
+
+// hookRunner runs user executables when network events happen and routes are applied,
+// e.g. to flush a DNS cache or restart a sync agent. Hooks run in the background with a
+// timeout and bounded concurrency; their exit status is logged.
+type hookRunner struct {
+	hooks   map[string][]string
+	dir     string
+	timeout time.Duration
+	slots   chan struct{}
+	logger  *logger.Logger
+	wg      sync.WaitGroup
+}
+
+// newHookRunner creates a runner for the hooks configured in cfg
+func newHookRunner(cfg *config.Config, log *logger.Logger) *hookRunner {
+	known := knownHookNames()
+	for name := range cfg.Hooks {
+		if !known[name] {
+			log.Warn("Hooks configured for unknown event", "event", name)
+		}
+	}
+
+	return &hookRunner{
+		hooks:   cfg.Hooks,
+		dir:     cfg.HookDir,
+		timeout: cfg.HookTimeout,
+		slots:   make(chan struct{}, max(cfg.HookConcurrency, 1)),
+		logger:  log,
+	}
+}
+
+// knownHookNames returns the names hooks can be registered for
+func knownHookNames() map[string]bool {
+	names := map[string]bool{hookApplySucceeded: true, hookApplyFailed: true}
+	for eventType := routing.EventType(0); eventType.String() != "UnknownEvent"; eventType++ {
+		names[eventType.String()] = true
+	}
+	return names
+}
+
+// Fire starts the hooks registered for event.Event. Hooks beyond the concurrency limit wait
+// for a free slot; cancelling ctx drops waiting hooks and kills running ones.
+func (h *hookRunner) Fire(ctx context.Context, event hookEvent) {
+	if ctx.Err() != nil {
+		return
+	}
+	paths := h.hooksFor(event.Event)
+	if len(paths) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		h.logger.Error("failed to encode hook event", "event", event.Event, "error", err)
+		return
+	}
+
+	for _, path := range paths {
+		h.wg.Add(1)
+		go func() {
+			defer h.wg.Done()
+			select {
+			case h.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-h.slots }()
+
+			h.run(ctx, path, event, payload)
+		}()
+	}
+}
+
+// Wait blocks until all started hooks have finished
+func (h *hookRunner) Wait() {
+	h.wg.Wait()
+}
+
+// hooksFor returns the configured executables for name followed by those in the
+// name subdirectory of the hook directory, which is read on every event so hooks
+// can be added without restarting the daemon
+func (h *hookRunner) hooksFor(name string) []string {
+	paths := slices.Clone(h.hooks[name])
+	if h.dir == "" {
+		return paths
+	}
+
+	dir := filepath.Join(h.dir, name)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			h.logger.Warn("failed to read hook directory", "dir", dir, "error", err)
+		}
+		return paths
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !isExecutable(info) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	return paths
+}
+
+// isExecutable reports whether a hook directory entry should be run
+func isExecutable(info os.FileInfo) bool {
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+// run executes a single hook and logs its outcome
+func (h *hookRunner) run(ctx context.Context, path string, event hookEvent, payload []byte) {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	output := &limitedBuffer{limit: hookOutputLimit}
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Env = append(os.Environ(), event.environ()...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = hookWaitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start).Milliseconds()
+
+	exitCode := -1
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		h.logger.Warn("Hook timed out",
+			"event", event.Event,
+			"hook", path,
+			"timeout", h.timeout,
+			"output", output.String())
+	case err != nil:
+		h.logger.Warn("Hook failed",
+			"event", event.Event,
+			"hook", path,
+			"exit_code", exitCode,
+			"error", err,
+			"duration_ms", duration,
+			"output", output.String())
+	default:
+		h.logger.Info("Hook finished",
+			"event", event.Event,
+			"hook", path,
+			"exit_code", exitCode,
+			"duration_ms", duration)
+		if output.Len() > 0 {
+			h.logger.Debug("Hook output", "hook", path, "output", output.String())
+		}
+	}
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write implements io.Writer; it never fails so the hook is not disturbed by a full buffer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// fireHook starts the hooks for name with the details of desired
+func (sm *ServiceManager) fireHook(name string, desired routing.DesiredState, reason string, applyErr error) {
+	managed := sm.profiles.IPSet(desired.Profile).Size()
+	event := hookEvent{
+		Event:             name,
+		Timestamp:         time.Now(),
+		Reason:            reason,
+		VPNConnected:      desired.VPNConnected,
+		PhysicalInterface: desired.PhysicalInterface,
+		VPNInterface:      desired.VPNInterface,
+		ManagedRoutes:     managed,
+	}
+	if desired.PhysicalGateway != nil {
+		event.PhysicalGateway = desired.PhysicalGateway.String()
+	}
+	if desired.VPNConnected {
+		event.WithdrawnRoutes = len(desired.Withdrawn)
+		if desired.DirectPathDegraded {
+			event.WithdrawnRoutes = managed
+		}
+		event.ActiveRoutes = managed - event.WithdrawnRoutes
+	}
+	if applyErr != nil {
+		event.Error = applyErr.Error()
+	}
+
+	sm.hooks.Fire(sm.ctx, event)
+}
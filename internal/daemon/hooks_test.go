@@ -0,0 +1,104 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+)
+
+// writeHook creates an executable shell script in dir
+func writeHook(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create hook directory: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+	return path
+}
+
+func TestHookRunnerPassesEvent(t *testing.T) {
+	hookDir := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	writeHook(t, filepath.Join(hookDir, "VPNConnected"), "10-record",
+		`cat > "`+out+`.json"; echo "$SMARTROUTE_EVENT $SMARTROUTE_PHYSICAL_GATEWAY $SMARTROUTE_ACTIVE_ROUTES" > "`+out+`.env"`)
+	writeHook(t, filepath.Join(hookDir, "VPNConnected"), ".hidden", "exit 1")
+
+	cfg := config.NewConfig()
+	cfg.HookDir = hookDir
+	h := newHookRunner(cfg, logger.New("error"))
+
+	if paths := h.hooksFor("VPNConnected"); len(paths) != 1 {
+		t.Fatalf("Expected 1 hook, got %v", paths)
+	}
+
+	h.Fire(context.Background(), hookEvent{
+		Event:           "VPNConnected",
+		PhysicalGateway: "192.168.1.1",
+		ManagedRoutes:   10,
+		ActiveRoutes:    8,
+		WithdrawnRoutes: 2,
+	})
+	h.Wait()
+
+	env, err := os.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatalf("Hook did not run: %v", err)
+	}
+	if got := strings.TrimSpace(string(env)); got != "VPNConnected 192.168.1.1 8" {
+		t.Errorf("Unexpected hook environment %q", got)
+	}
+
+	var event hookEvent
+	data, _ := os.ReadFile(out + ".json")
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Hook stdin is not JSON: %v", err)
+	}
+	if event.Event != "VPNConnected" || event.WithdrawnRoutes != 2 {
+		t.Errorf("Unexpected hook stdin %+v", event)
+	}
+}
+
+func TestHookRunnerTimeoutAndConcurrency(t *testing.T) {
+	hookDir := t.TempDir()
+	cfg := config.NewConfig()
+	cfg.HookTimeout = 100 * time.Millisecond
+	cfg.HookConcurrency = 1
+	cfg.Hooks = map[string][]string{
+		"ApplyFailed": {
+			writeHook(t, hookDir, "slow", "sleep 5"),
+			writeHook(t, hookDir, "slow-too", "sleep 5"),
+		},
+	}
+	h := newHookRunner(cfg, logger.New("error"))
+
+	start := time.Now()
+	h.Fire(context.Background(), hookEvent{Event: "ApplyFailed"})
+	h.Wait()
+
+	// Both hooks are killed at the timeout, one after the other
+	elapsed := time.Since(start)
+	if elapsed < 200*time.Millisecond || elapsed > 3*time.Second {
+		t.Errorf("Expected two sequential timeouts, took %v", elapsed)
+	}
+}
+
+func TestKnownHookNames(t *testing.T) {
+	names := knownHookNames()
+	for _, name := range []string{"VPNConnected", "PhysicalGatewayChanged", "DirectPathRecovered", "ApplySucceeded", "ApplyFailed"} {
+		if !names[name] {
+			t.Errorf("Expected %s to be a known hook name", name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package daemon
+
+import (
+	"github.com/wesleywu/smart-route/internal/notify"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// notificationDriftRepaired is sent when drift reconciliation had to fix the route table
+const notificationDriftRepaired = "DriftRepaired"
+
+// notify sends a webhook notification describing desired; extra details are merged in
+func (sm *ServiceManager) notify(event, reason string, desired routing.DesiredState, details map[string]interface{}) {
+	if sm.notifier == nil {
+		return
+	}
+
+	merged := map[string]interface{}{
+		"vpn_connected":      desired.VPNConnected,
+		"physical_gateway":   sm.ipToString(desired.PhysicalGateway),
+		"physical_interface": desired.PhysicalInterface,
+		"vpn_interface":      desired.VPNInterface,
+	}
+	if reason != "" {
+		merged["reason"] = reason
+	}
+	for key, value := range details {
+		merged[key] = value
+	}
+
+	sm.notifier.Notify(notify.Notification{Event: event, Details: merged})
+}
@@ -0,0 +1,148 @@
+package daemon
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// pathHealthLoop periodically compares the direct path with the VPN while the VPN is connected.
+// Some hotel and café networks throttle or hijack direct access; managed routes are then withdrawn
+// so that the affected traffic takes the VPN, and restored once the direct path recovers.
+func (sm *ServiceManager) pathHealthLoop() {
+	if sm.pathHealth == nil {
+		sm.logger.Debug("Direct path health monitoring disabled")
+		return
+	}
+
+	ticker := time.NewTicker(sm.config.PathHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.ctx.Done():
+			return
+		case <-ticker.C:
+			sm.checkPathHealth()
+		}
+	}
+}
+
+// checkPathHealth runs one health round and emits an event when the withdrawn routes change
+func (sm *ServiceManager) checkPathHealth() {
+	desired, err := sm.currentDesiredState()
+	if err != nil {
+		sm.logger.Debug("Skipping path health check", "error", err)
+		return
+	}
+	if !desired.VPNConnected {
+		// Without the VPN there is no other path to compare with or fall back to
+		return
+	}
+
+	report := sm.pathHealth.Check(sm.ctx, desired.Uplink(), desired.VPNInterface)
+	if sm.ctx.Err() != nil {
+		return
+	}
+	sm.recordPathCheck(report)
+	if !report.Changed {
+		return
+	}
+
+	degraded, withdrawn := sm.pathWithdrawal(report)
+	sm.mutex.Lock()
+	sm.directPathDegraded = degraded
+	sm.withdrawn = withdrawn
+	sm.mutex.Unlock()
+
+	eventType := routing.DirectPathRecovered
+	withdrawnCount := len(withdrawn)
+	if degraded {
+		withdrawnCount = sm.activeIPSet().Size()
+	}
+	if withdrawnCount > 0 {
+		eventType = routing.DirectPathDegraded
+	}
+	sm.metrics.RecordPathTransition(eventType == routing.DirectPathDegraded, withdrawnCount)
+
+	event := routing.NetworkEvent{
+		EventType:         eventType,
+		PhysicalInterface: desired.PhysicalInterface,
+		VPNInterface:      desired.VPNInterface,
+		PhysicalGateway:   desired.PhysicalGateway,
+		Timestamp:         time.Now(),
+		VPNConnected:      true,
+	}
+	sm.monitor.Publish(event)
+}
+
+// pathWithdrawal decides which managed routes to withdraw for a health report: all of them when
+// every target is degraded and PathHealthWithdrawAll is set, otherwise the managed networks that
+// contain a degraded target
+func (sm *ServiceManager) pathWithdrawal(report routing.PathHealthReport) (all bool, withdrawn []net.IPNet) {
+	if sm.config.PathHealthWithdrawAll && report.AllDegraded() {
+		return true, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range report.Degraded() {
+		found := false
+		for _, network := range sm.activeIPSet().IPNets() {
+			if !network.Contains(target.IP) {
+				continue
+			}
+			found = true
+			if !seen[network.String()] {
+				seen[network.String()] = true
+				withdrawn = append(withdrawn, *network)
+			}
+		}
+		if !found {
+			sm.logger.Debug("Degraded path health target is outside the managed networks", "target", target.Target)
+		}
+	}
+
+	sort.Slice(withdrawn, func(i, j int) bool {
+		return withdrawn[i].String() < withdrawn[j].String()
+	})
+	return false, withdrawn
+}
+
+// recordPathCheck records the mean latency and loss of a health round over all targets
+func (sm *ServiceManager) recordPathCheck(report routing.PathHealthReport) {
+	if len(report.Targets) == 0 {
+		return
+	}
+
+	var direct, vpn routing.PathSample
+	for _, target := range report.Targets {
+		direct.Latency += target.Direct.Latency
+		direct.Loss += target.Direct.Loss
+		vpn.Latency += target.VPN.Latency
+		vpn.Loss += target.VPN.Loss
+	}
+	n := len(report.Targets)
+	sm.metrics.RecordPathCheck(direct.Latency/time.Duration(n), direct.Loss/float64(n),
+		vpn.Latency/time.Duration(n), vpn.Loss/float64(n))
+}
+
+// withPathHealth adds the current direct path withdrawal to a desired state
+func (sm *ServiceManager) withPathHealth(desired routing.DesiredState) routing.DesiredState {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	desired.DirectPathDegraded = sm.directPathDegraded
+	desired.Withdrawn = sm.withdrawn
+	return desired
+}
+
+// clearPathWithdrawal restores the direct path; health verdicts do not carry over to another network
+func (sm *ServiceManager) clearPathWithdrawal() {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.directPathDegraded = false
+	sm.withdrawn = nil
+}
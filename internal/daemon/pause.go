@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// PauseArgs are the arguments of CommandPause
+type PauseArgs struct {
+	Duration time.Duration `json:"duration,omitempty"` // resume automatically after this long, zero pauses until resumed
+}
+
+// PauseReply is the result of CommandPause and CommandResume
+type PauseReply struct {
+	Paused bool      `json:"paused"`
+	Until  time.Time `json:"until"`
+}
+
+// PauseStatePath returns the file the daemon persists its pause state to for cfg
+func PauseStatePath(cfg *config.Config) string {
+	if cfg.PauseStateFile != "" {
+		return cfg.PauseStateFile
+	}
+	return config.DefaultPauseStateFile()
+}
+
+// isPaused reports whether split routing is paused
+func (sm *ServiceManager) isPaused() bool {
+	state, _ := sm.state.State()
+	return state == StatePaused
+}
+
+// pause withdraws the managed routes and suppresses event handling until resumed, or until a
+// non-zero deadline passes. Pausing again while paused only moves the deadline.
+func (sm *ServiceManager) pause(until time.Time, reason string) {
+	first := sm.state.Fire(inputPause, routing.DesiredState{}, reason)
+
+	sm.mutex.Lock()
+	sm.pausedUntil = until
+	sm.schedulePauseExpiry()
+	sm.mutex.Unlock()
+
+	state := config.PauseState{Paused: true, Since: time.Now(), Until: until}
+	if err := state.Save(PauseStatePath(sm.config)); err != nil {
+		sm.logger.Warn("failed to persist pause, a restart will resume split routing", "error", err)
+	}
+
+	sm.logger.Info("Split routing paused", "until", until, "reason", reason)
+	if first {
+		sm.reconciler.Submit(applyRequest{reason: reason, pause: true})
+	}
+}
+
+// resume re-applies the current network state after a pause
+func (sm *ServiceManager) resume(reason string) error {
+	if !sm.state.Fire(inputResume, routing.DesiredState{}, reason) {
+		return fmt.Errorf("split routing is not paused")
+	}
+
+	sm.mutex.Lock()
+	sm.pausedUntil = time.Time{}
+	sm.schedulePauseExpiry()
+	sm.mutex.Unlock()
+
+	if err := config.ClearPauseState(PauseStatePath(sm.config)); err != nil {
+		sm.logger.Warn("failed to clear persisted pause, a restart will pause split routing again", "error", err)
+	}
+
+	sm.logger.Info("Split routing resumed", "reason", reason)
+	desired, err := sm.currentDesiredState()
+	if err != nil {
+		// Leave the state to the next network event or drift check
+		sm.state.Fire(inputApplyFailed, desired, reason)
+		return fmt.Errorf("failed to determine the network state: %w", err)
+	}
+	sm.reconciler.Submit(applyRequest{desired: desired, reason: reason, resume: true})
+	return nil
+}
+
+// schedulePauseExpiry arms the timer resuming an expiring pause; the caller holds the mutex
+func (sm *ServiceManager) schedulePauseExpiry() {
+	if sm.pauseTimer != nil {
+		sm.pauseTimer.Stop()
+		sm.pauseTimer = nil
+	}
+	if sm.pausedUntil.IsZero() {
+		return
+	}
+
+	sm.pauseTimer = time.AfterFunc(time.Until(sm.pausedUntil), func() {
+		if sm.ctx.Err() != nil {
+			return
+		}
+		if err := sm.resume("pause_expired"); err != nil {
+			sm.logger.Error("failed to resume after the pause expired", "error", err)
+		}
+	})
+}
+
+// restorePause pauses again if the daemon was paused when it last stopped, and reports whether
+// it did; the caller holds the mutex
+func (sm *ServiceManager) restorePause() bool {
+	path := PauseStatePath(sm.config)
+	state, err := config.LoadPauseState(path)
+	if err != nil {
+		sm.logger.Error("failed to load pause state, starting unpaused", "error", err)
+		return false
+	}
+	if !state.Active(time.Now()) {
+		if state.Paused {
+			sm.logger.Info("Pause expired while the daemon was stopped", "until", state.Until)
+			if err := config.ClearPauseState(path); err != nil {
+				sm.logger.Warn("failed to clear persisted pause", "error", err)
+			}
+		}
+		return false
+	}
+
+	sm.state.Reset(StatePaused)
+	sm.metrics.RecordStateTransition("", StatePaused.String())
+	sm.pausedUntil = state.Until
+	sm.schedulePauseExpiry()
+	sm.logger.Info("Split routing remains paused", "since", state.Since, "until", state.Until)
+	return true
+}
+
+// handlePause pauses split routing
+func (sm *ServiceManager) handlePause(_ context.Context, rawArgs json.RawMessage, send func(interface{}) error) error {
+	var args PauseArgs
+	if len(rawArgs) > 0 {
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return fmt.Errorf("invalid pause arguments: %w", err)
+		}
+	}
+	if args.Duration < 0 {
+		return fmt.Errorf("invalid pause duration %s", args.Duration)
+	}
+
+	var until time.Time
+	if args.Duration > 0 {
+		until = time.Now().Add(args.Duration)
+	}
+	sm.pause(until, "pause")
+	return send(PauseReply{Paused: true, Until: until})
+}
+
+// handleResume resumes split routing
+func (sm *ServiceManager) handleResume(_ context.Context, _ json.RawMessage, send func(interface{}) error) error {
+	if err := sm.resume("resume"); err != nil {
+		return err
+	}
+	return send(PauseReply{Paused: false})
+}
@@ -0,0 +1,118 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+)
+
+// startTestService starts a service on the fake backend without privileges or a real network
+func startTestService(t *testing.T, cfg *config.Config, router *platform.FakeRouteManager) *ServiceManager {
+	t.Helper()
+	sm, err := newServiceManager(cfg, logger.New("error"), router, "", "")
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	sm.mutex.Lock()
+	err = sm.start()
+	sm.isRunning = err == nil
+	sm.mutex.Unlock()
+	if err != nil {
+		t.Fatalf("Failed to start service: %v", err)
+	}
+	t.Cleanup(func() { sm.Stop() })
+	return sm
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.RouteBackend = "fake"
+	cfg.MonitorInterval = time.Hour
+	cfg.ReconcileInterval = 0
+	cfg.PathHealth = false
+	cfg.GatewayProbe = false
+	cfg.PauseStateFile = filepath.Join(t.TempDir(), "pause.json")
+
+	router := platform.NewFakeRouteManager(cfg)
+	gateway := net.ParseIP("192.168.1.1")
+	router.SetPhysicalGateway(gateway, "en0")
+	router.SetDefaultRoute(nil, "utun3")
+	routeCount := func() int {
+		routes, err := router.ListSystemRoutes(context.Background())
+		if err != nil {
+			t.Fatalf("ListSystemRoutes failed: %v", err)
+		}
+		return len(routes)
+	}
+	stateIs := func(sm *ServiceManager, want ConnectionState) func() bool {
+		return func() bool {
+			state, _ := sm.state.State()
+			return state == want && !sm.reconciler.InFlight()
+		}
+	}
+
+	sm := startTestService(t, cfg, router)
+	if state, _ := sm.state.State(); state != StateVPNActive {
+		t.Fatalf("Expected to start in VPNActive, got %s", state)
+	}
+	applied := routeCount()
+
+	sm.pause(time.Time{}, "pause")
+	waitFor(t, "the managed routes to be withdrawn", func() bool { return routeCount() < applied && !sm.reconciler.InFlight() })
+	if !sm.isPaused() {
+		t.Fatal("Expected the service to be paused")
+	}
+	if _, err := os.Stat(cfg.PauseStateFile); err != nil {
+		t.Fatalf("Expected the pause to be persisted: %v", err)
+	}
+	withdrawn := routeCount()
+
+	// A restart stays paused
+	if err := sm.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	sm = startTestService(t, cfg, router)
+	if !sm.isPaused() || routeCount() != withdrawn {
+		t.Fatalf("Expected the restarted service to stay paused with %d routes, got %d", withdrawn, routeCount())
+	}
+
+	if err := sm.resume("resume"); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	waitFor(t, "the managed routes to be restored", stateIs(sm, StateVPNActive))
+	if routeCount() != applied {
+		t.Errorf("Expected %d routes after resuming, got %d", applied, routeCount())
+	}
+	if _, err := os.Stat(cfg.PauseStateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the persisted pause to be cleared, got %v", err)
+	}
+	if err := sm.resume("resume"); err == nil {
+		t.Error("Expected resuming without a pause to fail")
+	}
+
+	// A timed pause resumes by itself
+	sm.pause(time.Now().Add(100*time.Millisecond), "pause")
+	waitFor(t, "the pause to expire", func() bool {
+		state, _ := sm.state.State()
+		return state != StatePaused && !sm.reconciler.InFlight() && routeCount() == applied
+	})
+}
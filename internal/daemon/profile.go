@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// defaultProfileName describes the settings of networks no profile matches in logs and status
+const defaultProfileName = "default"
+
+// networkProfiles holds the managed networks of every network profile
+type networkProfiles struct {
+	profiles []config.Profile
+	ipSets   map[string]*config.IPSet // by profile name, "" for networks no profile matches
+	known    *config.IPSet            // every network of every list, nil without profiles
+}
+
+// newNetworkProfiles loads the lists of the configured profiles. Unmatched networks use
+// defaultSet, which was loaded from routeFile and dnsFile.
+func newNetworkProfiles(cfg *config.Config, defaultSet *config.IPSet, routeFile, dnsFile string) (*networkProfiles, error) {
+	profiles := cfg.Profiles
+	if cfg.ProfilesFile != "" {
+		var err error
+		profiles, err = config.LoadProfiles(cfg.ProfilesFile)
+		if err != nil {
+			return nil, err
+		}
+	} else if err := config.ValidateProfiles(profiles); err != nil {
+		return nil, err
+	}
+
+	np := &networkProfiles{
+		profiles: profiles,
+		ipSets:   map[string]*config.IPSet{"": defaultSet},
+	}
+	if len(profiles) == 0 {
+		return np, nil
+	}
+
+	// Profiles sharing lists share one copy of them
+	type listFiles struct{ routeFile, dnsFile string }
+	lists := map[listFiles]*config.IPSet{{routeFile, dnsFile}: defaultSet}
+	np.known = config.NewIPSet()
+	np.known.Merge(defaultSet)
+	for _, profile := range profiles {
+		if !profile.IsEnabled() {
+			np.ipSets[profile.Name] = config.NewIPSet()
+			continue
+		}
+
+		files := listFiles{routeFile, dnsFile}
+		if profile.RouteFile != "" {
+			files.routeFile = profile.RouteFile
+		}
+		if profile.DNSFile != "" {
+			files.dnsFile = profile.DNSFile
+		}
+		list, ok := lists[files]
+		if !ok {
+			var err error
+			list, err = config.LoadManagedIPSetWithFallback(files.routeFile, files.dnsFile)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: %w", profile.Name, err)
+			}
+			lists[files] = list
+			np.known.Merge(list)
+		}
+
+		exclusions, err := profile.Exclusions()
+		if err != nil {
+			return nil, err
+		}
+		if len(exclusions) > 0 {
+			list = list.Without(exclusions)
+		}
+		np.ipSets[profile.Name] = list
+	}
+	return np, nil
+}
+
+// Enabled reports whether any profile is configured
+func (np *networkProfiles) Enabled() bool {
+	return len(np.profiles) > 0
+}
+
+// Select returns the name of the first profile matching identity, or "" if none does
+func (np *networkProfiles) Select(identity routing.NetworkIdentity) string {
+	for _, profile := range np.profiles {
+		if profileMatches(profile.Match, identity) {
+			return profile.Name
+		}
+	}
+	return ""
+}
+
+// IsEnabled reports whether split routing is enabled on the networks of a profile
+func (np *networkProfiles) IsEnabled(name string) bool {
+	for _, profile := range np.profiles {
+		if profile.Name == name {
+			return profile.IsEnabled()
+		}
+	}
+	return true
+}
+
+// IPSet returns the managed networks of a profile
+func (np *networkProfiles) IPSet(name string) *config.IPSet {
+	if ipSet, ok := np.ipSets[name]; ok {
+		return ipSet
+	}
+	return np.ipSets[""]
+}
+
+// profileMatches reports whether every criterion of match holds for identity.
+// The criteria were validated when the profiles were loaded.
+func profileMatches(match config.ProfileMatch, identity routing.NetworkIdentity) bool {
+	if match.Interface != "" && match.Interface != identity.Interface {
+		return false
+	}
+	if match.GatewayMAC != "" {
+		mac, _ := net.ParseMAC(match.GatewayMAC)
+		if identity.GatewayMAC == nil || !bytes.Equal(mac, identity.GatewayMAC) {
+			return false
+		}
+	}
+	if match.Subnet != "" {
+		_, subnet, _ := net.ParseCIDR(match.Subnet)
+		if !subnetMatches(subnet, identity) {
+			return false
+		}
+	}
+	if match.DHCPDomain != "" && !strings.EqualFold(strings.TrimSuffix(match.DHCPDomain, "."), identity.DHCPDomain) {
+		return false
+	}
+	return true
+}
+
+// subnetMatches reports whether subnet holds the gateway or an address of the interface
+func subnetMatches(subnet *net.IPNet, identity routing.NetworkIdentity) bool {
+	if identity.Gateway != nil && subnet.Contains(identity.Gateway) {
+		return true
+	}
+	for _, addr := range identity.Addresses {
+		if subnet.Contains(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// identifyProfile identifies the network behind the physical uplink and selects its profile.
+// Without profiles there is nothing to select and the network is not probed.
+func (sm *ServiceManager) identifyProfile(gateway net.IP, iface string) (string, routing.NetworkIdentity) {
+	if !sm.profiles.Enabled() {
+		return "", routing.NetworkIdentity{Interface: iface, Gateway: gateway}
+	}
+	identity := routing.IdentifyNetwork(sm.ctx, gateway, iface)
+	return sm.profiles.Select(identity), identity
+}
+
+// setProfile records the selected profile and the network it was selected for, and reports
+// whether the profile changed; the caller holds the mutex
+func (sm *ServiceManager) setProfile(profile string, identity routing.NetworkIdentity) bool {
+	changed := profile != sm.profile
+	sm.profile = profile
+	sm.network = identity
+
+	attrs := []interface{}{
+		"profile", profileName(profile),
+		"interface", identity.Interface,
+		"gateway", sm.ipToString(identity.Gateway),
+		"gateway_mac", identity.GatewayMAC.String(),
+		"dhcp_domain", identity.DHCPDomain,
+	}
+	if changed {
+		sm.logger.Info("Network profile selected", attrs...)
+	} else {
+		sm.logger.Debug("Network profile unchanged", attrs...)
+	}
+	return changed
+}
+
+// evaluateProfile re-selects the profile for the physical uplink and returns it
+func (sm *ServiceManager) evaluateProfile(gateway net.IP, iface string) string {
+	profile, identity := sm.identifyProfile(gateway, iface)
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.setProfile(profile, identity)
+	return profile
+}
+
+// withProfile sets the selected profile on desired
+func (sm *ServiceManager) withProfile(desired routing.DesiredState) routing.DesiredState {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	desired.Profile = sm.profile
+	return desired
+}
+
+// activeIPSet returns the managed networks of the selected profile
+func (sm *ServiceManager) activeIPSet() *config.IPSet {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.profiles.IPSet(sm.profile)
+}
+
+// profileStatus describes the selected profile and the network it was selected for; the caller
+// holds the mutex
+func (sm *ServiceManager) profileStatus() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        profileName(sm.profile),
+		"interface":   sm.network.Interface,
+		"gateway":     sm.ipToString(sm.network.Gateway),
+		"gateway_mac": sm.network.GatewayMAC.String(),
+		"dhcp_domain": sm.network.DHCPDomain,
+	}
+}
+
+// profileName returns the display name of a profile
+func profileName(profile string) string {
+	if profile == "" {
+		return defaultProfileName
+	}
+	return profile
+}
@@ -0,0 +1,142 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+)
+
+func TestProfileMatches(t *testing.T) {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	_, lan, _ := net.ParseCIDR("192.168.1.20/24")
+	lan.IP = net.ParseIP("192.168.1.20")
+	identity := routing.NetworkIdentity{
+		Interface:  "en0",
+		Gateway:    net.ParseIP("192.168.1.1"),
+		GatewayMAC: mac,
+		Addresses:  []*net.IPNet{lan},
+		DHCPDomain: "corp.example.com",
+	}
+
+	tests := []struct {
+		name  string
+		match config.ProfileMatch
+		want  bool
+	}{
+		{"no criteria", config.ProfileMatch{}, true},
+		{"gateway MAC", config.ProfileMatch{GatewayMAC: "00-11-22-33-44-55"}, true},
+		{"other gateway MAC", config.ProfileMatch{GatewayMAC: "00:11:22:33:44:66"}, false},
+		{"subnet holding the gateway", config.ProfileMatch{Subnet: "192.168.0.0/16"}, true},
+		{"subnet holding an address", config.ProfileMatch{Subnet: "192.168.1.20/32"}, true},
+		{"other subnet", config.ProfileMatch{Subnet: "10.0.0.0/8"}, false},
+		{"interface", config.ProfileMatch{Interface: "en0"}, true},
+		{"other interface", config.ProfileMatch{Interface: "en1"}, false},
+		{"DHCP domain", config.ProfileMatch{DHCPDomain: "Corp.Example.com."}, true},
+		{"other DHCP domain", config.ProfileMatch{DHCPDomain: "example.com"}, false},
+		{"all criteria", config.ProfileMatch{GatewayMAC: "00:11:22:33:44:55", Subnet: "192.168.1.0/24", Interface: "en0", DHCPDomain: "corp.example.com"}, true},
+		{"one criterion failing", config.ProfileMatch{GatewayMAC: "00:11:22:33:44:55", Interface: "en1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := profileMatches(tt.match, identity); got != tt.want {
+				t.Errorf("profileMatches(%+v) = %v, want %v", tt.match, got, tt.want)
+			}
+		})
+	}
+
+	if profileMatches(config.ProfileMatch{GatewayMAC: "00:11:22:33:44:55"}, routing.NetworkIdentity{Interface: "en0"}) {
+		t.Error("Expected a gateway MAC criterion not to match an unresolved gateway")
+	}
+}
+
+func TestNetworkProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	routeFile := writeFile("routes.txt", "1.0.0.0/24\n2.0.0.0/24\n")
+	dnsFile := writeFile("dns.txt", "223.5.5.5\n")
+	officeRoutes := writeFile("office.txt", "4.0.0.0/24\n")
+
+	disabled := false
+	cfg := config.NewConfig()
+	cfg.RouteBackend = "fake"
+	cfg.GatewayProbe = false
+	cfg.PathHealth = false
+	cfg.Profiles = []config.Profile{
+		{Name: "home", Match: config.ProfileMatch{Interface: "en0"}, Exclude: []string{"2.0.0.0/16"}},
+		{Name: "office", Match: config.ProfileMatch{Interface: "en1"}, RouteFile: officeRoutes},
+		{Name: "cafe", Match: config.ProfileMatch{Interface: "en2"}, Enabled: &disabled},
+	}
+
+	router := platform.NewFakeRouteManager(cfg)
+	gateway := net.ParseIP("192.168.1.1")
+	router.SetPhysicalGateway(gateway, "en0")
+	router.SetDefaultRoute(nil, "utun3")
+	sm, err := newServiceManager(cfg, logger.New("error"), router, routeFile, dnsFile)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+
+	if got := sm.profiles.Select(routing.NetworkIdentity{Interface: "en1"}); got != "office" {
+		t.Errorf("Expected the office profile to be selected, got %q", got)
+	}
+	if got := sm.profiles.Select(routing.NetworkIdentity{Interface: "en3"}); got != "" {
+		t.Errorf("Expected no profile to be selected, got %q", got)
+	}
+
+	managedRoutes := func() string {
+		routes, err := router.ListSystemRoutes(context.Background())
+		if err != nil {
+			t.Fatalf("ListSystemRoutes failed: %v", err)
+		}
+		var networks []string
+		for _, route := range routes {
+			if route.Gateway.Equal(gateway) && route.Destination.String() != "0.0.0.0/0" {
+				networks = append(networks, route.Destination.String())
+			}
+		}
+		sort.Strings(networks)
+		return strings.Join(networks, " ")
+	}
+
+	// Switching profiles leaves no routes of the previous profile behind
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{"", "1.0.0.0/24 2.0.0.0/24 223.5.5.5/32"},
+		{"home", "1.0.0.0/24 223.5.5.5/32"},
+		{"office", "223.5.5.5/32 4.0.0.0/24"},
+		{"cafe", ""},
+		{"", "1.0.0.0/24 2.0.0.0/24 223.5.5.5/32"},
+	}
+	for _, tt := range tests {
+		desired := routing.DesiredState{
+			VPNConnected:      true,
+			PhysicalGateway:   gateway,
+			PhysicalInterface: "en0",
+			VPNInterface:      "utun3",
+			Profile:           tt.profile,
+		}
+		if err := sm.applyDesiredState(context.Background(), applyRequest{desired: desired, reason: "test"}); err != nil {
+			t.Fatalf("Applying profile %q failed: %v", tt.profile, err)
+		}
+		if got := managedRoutes(); got != tt.want {
+			t.Errorf("Profile %q: expected managed routes %q, got %q", tt.profile, tt.want, got)
+		}
+	}
+}
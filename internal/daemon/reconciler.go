@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// applyRequest asks the reconciler to converge the route table to a desired state
+type applyRequest struct {
+	desired routing.DesiredState
+	reason  string
+	// verifyOnly requests a drift check: only deviating routes are repaired instead of a full reset
+	verifyOnly bool
+	// pause withdraws all managed routes regardless of desired; the only apply allowed while paused
+	pause bool
+	// resume re-applies desired after a pause; the only request that supersedes a pause
+	resume bool
+}
+
+// supersedes reports whether the request may replace or cancel other. Requests submitted after
+// a pause was decided but before the reconciler ran it would be rejected as paused anyway,
+// so only a resume gets past a pause.
+func (r applyRequest) supersedes(other applyRequest) bool {
+	return !other.pause || r.resume
+}
+
+// merge coalesces a newer request into a pending one. The newer desired state wins unless the
+// pending request is a pause, and a full apply is never downgraded to a verification.
+func (r applyRequest) merge(newer applyRequest) applyRequest {
+	if !newer.supersedes(r) {
+		return r
+	}
+	merged := newer
+	if !r.verifyOnly && r.desired.Equal(newer.desired) {
+		merged.verifyOnly = false
+	}
+	return merged
+}
+
+// applyFunc applies a request; it must return promptly once ctx is cancelled
+type applyFunc func(ctx context.Context, req applyRequest) error
+
+// reconciler serializes all route table work through a single goroutine.
+// Requests are coalesced while an apply is running, and an in-flight apply is cancelled
+// as soon as a request with a different desired state supersedes it.
+type reconciler struct {
+	apply  applyFunc
+	logger *logger.Logger
+
+	mutex          sync.Mutex
+	pending        *applyRequest
+	inFlight       *applyRequest
+	cancelInFlight context.CancelFunc
+	wake           chan struct{}
+}
+
+// newReconciler creates a reconciler that applies requests with the given function
+func newReconciler(apply applyFunc, log *logger.Logger) *reconciler {
+	return &reconciler{
+		apply:  apply,
+		logger: log,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Submit queues a request, replacing any pending one and cancelling a superseded in-flight apply
+func (r *reconciler) Submit(req applyRequest) {
+	r.mutex.Lock()
+	if r.pending != nil {
+		coalesced := r.pending.merge(req)
+		r.logger.Debug("Coalescing pending apply request",
+			"previous_reason", r.pending.reason,
+			"reason", req.reason)
+		req = coalesced
+	}
+
+	if r.inFlight != nil {
+		if req.verifyOnly && r.inFlight.desired.Equal(req.desired) {
+			// The running apply already converges to this state, a verification adds nothing
+			r.mutex.Unlock()
+			return
+		}
+		if !r.inFlight.desired.Equal(req.desired) && req.supersedes(*r.inFlight) && r.cancelInFlight != nil {
+			r.logger.Info("Cancelling superseded route apply",
+				"in_flight_reason", r.inFlight.reason,
+				"reason", req.reason)
+			r.cancelInFlight()
+		}
+	}
+
+	r.pending = &req
+	r.mutex.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes requests one at a time until ctx is cancelled
+func (r *reconciler) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		}
+
+		for {
+			req, applyCtx, ok := r.next(ctx)
+			if !ok {
+				break
+			}
+			r.run(applyCtx, req)
+		}
+	}
+}
+
+// next takes the pending request and marks it in flight
+func (r *reconciler) next(ctx context.Context) (applyRequest, context.Context, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.pending == nil || ctx.Err() != nil {
+		return applyRequest{}, nil, false
+	}
+
+	req := *r.pending
+	r.pending = nil
+	r.inFlight = &req
+
+	applyCtx, cancel := context.WithCancel(ctx)
+	r.cancelInFlight = cancel
+	return req, applyCtx, true
+}
+
+// run executes a single apply and clears the in-flight state
+func (r *reconciler) run(ctx context.Context, req applyRequest) {
+	start := time.Now()
+	err := r.apply(ctx, req)
+
+	r.mutex.Lock()
+	r.cancelInFlight()
+	r.inFlight = nil
+	r.cancelInFlight = nil
+	r.mutex.Unlock()
+
+	duration := time.Since(start).Milliseconds()
+	switch {
+	case err != nil && ctx.Err() != nil:
+		r.logger.Debug("Route apply cancelled", "reason", req.reason, "duration_ms", duration)
+	case err != nil:
+		r.logger.Error("Route apply failed", "reason", req.reason, "error", err, "duration_ms", duration)
+	default:
+		r.logger.Debug("Route apply completed", "reason", req.reason, "duration_ms", duration)
+	}
+}
+
+// InFlight reports whether an apply is currently running
+func (r *reconciler) InFlight() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.inFlight != nil
+}
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+func vpnState(gateway string) routing.DesiredState {
+	return routing.DesiredState{VPNConnected: true, PhysicalGateway: net.ParseIP(gateway)}
+}
+
+func TestReconcilerSerializesAndCancelsSuperseded(t *testing.T) {
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	var applied []string
+	var cancelled []string
+	firstStarted := make(chan struct{})
+	var once sync.Once
+
+	apply := func(ctx context.Context, req applyRequest) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		if n > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, n)
+		}
+
+		if req.reason == "first" {
+			once.Do(func() { close(firstStarted) })
+			select {
+			case <-ctx.Done():
+				mutex.Lock()
+				cancelled = append(cancelled, req.reason)
+				mutex.Unlock()
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+
+		mutex.Lock()
+		applied = append(applied, req.reason)
+		mutex.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := newReconciler(apply, logger.New("error"))
+	go r.Run(ctx)
+
+	r.Submit(applyRequest{desired: vpnState("192.168.1.1"), reason: "first"})
+	<-firstStarted
+
+	// A burst of newer requests while "first" is in flight: only the latest should be applied
+	r.Submit(applyRequest{desired: vpnState("192.168.2.1"), reason: "second"})
+	r.Submit(applyRequest{desired: vpnState("192.168.3.1"), reason: "third"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mutex.Lock()
+		done := len(applied) > 0
+		mutex.Unlock()
+		if done && !r.InFlight() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(cancelled) != 1 || cancelled[0] != "first" {
+		t.Errorf("Expected the first apply to be cancelled, got %v", cancelled)
+	}
+	if len(applied) != 1 || applied[0] != "third" {
+		t.Errorf("Expected only the latest request to be applied, got %v", applied)
+	}
+	if maxRunning != 1 {
+		t.Errorf("Expected at most one concurrent apply, got %d", maxRunning)
+	}
+}
+
+func TestApplyRequestMerge(t *testing.T) {
+	full := applyRequest{desired: vpnState("192.168.1.1"), reason: "vpn_connected"}
+	verify := applyRequest{desired: vpnState("192.168.1.1"), reason: "interval", verifyOnly: true}
+
+	if merged := full.merge(verify); merged.verifyOnly {
+		t.Error("A pending full apply must not be downgraded to a verification")
+	}
+
+	other := applyRequest{desired: vpnState("192.168.2.1"), reason: "interval", verifyOnly: true}
+	if merged := full.merge(other); !merged.verifyOnly || !merged.desired.Equal(other.desired) {
+		t.Error("A newer desired state should replace the pending request")
+	}
+}
+
+func TestApplyRequestMergeKeepsPause(t *testing.T) {
+	pause := applyRequest{reason: "pause", pause: true}
+	verify := applyRequest{desired: vpnState("192.168.1.1"), reason: "interval", verifyOnly: true}
+	if merged := pause.merge(verify); !merged.pause || merged.reason != "pause" {
+		t.Errorf("A pending pause must not be replaced by a verification, got %+v", merged)
+	}
+
+	resume := applyRequest{desired: vpnState("192.168.1.1"), reason: "resume", resume: true}
+	if merged := pause.merge(resume); merged.pause || merged.reason != "resume" {
+		t.Errorf("A resume should replace the pending pause, got %+v", merged)
+	}
+}
+
+func TestReconcilerPausePendingThenVerify(t *testing.T) {
+	var mutex sync.Mutex
+	var applied []string
+	blockerStarted := make(chan struct{})
+	release := make(chan struct{})
+
+	apply := func(ctx context.Context, req applyRequest) error {
+		if req.reason == "blocker" {
+			close(blockerStarted)
+			<-release
+		}
+		mutex.Lock()
+		applied = append(applied, req.reason)
+		mutex.Unlock()
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := newReconciler(apply, logger.New("error"))
+	go r.Run(ctx)
+
+	// Keep the reconciler busy so that the pause stays pending while a drift check submits
+	r.Submit(applyRequest{desired: vpnState("192.168.1.1"), reason: "blocker"})
+	<-blockerStarted
+	r.Submit(applyRequest{reason: "pause", pause: true})
+	r.Submit(applyRequest{desired: vpnState("192.168.1.1"), reason: "drift_check", verifyOnly: true})
+	r.Submit(applyRequest{desired: vpnState("192.168.2.1"), reason: "physical_gateway_changed"})
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mutex.Lock()
+		done := len(applied) >= 2
+		mutex.Unlock()
+		if done && !r.InFlight() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(applied) != 2 || applied[1] != "pause" {
+		t.Errorf("Expected the pending pause to be applied after the blocker, got %v", applied)
+	}
+}
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+)
+
+// replayIgnoredReasons are apply reasons driven by timers, path measurements or control commands
+// rather than by the recorded observations, so a replay cannot be expected to reproduce them
+var replayIgnoredReasons = map[string]bool{
+	"interval":              true,
+	"time_jump":             true,
+	"direct_path_degraded":  true,
+	"direct_path_recovered": true,
+	"pause":                 true,
+	"resume":                true,
+	"pause_expired":         true,
+}
+
+// replayIdleTimeout bounds the wait for the last apply after the trace has been replayed
+const replayIdleTimeout = 30 * time.Second
+
+// ReplayResult holds the decisions of a recording and of its replay
+type ReplayResult struct {
+	Inputs   int      // Checks and observations fed to the monitor
+	Recorded []string // Events and applies in the recording
+	Replayed []string // Events and applies in the replay
+}
+
+// Mismatch returns the index of the first decision that differs, or -1 if all match
+func (r *ReplayResult) Mismatch() int {
+	for i := 0; i < max(len(r.Recorded), len(r.Replayed)); i++ {
+		if i >= len(r.Recorded) || i >= len(r.Replayed) || r.Recorded[i] != r.Replayed[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// Replay runs the observations of a recorded trace through the monitor and the service against a
+// fake route manager, in real time, and returns the decisions made for comparison with the recording.
+// Drift timers, path health, hooks and webhooks are disabled during the replay.
+func Replay(ctx context.Context, cfg *config.Config, log *logger.Logger, entries []routing.TraceEntry, routeFile, dnsFile string) (*ReplayResult, error) {
+	var initial *routing.NetworkState
+	result := &ReplayResult{}
+	for _, entry := range entries {
+		switch {
+		case entry.Kind == routing.TraceKindInit && initial == nil:
+			initial = entry.State
+		case entry.Kind == routing.TraceKindCheck || entry.Kind == routing.TraceKindObserve:
+			result.Inputs++
+		}
+	}
+	if initial == nil {
+		return nil, fmt.Errorf("trace has no initial state")
+	}
+	result.Recorded = replayDecisions(entries)
+
+	replayCfg := *cfg
+	replayCfg.RouteBackend = "fake"
+	replayCfg.GatewayProbe = false
+	replayCfg.PathHealth = false
+	replayCfg.ReconcileInterval = 0
+	replayCfg.Hooks = nil
+	replayCfg.HookDir = ""
+	replayCfg.WebhookURLs = nil
+	replayCfg.EventHistoryFile = ""
+	replayCfg.TraceFile = ""
+	// The trace records no network identities to select profiles by
+	replayCfg.ProfilesFile = ""
+	replayCfg.Profiles = nil
+	// Neither pick up nor clear a pause of the live daemon
+	stateDir, err := os.MkdirTemp("", "smartroute-replay-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replay state directory: %w", err)
+	}
+	defer os.RemoveAll(stateDir)
+	replayCfg.PauseStateFile = filepath.Join(stateDir, "pause.json")
+
+	router := platform.NewFakeRouteManager(&replayCfg)
+	routing.SetReplayedUplinks(router, *initial)
+	router.SetDefaultRoute(initial.DefaultGateway, initial.DefaultInterface)
+
+	sm, err := newServiceManager(&replayCfg, log, router, routeFile, dnsFile)
+	if err != nil {
+		return nil, err
+	}
+	var decisions bytes.Buffer
+	sm.trace = routing.NewTraceRecorder(&decisions)
+	sm.monitor.SetRecorder(sm.trace)
+	done := make(chan struct{})
+	sm.monitor.SetSources(&routing.ReplaySource{Entries: entries, Router: router, Done: done})
+
+	sm.mutex.Lock()
+	err = sm.start()
+	sm.isRunning = err == nil
+	sm.mutex.Unlock()
+	if err != nil {
+		sm.cancel()
+		return nil, err
+	}
+
+	select {
+	case <-done:
+		sm.waitSettled(ctx)
+	case <-ctx.Done():
+	}
+	if err := sm.Stop(); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	replayed, err := routing.ReadTrace(&decisions)
+	if err != nil {
+		return nil, err
+	}
+	result.Replayed = replayDecisions(replayed)
+	return result, nil
+}
+
+// waitSettled waits until pending events have passed their debounce windows and the reconciler is idle
+func (sm *ServiceManager) waitSettled(ctx context.Context) {
+	settle := sm.config.VPNLossHoldDown
+	for _, window := range sm.config.EventDebounce {
+		settle = max(settle, window)
+	}
+	timer := time.NewTimer(settle + time.Second)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+		return
+	}
+
+	deadline := time.Now().Add(replayIdleTimeout)
+	for sm.reconciler.InFlight() && time.Now().Before(deadline) && ctx.Err() == nil {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// replayDecisions returns the comparable decisions of a trace
+func replayDecisions(entries []routing.TraceEntry) []string {
+	var decisions []string
+	for _, entry := range entries {
+		switch {
+		case entry.Kind == routing.TraceKindEvent && entry.Event != nil:
+			decisions = append(decisions, entry.Event.String())
+		case entry.Kind == routing.TraceKindApply && entry.Apply != nil && !replayIgnoredReasons[entry.Apply.Reason]:
+			decisions = append(decisions, entry.Apply.String())
+		}
+	}
+	return decisions
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+func TestReplay(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.EventDebounce = nil
+	cfg.VPNLossHoldDown = 100 * time.Millisecond
+
+	gateway := net.ParseIP("192.168.1.1")
+	direct := &routing.NetworkState{PhysicalGateway: gateway, PhysicalInterface: "en0", DefaultGateway: gateway, DefaultInterface: "en0"}
+	vpn := &routing.NetworkState{PhysicalGateway: gateway, PhysicalInterface: "en0", DefaultInterface: "utun3"}
+	connected := routing.TraceApply{
+		Reason:            "vpn_connected",
+		VPNConnected:      true,
+		PhysicalGateway:   gateway,
+		PhysicalInterface: "en0",
+		VPNInterface:      "utun3",
+		RoutesAdded:       100,
+	}
+	// Drift verifications depend on timers, not on the recorded observations
+	verified := connected
+	verified.Reason = "interval"
+	verified.VerifyOnly = true
+
+	entries := []routing.TraceEntry{
+		{Kind: routing.TraceKindInit, State: direct},
+		{Offset: 50 * time.Millisecond, Kind: routing.TraceKindTrigger, Source: "netlink"},
+		{Offset: 50 * time.Millisecond, Kind: routing.TraceKindCheck, State: vpn},
+		{Offset: 50 * time.Millisecond, Kind: routing.TraceKindEvent, Event: &routing.TraceEvent{
+			Type:              routing.VPNConnected.String(),
+			VPNConnected:      true,
+			PhysicalGateway:   gateway,
+			PhysicalInterface: "en0",
+			VPNInterface:      "utun3",
+		}},
+		{Offset: 80 * time.Millisecond, Kind: routing.TraceKindApply, Apply: &connected},
+		{Offset: 90 * time.Millisecond, Kind: routing.TraceKindApply, Apply: &verified},
+	}
+
+	result, err := Replay(context.Background(), cfg, logger.New("error"), entries, "", "")
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if result.Inputs != 1 || len(result.Recorded) != 2 {
+		t.Fatalf("Expected 1 input and 2 recorded decisions, got %+v", result)
+	}
+	if mismatch := result.Mismatch(); mismatch >= 0 {
+		t.Fatalf("Expected the replay to match, decision %d differs: %+v", mismatch, result)
+	}
+
+	// The VPN dropping for longer than the hold-down must be acted on, unlike in this recording
+	entries = append(entries, routing.TraceEntry{Offset: 300 * time.Millisecond, Kind: routing.TraceKindCheck, State: direct})
+	result, err = Replay(context.Background(), cfg, logger.New("error"), entries, "", "")
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if mismatch := result.Mismatch(); mismatch != 2 {
+		t.Fatalf("Expected the disconnect to differ from the recording, got mismatch %d in %+v", mismatch, result)
+	}
+}
+
+func TestReplayWithoutInitialState(t *testing.T) {
+	_, err := Replay(context.Background(), config.NewConfig(), logger.New("error"), nil, "", "")
+	if err == nil {
+		t.Fatal("Expected a trace without an initial state to be rejected")
+	}
+}
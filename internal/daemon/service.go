@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -11,9 +12,15 @@ import (
 	"time"
 
 	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/control"
+	"github.com/wesleywu/smart-route/internal/history"
 	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/notify"
 	"github.com/wesleywu/smart-route/internal/routing"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
 	"github.com/wesleywu/smart-route/internal/routing/types"
+	"github.com/wesleywu/smart-route/internal/utils"
 )
 
 // ServiceManager is a manager for the service
@@ -21,9 +28,25 @@ type ServiceManager struct {
 	config       *config.Config
 	logger       *logger.Logger
 	monitor      *routing.NetworkMonitor
+	external     *routing.ExternalSource // signals received through the control socket
 	router       types.RouteManager
 	routeSwitch  *routing.RouteSwitch
 	managedIPSet        *config.IPSet
+	profiles     *networkProfiles
+	profile      string                  // selected network profile, "" if none matches
+	network      routing.NetworkIdentity // network the profile was selected for
+	metrics      *metrics.Metrics
+	reconciler   *reconciler
+	state        *stateMachine
+	debouncer    *eventDebouncer
+	pathHealth   *routing.PathHealthChecker // nil when direct path monitoring is disabled
+	events       *routing.EventSubscription
+	hooks        *hookRunner
+	notifier     *notify.WebhookNotifier // nil when no webhook is configured
+	history      *history.History
+	control      *control.Server
+	trace        *routing.TraceRecorder // nil unless recording a trace
+	lastApplied  *routing.DesiredState // last desired state that was applied successfully
 	stopChan     chan os.Signal
 	doneChan     chan struct{}
 	ctx          context.Context
@@ -33,41 +56,100 @@ type ServiceManager struct {
 	currentGW    net.IP
 	currentIface string
 	lastCheck    time.Time
+	pausedUntil  time.Time   // zero while not paused or paused until resumed
+	pauseTimer   *time.Timer // resumes an expiring pause
+
+	// Managed routes withdrawn from a degraded direct path
+	directPathDegraded bool
+	withdrawn          []net.IPNet
 }
 
 // NewServiceManager creates a new ServiceManager
 func NewServiceManager(cfg *config.Config, log *logger.Logger, routeFile, dnsFile string) (*ServiceManager, error) {
+	router, err := routing.NewPlatformRouteManager(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create route manager: %w", err)
+	}
+	return newServiceManager(cfg, log, router, routeFile, dnsFile)
+}
+
+// newServiceManager creates a ServiceManager on top of the given route manager
+func newServiceManager(cfg *config.Config, log *logger.Logger, router types.RouteManager, routeFile, dnsFile string) (*ServiceManager, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	sm := &ServiceManager{
-		config:    cfg,
-		logger:    log.WithComponent("service"),
-		stopChan:  make(chan os.Signal, 1),
-		doneChan:  make(chan struct{}),
-		ctx:       ctx,
-		cancel:    cancel,
+		config:     cfg,
+		logger:     log.WithComponent("service"),
+		stopChan:   make(chan os.Signal, 1),
+		doneChan:   make(chan struct{}),
+		metrics:    metrics.NewMetrics(),
+		ctx:        ctx,
+		cancel:     cancel,
+		router:     router,
 	}
 
 	var err error
-	sm.router, err = routing.NewPlatformRouteManager(cfg.ConcurrencyLimit, cfg.RetryAttempts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create route manager: %w", err)
-	}
-
 	sm.monitor, err = routing.NewNetworkMonitor(cfg.MonitorInterval, sm.router, sm.logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create network monitor: %w", err)
 	}
+	sm.external = routing.NewExternalSource()
+	sm.monitor.AddSource(sm.external)
+	if len(cfg.UplinkLists) > 0 {
+		sm.monitor.TrackUplinks()
+	}
+	if cfg.TraceFile != "" {
+		sm.trace, err = routing.CreateTraceRecorder(cfg.TraceFile)
+		if err != nil {
+			return nil, err
+		}
+		sm.monitor.SetRecorder(sm.trace)
+	}
 
 	sm.managedIPSet, err = config.LoadManagedIPSetWithFallback(routeFile, dnsFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load Chinese routes and DNS: %w", err)
 	}
+	sm.profiles, err = newNetworkProfiles(cfg, sm.managedIPSet, routeFile, dnsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load network profiles: %w", err)
+	}
 	// Initialize route switch with unified logic
 	sm.routeSwitch, err = routing.NewRouteSwitch(sm.router, sm.managedIPSet, sm.logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create route switch: %w", err)
 	}
+	sm.routeSwitch.SetKnownIPSet(sm.profiles.known)
+	if len(cfg.UplinkLists) > 0 {
+		assignments, err := routing.LoadUplinkAssignments(cfg.UplinkLists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load uplink lists: %w", err)
+		}
+		sm.routeSwitch.SetUplinkAssignments(assignments)
+	}
+	sm.routeSwitch.SetRetryPolicy(batch.NewRetryPolicy(cfg))
+	if cfg.GatewayProbe {
+		sm.routeSwitch.SetGatewayProber(routing.NewGatewayProber(cfg, sm.logger))
+	}
+	if cfg.PathHealth {
+		sm.pathHealth, err = routing.NewPathHealthChecker(cfg, sm.logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create path health checker: %w", err)
+		}
+	}
+	sm.hooks = newHookRunner(cfg, sm.logger)
+	sm.notifier = notify.NewWebhookNotifier(cfg, sm.logger)
+	sm.history, err = newHistory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event history: %w", err)
+	}
+	sm.control = control.NewServer(ControlSocketPath(cfg), sm.logger)
+	sm.registerControlHandlers(sm.control)
+	sm.reconciler = newReconciler(sm.applyDesiredState, sm.logger)
+	sm.state = newStateMachine(sm.logger, func(transition StateTransition) {
+		sm.metrics.RecordStateTransition(transition.From, transition.To)
+	})
+	sm.debouncer = newEventDebouncer(cfg, sm.logger)
 
 	return sm, nil
 }
@@ -87,30 +169,69 @@ func (sm *ServiceManager) Start() error {
 
 	signal.Notify(sm.stopChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
+	if err := sm.start(); err != nil {
+		return err
+	}
+
+	// The control socket is a convenience for the CLI; routing works without it
+	if err := sm.control.Start(sm.ctx); err != nil {
+		sm.logger.Error("failed to start control socket", "error", err)
+	}
+	sm.isRunning = true
+
+	return nil
+}
+
+// start sets up the initial routes and starts monitoring; the caller holds the mutex
+func (sm *ServiceManager) start() error {
 	sm.logger.ServiceStart("1.0.0", fmt.Sprintf("%d", os.Getpid()))
 	sm.logger.Info("Managed IP set loaded",
 		"managed_ip_set_size", sm.managedIPSet.Size(),
 	)
 
-	gw, iface, err := sm.router.GetPhysicalGateway()
+	gw, iface, err := sm.router.GetPhysicalGateway(sm.ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get default gateway: %w", err)
 	}
 	sm.currentGW = gw
 	sm.currentIface = iface
-
-	if err := sm.routeSwitch.InitRoutes(); err != nil {
-		return fmt.Errorf("failed to setup initial routes: %w", err)
+	sm.setProfile(sm.identifyProfile(gw, iface))
+	sm.routeSwitch.SetManagedIPSet(sm.profiles.IPSet(sm.profile))
+	sm.routeSwitch.SetUplinkListsEnabled(sm.profiles.IsEnabled(sm.profile))
+
+	if sm.restorePause() {
+		// Make sure nothing was left behind, e.g. by a crash before the routes were withdrawn
+		if _, err := sm.routeSwitch.CleanRoutes(sm.ctx); err != nil {
+			return fmt.Errorf("failed to withdraw managed routes: %w", err)
+		}
+	} else {
+		// An unreachable gateway is not fatal: the VPN path is kept and the monitor retries on the next change
+		err = sm.routeSwitch.InitRoutes(sm.ctx)
+		if err != nil && !errors.Is(err, routing.ErrGatewayUnreachable) {
+			return fmt.Errorf("failed to setup initial routes: %w", err)
+		}
+		sm.resetState(err)
 	}
 
+	// Subscribe before starting the monitor so that no event is missed. The service must see every
+	// event, while the metrics only count them and would rather drop some than delay the monitor.
+	sm.events = sm.monitor.Subscribe("service", routing.SubscribeOptions{Buffer: 100, Policy: routing.Block})
+	changes := sm.monitor.Subscribe("metrics", routing.SubscribeOptions{Buffer: 16, Policy: routing.DropOldest})
+
 	if err := sm.monitor.Start(); err != nil {
 		return fmt.Errorf("failed to start network monitor: %w", err)
 	}
 
 	sm.logger.MonitorStart(sm.config.MonitorInterval.String())
 
+	if sm.notifier != nil {
+		sm.notifier.Start(sm.ctx)
+	}
+	go sm.reconciler.Run(sm.ctx)
 	go sm.serviceLoop()
-	sm.isRunning = true
+	go sm.metricsLoop(changes)
+	go sm.driftLoop()
+	go sm.pathHealthLoop()
 
 	return nil
 }
@@ -128,6 +249,10 @@ func (sm *ServiceManager) Stop() error {
 
 	sm.cancel()
 	close(sm.stopChan)
+	sm.debouncer.Stop()
+	if sm.pauseTimer != nil {
+		sm.pauseTimer.Stop()
+	}
 
 	if err := sm.monitor.Stop(); err != nil {
 		sm.logger.Error("failed to stop network monitor", "error", err)
@@ -137,6 +262,18 @@ func (sm *ServiceManager) Stop() error {
 		sm.logger.Error("failed to close route manager", "error", err)
 	}
 
+	sm.hooks.Wait()
+	if sm.notifier != nil {
+		sm.notifier.Stop()
+	}
+	sm.control.Stop()
+	if err := sm.history.Close(); err != nil {
+		sm.logger.Error("failed to close event history", "error", err)
+	}
+	if err := sm.trace.Close(); err != nil {
+		sm.logger.Error("failed to close trace", "error", err)
+	}
+
 	sm.logger.MonitorStop()
 	sm.isRunning = false
 
@@ -167,13 +304,35 @@ func (sm *ServiceManager) serviceLoop() {
 		select {
 		case <-sm.ctx.Done():
 			return
-		case event := <-sm.monitor.Events():
+		case event, ok := <-sm.events.Events():
+			if !ok {
+				return
+			}
+			switch event.EventType {
+			case routing.DirectPathDegraded, routing.DirectPathRecovered:
+				// Already settled by the path health hysteresis
+				sm.handleNetworkEvent(event)
+			case routing.PollFallbackEnabled:
+				// Signals how the network is monitored, not a network change to settle
+				sm.handleNetworkEvent(event)
+			default:
+				sm.debouncer.Submit(event)
+			}
+		case event := <-sm.debouncer.Events():
 			sm.handleNetworkEvent(event)
 		}
 	}
 }
 
-// handleNetworkEvent handles network events
+// metricsLoop counts the network events seen by the monitor
+func (sm *ServiceManager) metricsLoop(changes *routing.EventSubscription) {
+	for range changes.Events() {
+		sm.metrics.RecordNetworkChange()
+	}
+}
+
+// handleNetworkEvent handles network events by translating them into desired states for the reconciler.
+// No route work happens here; the reconciler goroutine performs all applies one at a time.
 func (sm *ServiceManager) handleNetworkEvent(event routing.NetworkEvent) {
 	// Cache frequently used string conversions and field values
 	eventType := event.EventType.String()
@@ -193,6 +352,26 @@ func (sm *ServiceManager) handleNetworkEvent(event routing.NetworkEvent) {
 			"is_vpn", vpnConnected)
 	}
 
+	if event.EventType == routing.PhysicalGatewayChanged {
+		// Direct path health verdicts belong to the previous network, and so does its profile
+		sm.clearPathWithdrawal()
+		sm.evaluateProfile(event.PhysicalGateway, physicalInterface)
+	}
+	desired := sm.withProfile(sm.withPathHealth(routing.DesiredState{
+		VPNConnected:      vpnConnected,
+		PhysicalGateway:   event.PhysicalGateway,
+		PhysicalInterface: physicalInterface,
+		VPNInterface:      vpnInterface,
+		Uplinks:           event.Uplinks,
+	}))
+	sm.recordEvent(eventType, desired)
+	if sm.isPaused() {
+		sm.logger.Debug("Split routing paused, ignoring network event", "type", eventType)
+		return
+	}
+	sm.fireHook(eventType, desired, "", nil)
+	sm.notify(eventType, "", desired, nil)
+
 	switch event.EventType {
 	case routing.PhysicalGatewayChanged:
 		if vpnConnected {
@@ -208,90 +387,168 @@ func (sm *ServiceManager) handleNetworkEvent(event routing.NetworkEvent) {
 		}
 		
 		// 只在VPN连接状态下处理WiFi切换，使用物理网关重新设置路由
-		if vpnConnected {
-			if err := sm.handlePhysicalGatewayChange(event.PhysicalGateway); err != nil {
-				sm.logger.Error("failed to handle physical gateway change", "error", err)
-			}
-		} else {
-			sm.logger.Debug("VPN not connected, skipping physical gateway change handling")
-		}
+		sm.handlePhysicalGatewayChange(desired, "physical_gateway_changed")
 		
 	case routing.VPNConnected:
 		// VPN连接时，使用物理网关设置中国路由
-		if err := sm.handleVPNConnection(event.PhysicalGateway, vpnInterface); err != nil {
-			sm.logger.Error("failed to handle VPN connection", "error", err)
+		sm.logger.Info("VPN connected",
+			"vpn_interface", vpnInterface,
+			"physical_gateway", physicalGateway)
+		// A reconnect within the VPN loss hold-down left the routes in place, so verifying them is enough
+		verifyOnly := sm.isApplied(desired)
+		if verifyOnly {
+			sm.logger.Debug("Routes already applied for this state, verifying instead of resetting")
 		}
+		sm.reconciler.Submit(applyRequest{desired: desired, reason: "vpn_connected", verifyOnly: verifyOnly})
 		
 	case routing.VPNDisconnected:
 		// VPN断开时，清理所有管理的路由（不需要网关参数）
-		if err := sm.handleVPNDisconnection(); err != nil {
-			sm.logger.Error("failed to handle VPN disconnection", "error", err)
-		}
+		sm.reconciler.Submit(applyRequest{desired: desired, reason: "vpn_disconnected"})
 	case routing.NetworkAddressChanged:
 		// For address changes, also check if gateway has changed
 		// This is a backup mechanism in case gateway change detection is not perfect
-		go func() {
-			time.Sleep(500 * time.Millisecond) // Allow network to stabilize
-			sm.checkAndHandlePhysicalGatewayChange()
-		}()
+		// The event debounce window allows the network to stabilize before checking
+		sm.checkAndHandlePhysicalGatewayChange()
+	case routing.DirectPathDegraded:
+		sm.logger.Warn("Direct path degraded, keeping affected networks on the VPN",
+			"physical_interface", physicalInterface,
+			"all_networks", desired.DirectPathDegraded,
+			"withdrawn_networks", len(desired.Withdrawn))
+		sm.checkDrift("direct_path_degraded")
+	case routing.DirectPathRecovered:
+		sm.logger.Info("Direct path recovered, restoring managed routes",
+			"physical_interface", physicalInterface)
+		sm.checkDrift("direct_path_recovered")
 	}
 }
 
-// handlePhysicalGatewayChange handles physical gateway changes (WiFi switching in VPN environment)
-func (sm *ServiceManager) handlePhysicalGatewayChange(newGW net.IP) error {
+// handlePhysicalGatewayChange handles physical gateway changes (WiFi switching in VPN environment);
+// the state machine skips them while the VPN is off, as no managed routes depend on the uplink then
+func (sm *ServiceManager) handlePhysicalGatewayChange(desired routing.DesiredState, reason string) {
+	if !sm.state.Fire(inputGatewayChanged, desired, reason) {
+		return
+	}
+	sm.reconciler.Submit(applyRequest{desired: desired, reason: reason})
+}
 
-	// Use unified route switch logic
-	if err := sm.routeSwitch.SetupRoutes(newGW); err != nil {
-		sm.logger.Error("failed to switch routes", "error", err)
-		return err
+// applyDesiredState converges the route table to the requested state.
+// It is only ever called from the reconciler goroutine, so applies never interleave.
+func (sm *ServiceManager) applyDesiredState(ctx context.Context, req applyRequest) error {
+	if req.pause {
+		return sm.withdrawRoutes(ctx, req)
+	}
+	input := inputApplyStarted
+	if req.verifyOnly {
+		input = inputVerifyStarted
+	}
+	if !sm.state.Fire(input, req.desired, req.reason) {
+		return nil
 	}
+	sm.routeSwitch.SetManagedIPSet(sm.profiles.IPSet(req.desired.Profile))
+	sm.routeSwitch.SetUplinkListsEnabled(sm.profiles.IsEnabled(req.desired.Profile))
+	sm.routeSwitch.SetUplinks(req.desired.Uplinks)
+
+	start := time.Now()
+	var diff routing.RouteDiff
+	var err error
+	switch {
+	case req.verifyOnly:
+		diff, err = sm.repairDrift(ctx, req.desired, req.reason)
+	case req.desired.VPNConnected && req.desired.DirectPathDegraded:
+		// The whole direct path is withdrawn, managed traffic stays on the VPN
+		diff, err = sm.routeSwitch.CleanRoutes(ctx)
+	case req.desired.VPNConnected:
+		diff, err = sm.applyVPNRoutes(ctx, req.desired)
+	default:
+		diff, err = sm.cleanVPNRoutes(ctx)
+	}
+	sm.recordApply(req, time.Since(start), diff, err)
 
-	// Update current gateway after successful transition
 	sm.mutex.Lock()
-	sm.currentGW = newGW
+	if err == nil {
+		desired := req.desired
+		sm.lastApplied = &desired
+	} else {
+		sm.lastApplied = nil
+	}
 	sm.mutex.Unlock()
 
-	// Note: Removed route cache flush as it was clearing all routes including the ones we just added
-	// The route changes should take effect immediately without flushing the entire route cache
+	// A superseded apply is neither a success nor a failure, the superseding apply settles the state.
+	// Hooks only hear about applies that changed something.
+	switch {
+	case err == nil:
+		sm.state.Fire(inputApplySucceeded, req.desired, req.reason)
+		if diff.Changed() {
+			sm.fireHook(hookApplySucceeded, req.desired, req.reason, nil)
+		}
+	case ctx.Err() == nil:
+		sm.state.Fire(inputApplyFailed, req.desired, req.reason)
+		sm.fireHook(hookApplyFailed, req.desired, req.reason, err)
+		sm.notify(hookApplyFailed, req.reason, req.desired, map[string]interface{}{"error": err.Error()})
+	}
 
-	return nil
+	return err
 }
 
-// handleVPNConnection handles VPN connection events
-func (sm *ServiceManager) handleVPNConnection(physicalGW net.IP, vpnInterface string) error {
+// withdrawRoutes removes all managed routes on pause, leaving the connection state Paused
+func (sm *ServiceManager) withdrawRoutes(ctx context.Context, req applyRequest) error {
+	start := time.Now()
+	diff, err := sm.routeSwitch.CleanRoutes(ctx)
+	sm.recordApply(req, time.Since(start), diff, err)
 
-	sm.logger.Info("VPN connected",
-		"vpn_interface", vpnInterface,
-		"physical_gateway", physicalGW.String())
+	sm.mutex.Lock()
+	sm.lastApplied = nil
+	sm.mutex.Unlock()
 
+	if err != nil && ctx.Err() == nil {
+		sm.fireHook(hookApplyFailed, req.desired, req.reason, err)
+		sm.notify(hookApplyFailed, req.reason, req.desired, map[string]interface{}{"error": err.Error()})
+	}
+	return err
+}
+
+// isApplied reports whether the last successful apply converged to the given desired state
+func (sm *ServiceManager) isApplied(desired routing.DesiredState) bool {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.lastApplied != nil && sm.lastApplied.Equal(desired)
+}
+
+// applyVPNRoutes sets up managed routes via the physical gateway while the VPN is connected
+func (sm *ServiceManager) applyVPNRoutes(ctx context.Context, desired routing.DesiredState) (routing.RouteDiff, error) {
 	// Use unified route switch logic with physical gateway
-	if err := sm.routeSwitch.SetupRoutes(physicalGW); err != nil {
+	diff, err := sm.routeSwitch.SetupRoutes(ctx, desired.Uplink(), desired.Withdrawn...)
+	if err != nil {
 		sm.logger.Error("failed to switch routes", "error", err)
-		return err
+		return diff, err
 	}
 
 	// Update current gateway after successful transition
 	sm.mutex.Lock()
-	sm.currentGW = physicalGW
+	sm.currentGW = desired.PhysicalGateway
+	if desired.PhysicalInterface != "" {
+		sm.currentIface = desired.PhysicalInterface
+	}
 	sm.mutex.Unlock()
 
 	// Note: Removed route cache flush as it was clearing all routes including the ones we just added
 	// The route changes should take effect immediately without flushing the entire route cache
 
-	return nil
+	return diff, nil
 }
 
-// handleVPNDisconnection handles VPN disconnection events - no gateway parameter needed since we're cleaning all routes
-func (sm *ServiceManager) handleVPNDisconnection() error {
+// cleanVPNRoutes removes all managed routes after VPN disconnection - no gateway needed since we're cleaning all routes
+func (sm *ServiceManager) cleanVPNRoutes(ctx context.Context) (routing.RouteDiff, error) {
 	sm.mutex.Lock()
 	oldGW := sm.currentGW
 	oldIface := sm.currentIface
 	sm.mutex.Unlock()
 
 	// Clean all managed routes - gateway-independent operation
-	if err := sm.routeSwitch.CleanRoutes(); err != nil {
+	diff, err := sm.routeSwitch.CleanRoutes(ctx)
+	if err != nil {
 		sm.logger.Error("failed to clean routes", "error", err)
-		return err
+		return diff, err
 	}
 
 	// Note: We don't update currentGW here since VPN disconnection doesn't change the physical gateway
@@ -301,7 +558,7 @@ func (sm *ServiceManager) handleVPNDisconnection() error {
 		"physical_gateway", sm.ipToString(oldGW),
 		"physical_interface", oldIface)
 
-	return nil
+	return diff, nil
 }
 
 // checkAndHandlePhysicalGatewayChange checks and handles physical gateway changes
@@ -316,11 +573,12 @@ func (sm *ServiceManager) checkAndHandlePhysicalGatewayChange() {
 	sm.lastCheck = now
 	sm.mutex.Unlock()
 
-	currentGW, currentIface, err := sm.router.GetPhysicalGateway()
+	desired, err := sm.currentDesiredState()
 	if err != nil {
 		sm.logger.Error("failed to get current gateway during check", "error", err)
 		return
 	}
+	currentGW, currentIface := desired.PhysicalGateway, desired.PhysicalInterface
 
 	sm.mutex.RLock()
 	gatewayChanged := !sm.currentGW.Equal(currentGW)
@@ -335,11 +593,45 @@ func (sm *ServiceManager) checkAndHandlePhysicalGatewayChange() {
 			"old_physical_interface", oldIface,
 			"new_gateway", currentGW.String(),
 			"new_physical_interface", currentIface)
+		sm.notify(routing.PhysicalGatewayChanged.String(), "gateway_change_detected", desired, map[string]interface{}{
+			"old_gateway":            sm.ipToString(oldGW),
+			"old_physical_interface": oldIface,
+		})
 
-		if err := sm.handlePhysicalGatewayChange(currentGW); err != nil {
-			sm.logger.Error("failed to handle detected gateway change", "error", err)
+		desired.Profile = sm.evaluateProfile(currentGW, currentIface)
+		sm.handlePhysicalGatewayChange(desired, "gateway_change_detected")
+	}
+}
+
+// currentDesiredState derives the desired state from the live system routing table
+func (sm *ServiceManager) currentDesiredState() (routing.DesiredState, error) {
+	_, currentIface, err := sm.router.GetSystemDefaultRoute(sm.ctx)
+	if err != nil {
+		return routing.DesiredState{}, fmt.Errorf("failed to get default route: %w", err)
+	}
+	vpnConnected := utils.IsVPNInterface(currentIface)
+
+	physicalGW, physicalIface, err := sm.router.GetPhysicalGateway(sm.ctx)
+	if err != nil && vpnConnected {
+		return routing.DesiredState{}, fmt.Errorf("failed to get physical gateway: %w", err)
+	}
+
+	desired := routing.DesiredState{
+		VPNConnected:      vpnConnected,
+		PhysicalGateway:   physicalGW,
+		PhysicalInterface: physicalIface,
+	}
+	if vpnConnected {
+		desired.VPNInterface = currentIface
+	}
+	if len(sm.config.UplinkLists) > 0 {
+		// Without the other uplinks the uplink lists go through the physical gateway
+		desired.Uplinks, err = routing.ListUplinks(sm.ctx, sm.router)
+		if err != nil {
+			sm.logger.Debug("Failed to list physical uplinks", "error", err)
 		}
 	}
+	return sm.withProfile(sm.withPathHealth(desired)), nil
 }
 
 // flushRouteCache was removed because it was causing route loss
@@ -381,6 +673,45 @@ func (sm *ServiceManager) GetStatus() map[string]interface{} {
 		"running":             sm.isRunning,
 		"current_gateway":     sm.currentGW.String(),
 		"current_interface":   sm.currentIface,
-		"managed_ip_set_size": sm.managedIPSet.Size(),
+		"managed_ip_set_size": sm.profiles.IPSet(sm.profile).Size(),
+		"network_changes":     sm.metrics.GetNetworkChanges(),
+		"drift":               sm.metrics.GetDriftStats(),
+		"path_health":         sm.metrics.GetPathHealthStats(),
+		"apply_in_flight":     sm.reconciler.InFlight(),
+		"connection":          sm.state.Status(),
+		"paused_until":        sm.pausedUntil,
+		"profile":             sm.profileStatus(),
+		"connection_stats":    sm.metrics.GetStateStats(),
+		"route_operations":    sm.routeOperationStats(),
+	}
+}
+
+// routeOperationStats returns the route operation statistics of the route manager, including
+// retries, or nil if it keeps none
+func (sm *ServiceManager) routeOperationStats() map[string]interface{} {
+	reporter, ok := sm.router.(types.MetricsReporter)
+	if !ok || reporter.Metrics() == nil {
+		return nil
+	}
+	return reporter.Metrics().GetOperationStats()
+}
+
+// resetState sets the initial connection state once the initial routes are in place. No direct
+// path verdict exists yet, so an unreachable gateway is the only way to start out degraded.
+// The caller holds the mutex.
+func (sm *ServiceManager) resetState(initErr error) {
+	state := StateDegraded
+	if initErr == nil {
+		_, iface, err := sm.router.GetSystemDefaultRoute(sm.ctx)
+		switch {
+		case err != nil:
+			state = StateError
+		case utils.IsVPNInterface(iface):
+			state = StateVPNActive
+		default:
+			state = StateIdle
+		}
 	}
+	sm.state.Reset(state)
+	sm.metrics.RecordStateTransition("", state.String())
 }
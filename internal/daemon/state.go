@@ -0,0 +1,243 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+// stateHistorySize is the number of transitions kept for status
+const stateHistorySize = 50
+
+// ConnectionState is the state of the daemon's connection state machine
+type ConnectionState int
+
+const (
+	// StateIdle means the VPN is down and no managed routes are installed
+	StateIdle ConnectionState = iota
+	// StateVPNActive means the VPN is up and every managed network goes direct
+	StateVPNActive
+	// StateApplying means routes are being changed towards a new desired state
+	StateApplying
+	// StateDegraded means the VPN is up but some or all managed networks are kept on the VPN,
+	// because the direct path is unhealthy or the physical gateway is unreachable
+	StateDegraded
+	// StatePaused means route changes are suspended
+	StatePaused
+	// StateError means the last apply failed and the routing table may not match the desired state
+	StateError
+)
+
+// String returns the string representation of the state
+func (s ConnectionState) String() string {
+	switch s {
+	case StateIdle:
+		return "Idle"
+	case StateVPNActive:
+		return "VPNActive"
+	case StateApplying:
+		return "Applying"
+	case StateDegraded:
+		return "Degraded"
+	case StatePaused:
+		return "Paused"
+	case StateError:
+		return "Error"
+	default:
+		return "Unknown"
+	}
+}
+
+// stateInput is an input of the connection state machine
+type stateInput int
+
+const (
+	inputGatewayChanged stateInput = iota // the physical uplink changed
+	inputApplyStarted                     // a full apply is about to run
+	inputVerifyStarted                    // a drift verification is about to run
+	inputApplySucceeded                   // an apply or drift verification succeeded
+	inputApplyFailed                      // an apply or drift verification failed, and was not superseded
+	inputPause                            // route changes are suspended
+	inputResume                           // route changes are resumed
+)
+
+// String returns the string representation of the input
+func (i stateInput) String() string {
+	switch i {
+	case inputGatewayChanged:
+		return "gateway_changed"
+	case inputApplyStarted:
+		return "apply_started"
+	case inputVerifyStarted:
+		return "verify_started"
+	case inputApplySucceeded:
+		return "apply_succeeded"
+	case inputApplyFailed:
+		return "apply_failed"
+	case inputPause:
+		return "pause"
+	case inputResume:
+		return "resume"
+	default:
+		return "unknown"
+	}
+}
+
+// StateTransition is a recorded change of the connection state
+type StateTransition struct {
+	Time   time.Time `json:"time"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Input  string    `json:"input"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// transition returns the state an input leads to, and false if a guard rejects the input.
+// It is a pure function of its arguments so that every transition can be tested in isolation.
+func transition(from ConnectionState, input stateInput, desired routing.DesiredState) (ConnectionState, bool) {
+	switch input {
+	case inputGatewayChanged:
+		// Managed routes only exist while the VPN is up, so with the VPN down there is nothing to move
+		return from, from != StatePaused && desired.VPNConnected
+	case inputApplyStarted:
+		if from == StatePaused {
+			return from, false
+		}
+		return StateApplying, true
+	case inputVerifyStarted:
+		// A verification only repairs drift, the state changes once its outcome is known
+		return from, from != StatePaused
+	case inputApplySucceeded:
+		if from == StatePaused {
+			return from, false
+		}
+		return settledState(desired), true
+	case inputApplyFailed:
+		if from == StatePaused {
+			return from, false
+		}
+		return StateError, true
+	case inputPause:
+		return StatePaused, from != StatePaused
+	case inputResume:
+		// Resuming re-applies the current state, whatever happened while paused
+		if from != StatePaused {
+			return from, false
+		}
+		return StateApplying, true
+	default:
+		return from, false
+	}
+}
+
+// settledState returns the state reached once desired has been applied
+func settledState(desired routing.DesiredState) ConnectionState {
+	switch {
+	case !desired.VPNConnected:
+		return StateIdle
+	case desired.DirectPathDegraded || len(desired.Withdrawn) > 0:
+		return StateDegraded
+	default:
+		return StateVPNActive
+	}
+}
+
+// stateMachine tracks the connection state of the daemon and the transitions leading to it
+type stateMachine struct {
+	logger   *logger.Logger
+	onChange func(transition StateTransition) // called with the mutex released, may be nil
+
+	mutex   sync.Mutex
+	state   ConnectionState
+	since   time.Time
+	history []StateTransition // oldest first, at most stateHistorySize
+}
+
+// newStateMachine creates a state machine in the Idle state
+func newStateMachine(log *logger.Logger, onChange func(transition StateTransition)) *stateMachine {
+	return &stateMachine{
+		logger:   log,
+		onChange: onChange,
+		state:    StateIdle,
+		since:    time.Now(),
+	}
+}
+
+// Reset sets the state without a transition, e.g. once the initial routes are in place
+func (m *stateMachine) Reset(state ConnectionState) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.state = state
+	m.since = time.Now()
+}
+
+// Fire feeds an input to the state machine and reports whether it was accepted; the caller
+// must skip the action the input announces when it is rejected
+func (m *stateMachine) Fire(input stateInput, desired routing.DesiredState, reason string) bool {
+	m.mutex.Lock()
+	from := m.state
+	to, ok := transition(from, input, desired)
+	if !ok {
+		m.mutex.Unlock()
+		m.logger.Debug("State machine input rejected",
+			"state", from.String(),
+			"input", input.String(),
+			"reason", reason)
+		return false
+	}
+	if to == from {
+		m.mutex.Unlock()
+		return true
+	}
+
+	change := StateTransition{
+		Time:   time.Now(),
+		From:   from.String(),
+		To:     to.String(),
+		Input:  input.String(),
+		Reason: reason,
+	}
+	m.state = to
+	m.since = change.Time
+	m.history = append(m.history, change)
+	if len(m.history) > stateHistorySize {
+		m.history = m.history[len(m.history)-stateHistorySize:]
+	}
+	m.mutex.Unlock()
+
+	m.logger.Info("State changed",
+		"from", change.From,
+		"to", change.To,
+		"input", change.Input,
+		"reason", reason)
+	if m.onChange != nil {
+		m.onChange(change)
+	}
+	return true
+}
+
+// State returns the current state and when it was entered
+func (m *stateMachine) State() (ConnectionState, time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.state, m.since
+}
+
+// History returns the recorded transitions, oldest first
+func (m *stateMachine) History() []StateTransition {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]StateTransition(nil), m.history...)
+}
+
+// Status describes the state machine for the service status
+func (m *stateMachine) Status() map[string]interface{} {
+	state, since := m.State()
+	return map[string]interface{}{
+		"state":       state.String(),
+		"state_since": since,
+		"transitions": m.History(),
+	}
+}
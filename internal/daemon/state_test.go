@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing"
+)
+
+func TestStateTransitions(t *testing.T) {
+	off := routing.DesiredState{PhysicalGateway: net.ParseIP("192.168.1.1"), PhysicalInterface: "en0"}
+	on := vpnState("192.168.1.1")
+	degraded := on
+	degraded.DirectPathDegraded = true
+	withdrawn := on
+	withdrawn.Withdrawn = []net.IPNet{{IP: net.ParseIP("1.0.0.0").To4(), Mask: net.CIDRMask(24, 32)}}
+
+	tests := []struct {
+		name    string
+		from    ConnectionState
+		input   stateInput
+		desired routing.DesiredState
+		want    ConnectionState
+		ok      bool
+	}{
+		{"gateway change with VPN up", StateVPNActive, inputGatewayChanged, on, StateVPNActive, true},
+		{"gateway change with VPN down is ignored", StateIdle, inputGatewayChanged, off, StateIdle, false},
+		{"gateway change while paused is ignored", StatePaused, inputGatewayChanged, on, StatePaused, false},
+		{"apply from idle", StateIdle, inputApplyStarted, on, StateApplying, true},
+		{"apply from error", StateError, inputApplyStarted, on, StateApplying, true},
+		{"apply superseding an apply", StateApplying, inputApplyStarted, off, StateApplying, true},
+		{"no apply while paused", StatePaused, inputApplyStarted, on, StatePaused, false},
+		{"verify keeps the state", StateVPNActive, inputVerifyStarted, on, StateVPNActive, true},
+		{"no verify while paused", StatePaused, inputVerifyStarted, on, StatePaused, false},
+		{"applied VPN up", StateApplying, inputApplySucceeded, on, StateVPNActive, true},
+		{"applied VPN down", StateApplying, inputApplySucceeded, off, StateIdle, true},
+		{"applied with degraded direct path", StateApplying, inputApplySucceeded, degraded, StateDegraded, true},
+		{"applied with withdrawn networks", StateVPNActive, inputApplySucceeded, withdrawn, StateDegraded, true},
+		{"verification repairs an error", StateError, inputApplySucceeded, on, StateVPNActive, true},
+		{"apply failed", StateApplying, inputApplyFailed, on, StateError, true},
+		{"verification failed", StateVPNActive, inputApplyFailed, on, StateError, true},
+		{"outcome while paused is ignored", StatePaused, inputApplyFailed, on, StatePaused, false},
+		{"pause", StateVPNActive, inputPause, on, StatePaused, true},
+		{"pause twice", StatePaused, inputPause, on, StatePaused, false},
+		{"resume", StatePaused, inputResume, on, StateApplying, true},
+		{"resume without pause", StateIdle, inputResume, off, StateIdle, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := transition(tt.from, tt.input, tt.desired)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("transition(%s, %s) = %s, %t; want %s, %t", tt.from, tt.input, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStateMachineHistory(t *testing.T) {
+	var changes []StateTransition
+	m := newStateMachine(logger.New("error"), func(transition StateTransition) {
+		changes = append(changes, transition)
+	})
+	on := vpnState("192.168.1.1")
+
+	if !m.Fire(inputApplyStarted, on, "vpn_connected") || !m.Fire(inputApplySucceeded, on, "vpn_connected") {
+		t.Fatal("Expected the apply to be accepted")
+	}
+	// Staying in a state is not a transition
+	m.Fire(inputVerifyStarted, on, "interval")
+	m.Fire(inputApplySucceeded, on, "interval")
+	if m.Fire(inputResume, on, "") {
+		t.Error("Expected resume to be rejected while not paused")
+	}
+
+	if state, _ := m.State(); state != StateVPNActive {
+		t.Errorf("Expected VPNActive, got %s", state)
+	}
+	history := m.History()
+	if len(history) != 2 || len(changes) != 2 {
+		t.Fatalf("Expected 2 transitions, got %+v", history)
+	}
+	if history[0].From != "Idle" || history[0].To != "Applying" || history[1].To != "VPNActive" || history[1].Input != "apply_succeeded" {
+		t.Errorf("Unexpected transitions %+v", history)
+	}
+
+	for i := 0; i < stateHistorySize; i++ {
+		m.Fire(inputApplyStarted, on, "flap")
+		m.Fire(inputApplySucceeded, on, "flap")
+	}
+	if history := m.History(); len(history) != stateHistorySize || history[len(history)-1].Reason != "flap" {
+		t.Errorf("Expected the history to keep the latest %d transitions, got %d", stateHistorySize, len(history))
+	}
+}
@@ -0,0 +1,253 @@
+// Package history keeps a bounded record of what the daemon observed and did, so that
+// "my routes vanished an hour ago" can be answered after the fact.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record kinds
+const (
+	KindEvent = "event" // A network event was handled
+	KindApply = "apply" // A route apply finished
+)
+
+// Record is one entry of the history
+type Record struct {
+	Seq               uint64        `json:"seq"`
+	Time              time.Time     `json:"time"`
+	Kind              string        `json:"kind"`
+	Type              string        `json:"type"`             // Event type name or apply reason
+	VerifyOnly        bool          `json:"verify,omitempty"` // The apply was a drift verification
+	VPNConnected      bool          `json:"vpn_connected"`
+	PhysicalGateway   string        `json:"physical_gateway,omitempty"`
+	PhysicalInterface string        `json:"physical_interface,omitempty"`
+	VPNInterface      string        `json:"vpn_interface,omitempty"`
+	Duration          time.Duration `json:"duration,omitempty"`
+	RoutesAdded       int           `json:"routes_added,omitempty"`
+	RoutesRemoved     int           `json:"routes_removed,omitempty"`
+	Error             string        `json:"error,omitempty"`
+}
+
+// subscriberBuffer is how many records a follower may lag behind before records are dropped for it
+const subscriberBuffer = 64
+
+// History is a ring buffer of records, optionally appended to a JSONL file with size-based rotation
+type History struct {
+	mutex       sync.Mutex
+	records     []Record // ring buffer, oldest at start once full
+	start       int
+	size        int
+	seq         uint64
+	subscribers map[chan Record]struct{}
+
+	file       *os.File // nil until reopened after a failed rotation
+	path       string
+	closed     bool
+	written    int64
+	maxBytes   int64
+	maxBackups int
+}
+
+// New creates a history that keeps the last size records in memory
+func New(size int) *History {
+	return &History{
+		records:     make([]Record, 0, max(size, 1)),
+		size:        max(size, 1),
+		subscribers: make(map[chan Record]struct{}),
+	}
+}
+
+// Open creates a history persisted to path. Records already in the file are loaded, so the
+// history survives restarts. The file is rotated to path.1 ... path.<maxBackups> once it grows
+// beyond maxBytes.
+func Open(path string, size int, maxBytes int64, maxBackups int) (*History, error) {
+	h := New(size)
+	h.path = path
+	h.maxBytes = maxBytes
+	h.maxBackups = maxBackups
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	if err := h.openFile(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// load reads the records of an existing history file into the ring buffer
+func (h *History) load() error {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // a torn last line after a crash
+		}
+		h.push(record)
+		h.seq = max(h.seq, record.Seq)
+	}
+	return scanner.Err()
+}
+
+// openFile opens the history file for appending
+func (h *History) openFile() error {
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat history file: %w", err)
+	}
+	h.file = f
+	h.written = info.Size()
+	return nil
+}
+
+// Add appends a record, assigning its sequence number and, if unset, its time.
+// A failure to persist the record is returned, but the record is kept in memory regardless.
+func (h *History) Add(record Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.seq++
+	record.Seq = h.seq
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	h.push(record)
+	for ch := range h.subscribers {
+		select {
+		case ch <- record:
+		default: // a follower that cannot keep up misses records rather than blocking the daemon
+		}
+	}
+
+	if h.path == "" || h.closed {
+		return nil
+	}
+	if h.file == nil {
+		if err := h.openFile(); err != nil {
+			return err
+		}
+	}
+	return h.persist(record)
+}
+
+// push adds a record to the ring buffer, overwriting the oldest one when full
+func (h *History) push(record Record) {
+	if len(h.records) < h.size {
+		h.records = append(h.records, record)
+		return
+	}
+	h.records[h.start] = record
+	h.start = (h.start + 1) % h.size
+}
+
+// persist appends a record to the file, rotating it first if it is full
+func (h *History) persist(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if h.maxBytes > 0 && h.written > 0 && h.written+int64(len(line)) > h.maxBytes {
+		if err := h.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := h.file.Write(line)
+	h.written += int64(n)
+	return err
+}
+
+// rotate shifts path.N-1 to path.N, path to path.1 and starts an empty file.
+// If rotation fails the file stays closed and the next Add reopens it.
+func (h *History) rotate() error {
+	err := h.file.Close()
+	h.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close history file: %w", err)
+	}
+
+	if h.maxBackups > 0 {
+		for i := h.maxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", h.path, i), fmt.Sprintf("%s.%d", h.path, i+1))
+		}
+		if err := os.Rename(h.path, h.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate history file: %w", err)
+		}
+	} else if err := os.Remove(h.path); err != nil {
+		return fmt.Errorf("failed to rotate history file: %w", err)
+	}
+
+	return h.openFile()
+}
+
+// Since returns the records at or after t, oldest first
+func (h *History) Since(t time.Time) []Record {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.sinceLocked(t)
+}
+
+func (h *History) sinceLocked(t time.Time) []Record {
+	records := make([]Record, 0, len(h.records))
+	for i := range h.records {
+		record := h.records[(h.start+i)%len(h.records)]
+		if !record.Time.Before(t) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Follow returns the records at or after t and a channel of the records added afterwards,
+// without gaps between the two. cancel must be called to stop following.
+func (h *History) Follow(t time.Time) (records []Record, updates <-chan Record, cancel func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ch := make(chan Record, subscriberBuffer)
+	h.subscribers[ch] = struct{}{}
+	cancel = func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.subscribers, ch)
+	}
+	return h.sinceLocked(t), ch, cancel
+}
+
+// Close closes the history file
+func (h *History) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closed = true
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
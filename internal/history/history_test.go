@@ -0,0 +1,150 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	h := New(3)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		if err := h.Add(Record{Time: base.Add(time.Duration(i) * time.Minute), Kind: KindEvent}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	records := h.Since(time.Time{})
+	if len(records) != 3 {
+		t.Fatalf("Expected the last 3 records, got %d", len(records))
+	}
+	for i, record := range records {
+		if want := uint64(i + 3); record.Seq != want {
+			t.Errorf("Record %d: expected seq %d, got %d", i, want, record.Seq)
+		}
+	}
+
+	if records := h.Since(base.Add(4 * time.Minute)); len(records) != 1 || records[0].Seq != 5 {
+		t.Errorf("Expected only the newest record since its time, got %+v", records)
+	}
+}
+
+func TestHistoryFollow(t *testing.T) {
+	h := New(10)
+	h.Add(Record{Kind: KindEvent, Type: "VPNConnected"})
+
+	records, updates, cancel := h.Follow(time.Time{})
+	defer cancel()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 existing record, got %d", len(records))
+	}
+
+	h.Add(Record{Kind: KindApply, Type: "vpn_connected"})
+	select {
+	case record := <-updates:
+		if record.Seq != 2 || record.Type != "vpn_connected" {
+			t.Errorf("Unexpected followed record %+v", record)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the new record to be delivered to the follower")
+	}
+}
+
+func TestHistoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	h, err := Open(path, 10, 0, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	h.Add(Record{Kind: KindEvent, Type: "VPNConnected"})
+	h.Add(Record{Kind: KindApply, Type: "vpn_connected", RoutesAdded: 42, Duration: time.Second})
+	h.Close()
+
+	h, err = Open(path, 10, 0, 0)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer h.Close()
+	records := h.Since(time.Time{})
+	if len(records) != 2 || records[1].RoutesAdded != 42 || records[1].Duration != time.Second {
+		t.Fatalf("Expected the persisted records to be loaded, got %+v", records)
+	}
+	h.Add(Record{Kind: KindEvent})
+	if records := h.Since(time.Time{}); records[2].Seq != 3 {
+		t.Errorf("Expected sequence numbers to continue after a restart, got %d", records[2].Seq)
+	}
+}
+
+func TestHistoryRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	h, err := Open(path, 100, 200, 2)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer h.Close()
+	for i := 0; i < 20; i++ {
+		if err := h.Add(Record{Kind: KindEvent, Type: "NetworkAddressChanged"}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("Expected %s to stay within the size limit, got %d bytes", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 backups, found %s.3", path)
+	}
+	if len(h.Since(time.Time{})) != 20 {
+		t.Errorf("Expected rotation to keep the in-memory history")
+	}
+}
+
+func TestHistoryReopensAfterFailedRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "events.jsonl")
+
+	h, err := Open(path, 100, 200, 2)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer h.Close()
+	if err := h.Add(Record{Kind: KindEvent, Type: "NetworkAddressChanged"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Without the directory neither rotation nor reopening can succeed
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := h.Add(Record{Kind: KindEvent, Type: "NetworkAddressChanged"}); err == nil {
+			t.Fatalf("Expected Add %d to fail without the history directory", i)
+		}
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Add(Record{Kind: KindEvent, Type: "NetworkAddressChanged"}); err != nil {
+		t.Fatalf("Expected Add to reopen the history file, got %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Errorf("Expected the record to be written to the reopened file: %v", err)
+	}
+	if len(h.Since(time.Time{})) != 4 {
+		t.Errorf("Expected every record to be kept in memory")
+	}
+}
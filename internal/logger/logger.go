@@ -77,11 +77,12 @@ func (l *Logger) ServiceStop() {
 	l.Info("Service stopping")
 }
 
-func (l *Logger) BatchOperation(action string, total, success, failed int, duration int64) {
+func (l *Logger) BatchOperation(action string, total, success, skipped, failed int, duration int64) {
 	l.Info("Batch operation completed",
 		slog.String("action", action),
 		slog.Int("total", total),
 		slog.Int("success", success),
+		slog.Int("skipped", skipped),
 		slog.Int("failed", failed),
 		slog.Int64("duration_ms", duration))
 }
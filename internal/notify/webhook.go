@@ -0,0 +1,247 @@
+// Package notify delivers state transitions of the daemon to external systems.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+)
+
+// Webhook request headers
+const (
+	SignatureHeader = "X-SmartRoute-Signature" // "sha256=" followed by the hex HMAC-SHA256 of the body
+	EventHeader     = "X-SmartRoute-Event"
+)
+
+// Notification is the JSON payload POSTed to webhooks
+type Notification struct {
+	Event     string                 `json:"event"` // e.g. "VPNConnected" or "ApplyFailed"
+	Timestamp time.Time              `json:"timestamp"`
+	Host      string                 `json:"host"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// WebhookNotifier POSTs notifications to the configured URLs. Each URL has its own bounded
+// queue and delivery goroutine, so a slow or failing endpoint does not hold up the others.
+// Failed deliveries are retried with exponential backoff.
+type WebhookNotifier struct {
+	endpoints []*endpoint
+	events    map[string]bool // nil sends every event
+	secret    []byte
+	host      string
+	logger    *logger.Logger
+
+	client    *http.Client
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// endpoint is a webhook URL with its queue of encoded notifications
+type endpoint struct {
+	url   string
+	queue chan delivery
+	mutex sync.Mutex // serializes the drop-oldest enqueue
+}
+
+// delivery is an encoded notification waiting to be sent
+type delivery struct {
+	event string
+	body  []byte
+}
+
+// NewWebhookNotifier creates a notifier for the webhook settings in cfg.
+// It returns nil if no webhook URL is configured.
+func NewWebhookNotifier(cfg *config.Config, log *logger.Logger) *WebhookNotifier {
+	if len(cfg.WebhookURLs) == 0 {
+		return nil
+	}
+
+	host, _ := os.Hostname()
+	n := &WebhookNotifier{
+		secret:    []byte(cfg.WebhookSecret),
+		host:      host,
+		logger:    log,
+		client:    &http.Client{Timeout: cfg.WebhookTimeout},
+		attempts:  max(cfg.WebhookAttempts, 1),
+		baseDelay: cfg.WebhookRetryBaseDelay,
+		maxDelay:  cfg.WebhookRetryMaxDelay,
+	}
+	if len(cfg.WebhookEvents) > 0 {
+		n.events = make(map[string]bool, len(cfg.WebhookEvents))
+		for _, event := range cfg.WebhookEvents {
+			n.events[event] = true
+		}
+	}
+	for _, url := range cfg.WebhookURLs {
+		n.endpoints = append(n.endpoints, &endpoint{
+			url:   url,
+			queue: make(chan delivery, max(cfg.WebhookQueueSize, 1)),
+		})
+	}
+	return n
+}
+
+// Start starts a delivery goroutine per URL; they run until ctx is cancelled or Stop is called
+func (n *WebhookNotifier) Start(ctx context.Context) {
+	ctx, n.cancel = context.WithCancel(ctx)
+	for _, ep := range n.endpoints {
+		n.wg.Add(1)
+		go func() {
+			defer n.wg.Done()
+			n.deliverLoop(ctx, ep)
+		}()
+	}
+}
+
+// Stop stops delivery and waits for the delivery goroutines; queued notifications are dropped
+func (n *WebhookNotifier) Stop() {
+	if n.cancel != nil {
+		n.cancel()
+	}
+	n.wg.Wait()
+}
+
+// Notify queues a notification for every URL. It never blocks: when a queue is full,
+// its oldest notification is dropped to make room.
+func (n *WebhookNotifier) Notify(notification Notification) {
+	if n.events != nil && !n.events[notification.Event] {
+		return
+	}
+	if notification.Timestamp.IsZero() {
+		notification.Timestamp = time.Now()
+	}
+	if notification.Host == "" {
+		notification.Host = n.host
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		n.logger.Error("failed to encode webhook notification", "event", notification.Event, "error", err)
+		return
+	}
+
+	for _, ep := range n.endpoints {
+		n.enqueue(ep, delivery{event: notification.Event, body: body})
+	}
+}
+
+// enqueue adds d to the endpoint queue, dropping the oldest queued notification if it is full
+func (n *WebhookNotifier) enqueue(ep *endpoint, d delivery) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+
+	for {
+		select {
+		case ep.queue <- d:
+			return
+		default:
+		}
+
+		select {
+		case dropped := <-ep.queue:
+			n.logger.Warn("Webhook queue full, dropping oldest notification",
+				"url", ep.url,
+				"event", dropped.event)
+		default:
+		}
+	}
+}
+
+// deliverLoop sends the queued notifications of one endpoint in order
+func (n *WebhookNotifier) deliverLoop(ctx context.Context, ep *endpoint) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-ep.queue:
+			n.deliver(ctx, ep.url, d)
+		}
+	}
+}
+
+// deliver POSTs one notification, retrying with exponential backoff until it is accepted,
+// rejected permanently, or the attempts are exhausted
+func (n *WebhookNotifier) deliver(ctx context.Context, url string, d delivery) {
+	delay := n.baseDelay
+	for attempt := 1; attempt <= n.attempts; attempt++ {
+		retryable, err := n.post(ctx, url, d)
+		if err == nil {
+			n.logger.Debug("Webhook delivered", "url", url, "event", d.event, "attempt", attempt)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !retryable || attempt == n.attempts {
+			n.logger.Warn("Webhook delivery failed",
+				"url", url,
+				"event", d.event,
+				"attempts", attempt,
+				"error", err)
+			return
+		}
+
+		n.logger.Debug("Retrying webhook delivery",
+			"url", url,
+			"event", d.event,
+			"attempt", attempt,
+			"delay", delay,
+			"error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay = min(delay*2, n.maxDelay)
+	}
+}
+
+// post sends a single request. Network errors, 429 and 5xx responses are retryable.
+func (n *WebhookNotifier) post(ctx context.Context, url string, d delivery) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.event)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(n.secret, d.body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// Sign returns the signature header value for body: "sha256=" and the hex HMAC-SHA256 with secret
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+)
+
+func testConfig(urls ...string) *config.Config {
+	cfg := config.NewConfig()
+	cfg.WebhookURLs = urls
+	cfg.WebhookSecret = "s3cret"
+	cfg.WebhookAttempts = 3
+	cfg.WebhookRetryBaseDelay = 10 * time.Millisecond
+	cfg.WebhookRetryMaxDelay = 20 * time.Millisecond
+	cfg.WebhookTimeout = time.Second
+	return cfg
+}
+
+func TestWebhookNotifierDeliversSignedPayload(t *testing.T) {
+	var mutex sync.Mutex
+	calls := 0
+	received := make(chan Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		calls++
+		first := calls == 1
+		mutex.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable) // retried
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign([]byte("s3cret"), body); got != want {
+			t.Errorf("Signature = %q, want %q", got, want)
+		}
+		if r.Header.Get(EventHeader) != "ApplyFailed" {
+			t.Errorf("Unexpected event header %q", r.Header.Get(EventHeader))
+		}
+
+		var notification Notification
+		if err := json.Unmarshal(body, &notification); err != nil {
+			t.Errorf("Payload is not JSON: %v", err)
+		}
+		received <- notification
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(testConfig(server.URL), logger.New("error"))
+	n.Start(context.Background())
+	defer n.Stop()
+
+	n.Notify(Notification{Event: "NetworkAddressChanged"}) // not in the default event filter
+	n.Notify(Notification{Event: "ApplyFailed", Details: map[string]interface{}{"error": "boom"}})
+
+	select {
+	case notification := <-received:
+		if notification.Event != "ApplyFailed" || notification.Details["error"] != "boom" || notification.Host == "" {
+			t.Errorf("Unexpected notification %+v", notification)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notification was not delivered")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if calls != 2 {
+		t.Errorf("Expected 1 failed and 1 successful attempt, got %d calls", calls)
+	}
+}
+
+func TestWebhookNotifierDoesNotRetryClientErrors(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- struct{}{}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(testConfig(server.URL), logger.New("error"))
+	n.Start(context.Background())
+	n.Notify(Notification{Event: "VPNConnected"})
+
+	<-calls
+	time.Sleep(100 * time.Millisecond)
+	n.Stop()
+	if len(calls) != 0 {
+		t.Errorf("Expected a single attempt, got %d more", len(calls))
+	}
+}
+
+func TestWebhookNotifierDropsOldestWhenQueueFull(t *testing.T) {
+	cfg := testConfig("http://127.0.0.1:0")
+	cfg.WebhookQueueSize = 2
+	cfg.WebhookEvents = nil
+	n := NewWebhookNotifier(cfg, logger.New("error"))
+
+	// Not started, so nothing drains the queue
+	for _, event := range []string{"first", "second", "third"} {
+		n.Notify(Notification{Event: event})
+	}
+
+	queue := n.endpoints[0].queue
+	if len(queue) != 2 {
+		t.Fatalf("Expected 2 queued notifications, got %d", len(queue))
+	}
+	if d := <-queue; d.event != "second" {
+		t.Errorf("Expected the oldest notification to be dropped, got %s first", d.event)
+	}
+}
+
+func TestNewWebhookNotifierWithoutURLs(t *testing.T) {
+	if n := NewWebhookNotifier(config.NewConfig(), logger.New("error")); n != nil {
+		t.Error("Expected no notifier without webhook URLs")
+	}
+}
@@ -0,0 +1,99 @@
+package batch
+
+import (
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+const (
+	// throttleBackoffBase is the pause after the first chunk that hit ENOBUFS/EAGAIN
+	throttleBackoffBase = 100 * time.Millisecond
+	// throttleBackoffMax caps the pause between chunks while the kernel keeps pushing back
+	throttleBackoffMax = 5 * time.Second
+	// maxFailureRate is the share of failed routes in a chunk above which concurrency is reduced
+	maxFailureRate = 0.2
+	// maxLatencyGrowth is how much slower than the best observed chunk a chunk may get before concurrency is reduced
+	maxLatencyGrowth = 2.0
+)
+
+// concurrencyController adapts the number of workers between chunks.
+// It grows the limit additively while chunks are healthy and shrinks it multiplicatively
+// when latency climbs, routes fail or the kernel reports it is out of buffer space.
+type concurrencyController struct {
+	min       int
+	max       int
+	current   int
+	step      int
+	baseline  time.Duration // Lowest average per-route latency observed
+	throttled int           // Consecutive chunks that hit ENOBUFS/EAGAIN
+}
+
+// newConcurrencyController creates a controller bounded by the batch options.
+// Without adaptive concurrency the limit stays at opts.ConcurrencyLimit.
+func newConcurrencyController(opts Options) *concurrencyController {
+	limit := max(opts.ConcurrencyLimit, 1)
+	c := &concurrencyController{
+		min:     limit,
+		max:     limit,
+		current: limit,
+		step:    max(limit/10, 1),
+	}
+	if opts.Adaptive {
+		c.min = min(max(opts.MinConcurrency, 1), limit)
+	}
+	return c
+}
+
+// Limit returns the number of workers to use for the next chunk
+func (c *concurrencyController) Limit() int {
+	return c.current
+}
+
+// Observe adjusts the limit from the outcomes of a finished chunk and
+// returns how long to pause before starting the next chunk
+func (c *concurrencyController) Observe(outcomes []types.RouteOutcome) time.Duration {
+	if len(outcomes) == 0 || c.min == c.max {
+		return 0
+	}
+
+	var failed, throttled int
+	var total time.Duration
+	for _, outcome := range outcomes {
+		total += outcome.Duration
+		if outcome.Status != types.OutcomeFailed {
+			continue
+		}
+		failed++
+		if types.IsThrottled(outcome.Err) {
+			throttled++
+		}
+	}
+	latency := total / time.Duration(len(outcomes))
+
+	if throttled > 0 {
+		c.throttled++
+		c.decrease(2)
+		return min(throttleBackoffBase<<min(c.throttled-1, 8), throttleBackoffMax)
+	}
+	c.throttled = 0
+
+	if c.baseline == 0 || latency < c.baseline {
+		c.baseline = latency
+	}
+
+	switch {
+	case float64(failed)/float64(len(outcomes)) > maxFailureRate:
+		c.decrease(4.0 / 3)
+	case float64(latency) > maxLatencyGrowth*float64(c.baseline):
+		c.decrease(4.0 / 3)
+	default:
+		c.current = min(c.current+c.step, c.max)
+	}
+	return 0
+}
+
+// decrease divides the limit by factor, never going below the minimum
+func (c *concurrencyController) decrease(factor float64) {
+	c.current = max(int(float64(c.current)/factor), c.min)
+}
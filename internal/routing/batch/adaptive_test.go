@@ -0,0 +1,64 @@
+package batch
+
+import (
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func chunkOutcomes(n, failed int, latency time.Duration, err error) []types.RouteOutcome {
+	outcomes := make([]types.RouteOutcome, 0, n)
+	for i, route := range testRoutes(n) {
+		if i < failed {
+			outcomes = append(outcomes, types.NewRouteOutcome(route, err, latency))
+		} else {
+			outcomes = append(outcomes, types.NewRouteOutcome(route, nil, latency))
+		}
+	}
+	return outcomes
+}
+
+func TestConcurrencyControllerAdapts(t *testing.T) {
+	c := newConcurrencyController(Options{ConcurrencyLimit: 40, MinConcurrency: 4, Adaptive: true})
+	enobufs := &types.RouteOperationError{ErrorType: types.RouteErrNetwork, Cause: fmt.Errorf("write: %w", syscall.ENOBUFS)}
+
+	// Kernel pushback halves the workers and pauses with growing backoff
+	if pause := c.Observe(chunkOutcomes(10, 1, time.Millisecond, enobufs)); c.Limit() != 20 || pause != throttleBackoffBase {
+		t.Errorf("Expected limit 20 and pause %v after ENOBUFS, got %d and %v", throttleBackoffBase, c.Limit(), pause)
+	}
+	if pause := c.Observe(chunkOutcomes(10, 1, time.Millisecond, enobufs)); c.Limit() != 10 || pause != 2*throttleBackoffBase {
+		t.Errorf("Expected limit 10 and doubled pause, got %d and %v", c.Limit(), pause)
+	}
+
+	// Healthy chunks grow the limit additively
+	c.Observe(chunkOutcomes(10, 0, time.Millisecond, nil))
+	if c.Limit() != 14 {
+		t.Errorf("Expected limit to grow to 14, got %d", c.Limit())
+	}
+
+	// Latency well above the best observed chunk shrinks it again
+	c.Observe(chunkOutcomes(10, 0, 5*time.Millisecond, nil))
+	if c.Limit() != 10 {
+		t.Errorf("Expected limit to shrink to 10 on rising latency, got %d", c.Limit())
+	}
+
+	// Never below the minimum
+	for i := 0; i < 10; i++ {
+		c.Observe(chunkOutcomes(10, 1, time.Millisecond, enobufs))
+	}
+	if c.Limit() != 4 {
+		t.Errorf("Expected limit to stop at the minimum of 4, got %d", c.Limit())
+	}
+}
+
+func TestConcurrencyControllerFixed(t *testing.T) {
+	c := newConcurrencyController(Options{ConcurrencyLimit: 8})
+	enobufs := &types.RouteOperationError{ErrorType: types.RouteErrNetwork, Cause: syscall.ENOBUFS}
+
+	if pause := c.Observe(chunkOutcomes(10, 5, time.Millisecond, enobufs)); pause != 0 || c.Limit() != 8 {
+		t.Errorf("Expected a fixed limit without adaptive concurrency, got %d and %v", c.Limit(), pause)
+	}
+}
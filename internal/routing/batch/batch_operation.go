@@ -1,84 +1,224 @@
 package batch
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/panjf2000/ants/v2"
 	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
 	"github.com/wesleywu/smart-route/internal/routing/types"
 )
 
 // OperationFunc is a function that performs an operation on a route
-type OperationFunc func(*net.IPNet, net.IP, *logger.Logger) error
-
-// Process performs a batch operation on a list of routes with a concurrency limit
-func Process(routes []*types.Route, operationFunc OperationFunc, concurrencyLimit int, log *logger.Logger) error {
-	semaphore := make(chan struct{}, concurrencyLimit)
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(routes))
-
-	for _, route := range routes {
-		wg.Add(1)
-		go func(r *types.Route) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+type OperationFunc func(context.Context, *net.IPNet, net.IP, *logger.Logger, ...types.RouteOption) error
+
+// Options controls how a batch operation is split and executed
+type Options struct {
+	ConcurrencyLimit int           // Maximum number of routes processed in parallel
+	MinConcurrency   int           // Lower bound when adapting the number of workers
+	Adaptive         bool          // Adapt the number of workers to observed latency, failures and throttling
+	BatchSize        int           // Number of routes per chunk; chunks run one after another
+	BatchTimeout     time.Duration // Deadline for each chunk, zero means no deadline
+}
 
-			err := operationFunc(&r.Destination, r.Gateway, log)
+// chunks splits routes into consecutive slices of at most size routes
+func chunks(routes []*types.Route, size int) [][]*types.Route {
+	if size <= 0 || size >= len(routes) {
+		return [][]*types.Route{routes}
+	}
 
-			if err != nil {
-				errChan <- err
-			}
-		}(route)
+	result := make([][]*types.Route, 0, (len(routes)+size-1)/size)
+	for start := 0; start < len(routes); start += size {
+		end := min(start+size, len(routes))
+		result = append(result, routes[start:end])
 	}
+	return result
+}
 
-	wg.Wait()
-	close(errChan)
+// chunkContext derives the context for a single chunk, applying the per-batch timeout
+func chunkContext(ctx context.Context, opts Options) (context.Context, context.CancelFunc) {
+	if opts.BatchTimeout > 0 {
+		return context.WithTimeout(ctx, opts.BatchTimeout)
+	}
+	return context.WithCancel(ctx)
+}
 
-	var errors []error
-	for err := range errChan {
-		errors = append(errors, err)
+// notAttempted records the routes of chunks that never ran because ctx was done
+func notAttempted(routes []*types.Route, err error) []types.RouteOutcome {
+	outcomes := make([]types.RouteOutcome, 0, len(routes))
+	for _, route := range routes {
+		outcomes = append(outcomes, types.NewRouteOutcome(route, types.NewContextError(err, &route.Destination, route.Gateway), 0))
 	}
+	return outcomes
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("batch operation failed: %d errors", len(errors))
+// runRoute performs the operation on a single route and records its outcome
+func runRoute(ctx context.Context, route *types.Route, operationFunc OperationFunc, log *logger.Logger) types.RouteOutcome {
+	start := time.Now()
+	err := operationFunc(ctx, &route.Destination, route.Gateway, log, route.Options()...)
+	return types.NewRouteOutcome(route, err, time.Since(start))
+}
+
+// Process performs a batch operation on a list of routes with a concurrency limit.
+// Routes are processed in chunks of opts.BatchSize; cancelling ctx stops the batch between chunks.
+// The returned result holds one outcome per route, in the order of routes.
+func Process(ctx context.Context, action string, routes []*types.Route, operationFunc OperationFunc, opts Options, log *logger.Logger) *types.BatchResult {
+	start := time.Now()
+	outcomes := make([]types.RouteOutcome, 0, len(routes))
+	controller := newConcurrencyController(opts)
+
+	for i, chunk := range chunks(routes, opts.BatchSize) {
+		if err := ctx.Err(); err != nil {
+			log.Warn("batch operation cancelled", "action", action, "chunks_done", i, "error", err)
+			outcomes = append(outcomes, notAttempted(routes[len(outcomes):], err)...)
+			break
+		}
+
+		chunkCtx, cancel := chunkContext(ctx, opts)
+		semaphore := make(chan struct{}, controller.Limit())
+		var wg sync.WaitGroup
+		chunkOutcomes := make([]types.RouteOutcome, len(chunk))
+
+		for j, route := range chunk {
+			wg.Add(1)
+			go func(j int, r *types.Route) {
+				defer wg.Done()
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				chunkOutcomes[j] = runRoute(chunkCtx, r, operationFunc, log)
+			}(j, route)
+		}
+
+		wg.Wait()
+		cancel()
+		outcomes = append(outcomes, chunkOutcomes...)
+		adapt(ctx, controller, chunkOutcomes, action, log)
 	}
 
-	return nil
+	return types.NewBatchResult(action, outcomes, time.Since(start))
 }
 
-// ProcessUsingAnts performs a batch operation on a list of routes with a concurrency limit, using ants pool
-func ProcessUsingAnts(routes []*types.Route, operationFunc OperationFunc, concurrencyLimit int, log *logger.Logger) error {
-	var wg sync.WaitGroup
-	pool, _ := ants.NewPool(concurrencyLimit)
-	errChan := make(chan error, len(routes))
+// ProcessUsingAnts performs a batch operation on a list of routes with a concurrency limit, using ants pool.
+// Routes are processed in chunks of opts.BatchSize; cancelling ctx stops the batch between chunks.
+// The returned result holds one outcome per route, in the order of routes.
+func ProcessUsingAnts(ctx context.Context, action string, routes []*types.Route, operationFunc OperationFunc, opts Options, log *logger.Logger) (*types.BatchResult, error) {
+	pool, err := ants.NewPool(max(opts.ConcurrencyLimit, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worker pool: %w", err)
+	}
+	defer pool.Release()
+
+	start := time.Now()
+	outcomes := make([]types.RouteOutcome, 0, len(routes))
+	controller := newConcurrencyController(opts)
+
+	for i, chunk := range chunks(routes, opts.BatchSize) {
+		if err := ctx.Err(); err != nil {
+			log.Warn("batch operation cancelled", "action", action, "chunks_done", i, "error", err)
+			outcomes = append(outcomes, notAttempted(routes[len(outcomes):], err)...)
+			break
+		}
+
+		pool.Tune(controller.Limit())
+		chunkCtx, cancel := chunkContext(ctx, opts)
+		var wg sync.WaitGroup
+		chunkOutcomes := make([]types.RouteOutcome, len(chunk))
+
+		for j, route := range chunk {
+			wg.Add(1)
+			submitErr := pool.Submit(func() {
+				defer wg.Done()
+				chunkOutcomes[j] = runRoute(chunkCtx, route, operationFunc, log)
+			})
+			if submitErr != nil {
+				wg.Done()
+				chunkOutcomes[j] = types.NewRouteOutcome(route, submitErr, 0)
+			}
+		}
 
-	for _, route := range routes {
-		wg.Add(1)
-		pool.Submit(func() {
-			defer wg.Done()
+		wg.Wait()
+		cancel()
+		outcomes = append(outcomes, chunkOutcomes...)
+		adapt(ctx, controller, chunkOutcomes, action, log)
+	}
 
-			err := operationFunc(&route.Destination, route.Gateway, log)
+	return types.NewBatchResult(action, outcomes, time.Since(start)), nil
+}
 
-			if err != nil {
-				errChan <- err
-			}
-		})
+// adapt feeds a finished chunk to the controller and waits out any throttling pause
+func adapt(ctx context.Context, controller *concurrencyController, outcomes []types.RouteOutcome, action string, log *logger.Logger) {
+	previous := controller.Limit()
+	pause := controller.Observe(outcomes)
+	if controller.Limit() != previous {
+		log.Debug("adjusted batch concurrency", "action", action, "from", previous, "to", controller.Limit(), "pause_ms", pause.Milliseconds())
+	}
+	if pause > 0 {
+		// A cancelled context is picked up before the next chunk starts
+		_ = sleepContext(ctx, pause)
 	}
+}
 
-	wg.Wait()
-	close(errChan)
+// RetryFunc runs a batch operation again on some of its routes, e.g. a RouteManager batch method
+type RetryFunc func(context.Context, []*types.Route) (*types.BatchResult, error)
+
+// RetryFailed re-runs only the failures of a previous result that policy considers retryable and
+// merges the new outcomes into it, waiting the policy's delay before each round until the policy's
+// attempts are used up. Failures the route manager already retried per route are not retried again.
+// Every retried route is counted in m, if set.
+func RetryFailed(ctx context.Context, result *types.BatchResult, retry RetryFunc, policy RetryPolicy, log *logger.Logger, m *metrics.Metrics) (*types.BatchResult, error) {
+	for attempt := 1; attempt < policy.MaxAttempts; attempt++ {
+		routes := result.RetryableRoutes(policy.IsRetryable)
+		if len(routes) == 0 {
+			break
+		}
+
+		delay := policy.Delay(attempt)
+		log.Debug("retrying failed routes", "action", result.Action, "count", len(routes),
+			"attempt", attempt, "delay_ms", delay.Milliseconds())
+		if err := sleepContext(ctx, delay); err != nil {
+			break
+		}
+		if m != nil {
+			for range routes {
+				m.RecordRetry()
+			}
+		}
 
-	var errors []error
-	for err := range errChan {
-		errors = append(errors, err)
+		retried, err := retry(ctx, routes)
+		if err != nil {
+			return result, err
+		}
+		result.Merge(retried)
 	}
+	return result, nil
+}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("batch operation failed: %d errors", len(errors))
+// ChunkFunc performs an operation on a whole chunk of routes at once, returning one outcome per route in order
+type ChunkFunc func(context.Context, []*types.Route) []types.RouteOutcome
+
+// ProcessChunks performs a batch operation by handing whole chunks of opts.BatchSize routes to chunkFunc,
+// for backends that program many routes per system call or process.
+// Cancelling ctx stops the batch between chunks.
+func ProcessChunks(ctx context.Context, action string, routes []*types.Route, chunkFunc ChunkFunc, opts Options, log *logger.Logger) *types.BatchResult {
+	start := time.Now()
+	outcomes := make([]types.RouteOutcome, 0, len(routes))
+
+	for i, chunk := range chunks(routes, opts.BatchSize) {
+		if err := ctx.Err(); err != nil {
+			log.Warn("batch operation cancelled", "action", action, "chunks_done", i, "error", err)
+			outcomes = append(outcomes, notAttempted(routes[len(outcomes):], err)...)
+			break
+		}
+
+		chunkCtx, cancel := chunkContext(ctx, opts)
+		outcomes = append(outcomes, chunkFunc(chunkCtx, chunk)...)
+		cancel()
 	}
 
-	return nil
+	return types.NewBatchResult(action, outcomes, time.Since(start))
 }
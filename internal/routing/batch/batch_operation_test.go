@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func testRoutes(n int) []*types.Route {
+	routes := make([]*types.Route, 0, n)
+	for i := 0; i < n; i++ {
+		_, network, _ := net.ParseCIDR(fmt.Sprintf("10.%d.%d.0/24", i/256, i%256))
+		routes = append(routes, &types.Route{Destination: *network, Gateway: net.ParseIP("192.168.1.1")})
+	}
+	return routes
+}
+
+func TestChunks(t *testing.T) {
+	routes := testRoutes(250)
+
+	got := chunks(routes, 100)
+	if len(got) != 3 || len(got[0]) != 100 || len(got[2]) != 50 {
+		t.Errorf("Expected chunks of 100/100/50, got %d chunks", len(got))
+	}
+
+	if got := chunks(routes, 0); len(got) != 1 || len(got[0]) != 250 {
+		t.Errorf("Expected a single chunk when batch size is unset")
+	}
+}
+
+func TestProcessUsingAntsCancelsBetweenChunks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var processed int32
+	op := func(ctx context.Context, _ *net.IPNet, _ net.IP, _ *logger.Logger, _ ...types.RouteOption) error {
+		// Cancel once the first chunk is underway, as a newer network event would
+		if atomic.AddInt32(&processed, 1) == 1 {
+			cancel()
+		}
+		return nil
+	}
+
+	opts := Options{ConcurrencyLimit: 4, BatchSize: 10}
+	result, err := ProcessUsingAnts(ctx, "add", testRoutes(50), op, opts, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&processed); n != 10 {
+		t.Errorf("Expected only the first chunk of 10 routes to run, got %d", n)
+	}
+	if result.Total != 50 || result.Succeeded != 10 || len(result.Failures[types.RouteErrCanceled]) != 40 {
+		t.Errorf("Expected 10 succeeded and 40 cancelled, got %d/%d/%d",
+			result.Total, result.Succeeded, len(result.Failures[types.RouteErrCanceled]))
+	}
+}
+
+func TestRetryFailedOnlyRetriesRetryable(t *testing.T) {
+	routes := testRoutes(6)
+	attempts := make(map[string]int)
+	var mutex sync.Mutex
+
+	op := func(_ context.Context, dest *net.IPNet, gw net.IP, _ *logger.Logger, _ ...types.RouteOption) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		attempts[dest.String()]++
+
+		switch dest.String() {
+		case "10.0.0.0/24":
+			return &types.RouteOperationError{ErrorType: types.RouteErrPermission, Destination: *dest, Gateway: gw}
+		case "10.0.1.0/24":
+			return &types.RouteOperationError{ErrorType: types.RouteErrNotFound, Destination: *dest, Gateway: gw}
+		case "10.0.2.0/24":
+			if attempts[dest.String()] == 1 {
+				return &types.RouteOperationError{ErrorType: types.RouteErrNetwork, Destination: *dest, Gateway: gw}
+			}
+		case "10.0.3.0/24":
+			// Already retried per route by the route manager
+			return fmt.Errorf("%w: %w", ErrRetriesExhausted,
+				&types.RouteOperationError{ErrorType: types.RouteErrTimeout, Destination: *dest, Gateway: gw})
+		}
+		return nil
+	}
+
+	opts := Options{ConcurrencyLimit: 2}
+	log := logger.New("error")
+	result, err := ProcessUsingAnts(context.Background(), "add", routes, op, opts, log)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Succeeded != 2 || result.Skipped != 1 || result.Failed != 3 {
+		t.Fatalf("Expected 2/1/3 before retry, got %d/%d/%d", result.Succeeded, result.Skipped, result.Failed)
+	}
+
+	retry := func(ctx context.Context, routes []*types.Route) (*types.BatchResult, error) {
+		return ProcessUsingAnts(ctx, "add", routes, op, opts, log)
+	}
+	policy := RetryPolicy{MaxAttempts: 3}
+	m := metrics.NewMetrics()
+	result, err = RetryFailed(context.Background(), result, retry, policy, log, m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Total != 6 || result.Succeeded != 3 || result.Skipped != 1 || result.Failed != 2 {
+		t.Errorf("Expected 3/1/2 after retry, got %d/%d/%d", result.Succeeded, result.Skipped, result.Failed)
+	}
+	if attempts["10.0.2.0/24"] != 2 || attempts["10.0.3.0/24"] != 1 || m.GetRetries() != 1 {
+		t.Errorf("Expected only the network failure to be retried, got %v and %d retries", attempts, m.GetRetries())
+	}
+	if len(result.Failures[types.RouteErrPermission]) != 1 {
+		t.Errorf("Expected the permission failure to remain")
+	}
+	if attempts["10.0.0.0/24"] != 1 {
+		t.Errorf("Permission failures must not be retried, got %d attempts", attempts["10.0.0.0/24"])
+	}
+	if result.Err() == nil {
+		t.Error("Expected an error summarizing the remaining failure")
+	}
+}
@@ -0,0 +1,147 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// ErrRetriesExhausted marks a failure that was already retried as often as the policy allows
+var ErrRetriesExhausted = errors.New("max retries exceeded")
+
+// RetryPolicy decides whether and when a failed route operation is attempted again
+type RetryPolicy struct {
+	MaxAttempts int                           // Total attempts including the first one
+	BaseDelay   time.Duration                 // Delay before the first retry, doubled for every further retry
+	MaxDelay    time.Duration                 // Upper bound for a single delay
+	Jitter      float64                       // Fraction of each delay that is randomized
+	Retryable   map[types.RouteErrorType]bool // nil falls back to RouteOperationError.IsRetryable
+}
+
+// NewRetryPolicy builds the retry policy from the configuration
+func NewRetryPolicy(cfg *config.Config) RetryPolicy {
+	var retryable map[types.RouteErrorType]bool
+	if len(cfg.RetryableErrors) > 0 {
+		retryable = make(map[types.RouteErrorType]bool, len(cfg.RetryableErrors))
+	}
+	for errorType := types.RouteErrPermission; errorType <= types.RouteErrGatewayUnreachable; errorType++ {
+		for _, name := range cfg.RetryableErrors {
+			if errorType.String() == name {
+				retryable[errorType] = true
+			}
+		}
+	}
+
+	return RetryPolicy{
+		MaxAttempts: max(cfg.RetryAttempts, 1),
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+		Jitter:      cfg.RetryJitter,
+		Retryable:   retryable,
+	}
+}
+
+// IsRetryable reports whether err is worth another attempt. Errors that were not classified as a
+// RouteOperationError are retried, errors that already used up their attempts are not.
+func (p RetryPolicy) IsRetryable(err error) bool {
+	if errors.Is(err, ErrRetriesExhausted) {
+		return false
+	}
+	var routeErr *types.RouteOperationError
+	if !errors.As(err, &routeErr) {
+		return true
+	}
+	if p.Retryable == nil {
+		return routeErr.IsRetryable()
+	}
+	return p.Retryable[routeErr.ErrorType]
+}
+
+// Delay returns the wait before retry number attempt (starting at 1)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		spread := time.Duration(float64(delay) * p.Jitter)
+		delay += time.Duration(rand.Int64N(int64(2*spread)+1)) - spread
+	}
+	return delay
+}
+
+// Do runs op until it succeeds, fails with a non-retryable error, runs out of attempts or ctx is done.
+// Every attempt is logged at debug level and every retry is counted in m.
+func (p RetryPolicy) Do(ctx context.Context, action string, network *net.IPNet, gateway net.IP,
+	op func(context.Context) error, log *logger.Logger, m *metrics.Metrics) error {
+	var lastErr error
+	start := time.Now()
+
+	for attempt := 1; attempt <= p.MaxAttempts; attempt++ {
+		err := op(ctx)
+		if err == nil {
+			m.RecordOperation(time.Since(start), true)
+			return nil
+		}
+
+		var routeErr *types.RouteOperationError
+		if errors.As(err, &routeErr) && routeErr.ErrorType == types.RouteErrNotFound {
+			// Nothing to do, e.g. the route was already gone
+			m.RecordOperation(time.Since(start), true)
+			return err
+		}
+
+		lastErr = err
+		if !p.IsRetryable(err) || attempt == p.MaxAttempts {
+			break
+		}
+
+		delay := p.Delay(attempt)
+		if log != nil {
+			log.Debug("retrying route operation",
+				"action", action,
+				"network", network.String(),
+				"gateway", gateway.String(),
+				"attempt", attempt,
+				"delay_ms", delay.Milliseconds(),
+				"error", err)
+		}
+		m.RecordRetry()
+
+		if err := sleepContext(ctx, delay); err != nil {
+			m.RecordOperation(time.Since(start), false)
+			return types.NewContextError(err, network, gateway)
+		}
+	}
+
+	m.RecordOperation(time.Since(start), false)
+	if p.MaxAttempts > 1 && p.IsRetryable(lastErr) {
+		return fmt.Errorf("%w: %w", ErrRetriesExhausted, lastErr)
+	}
+	return lastErr
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func testRetryPolicy() RetryPolicy {
+	cfg := config.NewConfig()
+	cfg.RetryAttempts = 4
+	cfg.RetryBaseDelay = time.Millisecond
+	cfg.RetryMaxDelay = 4 * time.Millisecond
+	cfg.RetryJitter = 0
+	return NewRetryPolicy(cfg)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := testRetryPolicy()
+
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond}
+	for i, want := range expected {
+		if got := p.Delay(i + 1); got != want {
+			t.Errorf("Retry %d: expected delay %v, got %v", i+1, want, got)
+		}
+	}
+
+	p.BaseDelay = time.Second
+	p.MaxDelay = 10 * time.Second
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Delay(1); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Jittered delay %v out of bounds", got)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	gateway := net.ParseIP("192.168.1.1")
+	routeErr := func(errorType types.RouteErrorType) error {
+		return &types.RouteOperationError{ErrorType: errorType, Destination: *network, Gateway: gateway}
+	}
+	log := logger.New("error")
+
+	tests := []struct {
+		name     string
+		err      types.RouteErrorType
+		attempts int
+		retries  int64
+	}{
+		{"retryable until exhausted", types.RouteErrGatewayUnreachable, 4, 3},
+		{"not retryable", types.RouteErrPermission, 1, 0},
+		{"already gone", types.RouteErrNotFound, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := metrics.NewMetrics()
+			attempts := 0
+			err := testRetryPolicy().Do(context.Background(), "add", network, gateway, func(context.Context) error {
+				attempts++
+				return routeErr(tt.err)
+			}, log, m)
+
+			var got *types.RouteOperationError
+			if !errors.As(err, &got) || got.ErrorType != tt.err {
+				t.Errorf("Expected a %s error, got %v", tt.err, err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, attempts)
+			}
+			if m.GetRetries() != tt.retries {
+				t.Errorf("Expected %d retries counted, got %d", tt.retries, m.GetRetries())
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDoStopsOnCancel(t *testing.T) {
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	gateway := net.ParseIP("192.168.1.1")
+
+	p := testRetryPolicy()
+	p.BaseDelay = time.Hour
+	p.MaxDelay = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := p.Do(ctx, "add", network, gateway, func(context.Context) error {
+		cancel()
+		return &types.RouteOperationError{ErrorType: types.RouteErrNetwork, Destination: *network, Gateway: gateway}
+	}, logger.New("error"), metrics.NewMetrics())
+
+	var got *types.RouteOperationError
+	if !errors.As(err, &got) || got.ErrorType != types.RouteErrCanceled {
+		t.Errorf("Expected a Canceled error while waiting to retry, got %v", err)
+	}
+}
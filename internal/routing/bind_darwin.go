@@ -0,0 +1,35 @@
+//go:build darwin
+
+package routing
+
+import (
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToInterface returns a dialer control function that scopes the socket to iface,
+// so that the connection leaves through it whatever the default route says
+func bindToInterface(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, _ string, c syscall.RawConn) error {
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			return err
+		}
+
+		level, option := unix.IPPROTO_IP, unix.IP_BOUND_IF
+		if network == "tcp6" || network == "udp6" {
+			level, option = unix.IPPROTO_IPV6, unix.IPV6_BOUND_IF
+		}
+
+		var bindErr error
+		err = c.Control(func(fd uintptr) {
+			bindErr = unix.SetsockoptInt(int(fd), level, option, ifi.Index)
+		})
+		if err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !windows
+
+package routing
+
+import (
+	"errors"
+	"syscall"
+)
+
+// bindToInterface is not supported on this platform; every dial through it fails
+func bindToInterface(string) func(network, address string, c syscall.RawConn) error {
+	return func(string, string, syscall.RawConn) error {
+		return errors.New("binding sockets to an interface is not supported on this platform")
+	}
+}
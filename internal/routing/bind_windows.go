@@ -0,0 +1,45 @@
+//go:build windows
+
+package routing
+
+import (
+	"net"
+	"syscall"
+)
+
+// Socket options from ws2ipdef.h, not defined by the syscall package
+const (
+	ipUnicastIf   = 31
+	ipv6UnicastIf = 31
+)
+
+// bindToInterface returns a dialer control function that sends the socket's traffic out of iface,
+// whatever the default route says
+func bindToInterface(iface string) func(network, address string, c syscall.RawConn) error {
+	return func(network, _ string, c syscall.RawConn) error {
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			return err
+		}
+
+		// IP_UNICAST_IF takes the index in network byte order, IPV6_UNICAST_IF in host byte order
+		level, option, value := syscall.IPPROTO_IP, ipUnicastIf, int(htonl(uint32(ifi.Index)))
+		if network == "tcp6" || network == "udp6" {
+			level, option, value = syscall.IPPROTO_IPV6, ipv6UnicastIf, ifi.Index
+		}
+
+		var bindErr error
+		err = c.Control(func(fd uintptr) {
+			bindErr = syscall.SetsockoptInt(syscall.Handle(fd), level, option, value)
+		})
+		if err != nil {
+			return err
+		}
+		return bindErr
+	}
+}
+
+// htonl converts a 32-bit value from host (little-endian) to network byte order
+func htonl(v uint32) uint32 {
+	return v>>24 | v>>8&0xff00 | v<<8&0xff0000 | v<<24
+}
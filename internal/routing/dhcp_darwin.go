@@ -0,0 +1,19 @@
+//go:build darwin
+
+package routing
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// dhcpDomain returns the domain name option of the DHCP lease of iface
+func dhcpDomain(ctx context.Context, iface string) string {
+	// ipconfig exits non-zero when the lease has no such option
+	output, err := exec.CommandContext(ctx, "ipconfig", "getoption", iface, "domain_name").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,107 @@
+//go:build linux
+
+package routing
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const networkdLeaseDir = "/run/systemd/netif/leases"
+
+// NetworkManager leases, named <client>-<connection uuid>-<iface>.lease
+const networkManagerLeaseDir = "/var/lib/NetworkManager"
+
+// dhclientLeaseGlobs match the lease databases dhclient keeps on Debian and Red Hat derived systems
+var dhclientLeaseGlobs = []string{
+	"/var/lib/dhcp/dhclient*.leases",
+	"/var/lib/dhclient/dhclient*.lease*",
+}
+
+// dhcpDomain returns the domain from the DHCP lease of iface kept by systemd-networkd,
+// NetworkManager or dhclient. The resolver configuration is not consulted: VPN clients and
+// systemd-resolved rewrite it, so it does not tell which network the uplink is attached to.
+func dhcpDomain(_ context.Context, iface string) string {
+	if netIface, err := net.InterfaceByName(iface); err == nil {
+		if domain := readLease(filepath.Join(networkdLeaseDir, strconv.Itoa(netIface.Index)), parseNetworkdLease); domain != "" {
+			return domain
+		}
+	}
+
+	// The internal DHCP client of NetworkManager writes leases in the networkd format,
+	// its dhclient backend in the dhclient format
+	if paths, err := filepath.Glob(filepath.Join(networkManagerLeaseDir, "internal-*-"+iface+".lease")); err == nil {
+		for _, path := range paths {
+			if domain := readLease(path, parseNetworkdLease); domain != "" {
+				return domain
+			}
+		}
+	}
+	globs := append([]string{filepath.Join(networkManagerLeaseDir, "dhclient-*-"+iface+".lease")}, dhclientLeaseGlobs...)
+	for _, glob := range globs {
+		paths, err := filepath.Glob(glob)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			if domain := readLease(path, func(r io.Reader) string { return parseDhclientLeases(r, iface) }); domain != "" {
+				return domain
+			}
+		}
+	}
+	return ""
+}
+
+// readLease parses the lease file at path, returning an empty domain if it cannot be read
+func readLease(path string, parse func(io.Reader) string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// parseNetworkdLease returns the DOMAINNAME of a systemd-networkd lease file
+func parseNetworkdLease(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if domain, ok := strings.CutPrefix(scanner.Text(), "DOMAINNAME="); ok {
+			return strings.TrimSpace(domain)
+		}
+	}
+	return ""
+}
+
+// parseDhclientLeases returns the domain-name option of the most recent lease of iface in a
+// dhclient lease database. dhclient appends leases, so the last one is current.
+func parseDhclientLeases(r io.Reader, iface string) string {
+	var domain, leaseIface, leaseDomain string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), ";")
+		switch {
+		case strings.HasPrefix(line, "lease"):
+			leaseIface, leaseDomain = "", ""
+		case line == "}":
+			if leaseIface == iface && leaseDomain != "" {
+				domain = leaseDomain
+			}
+		case strings.HasPrefix(line, "interface "):
+			leaseIface = strings.Trim(strings.TrimPrefix(line, "interface "), `"`)
+		case strings.HasPrefix(line, "option domain-name "):
+			value := strings.Trim(strings.TrimPrefix(line, "option domain-name "), `"`)
+			// The option may carry several space separated domains, the first is the local one
+			if fields := strings.Fields(value); len(fields) > 0 {
+				leaseDomain = strings.TrimSuffix(fields[0], ".")
+			}
+		}
+	}
+	return domain
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package routing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDhclientLeases(t *testing.T) {
+	leases := `lease {
+  interface "eth0";
+  fixed-address 192.168.1.23;
+  option subnet-mask 255.255.255.0;
+  option domain-name "old.example.com";
+  renew 2 2026/10/13 08:00:00;
+}
+lease {
+  interface "wlan0";
+  fixed-address 10.0.0.23;
+  option domain-name "cafe.example.net";
+}
+lease {
+  interface "eth0";
+  fixed-address 192.168.1.23;
+  option domain-name "corp.example.com lab.example.com";
+}
+`
+	tests := []struct {
+		iface string
+		want  string
+	}{
+		{"eth0", "corp.example.com"}, // last lease, first domain
+		{"wlan0", "cafe.example.net"},
+		{"eth1", ""},
+	}
+	for _, tt := range tests {
+		if got := parseDhclientLeases(strings.NewReader(leases), tt.iface); got != tt.want {
+			t.Errorf("parseDhclientLeases(%s) = %q, want %q", tt.iface, got, tt.want)
+		}
+	}
+}
+
+func TestParseNetworkdLease(t *testing.T) {
+	lease := "# This is private data. Do not parse.\nADDRESS=192.168.1.23\nDOMAINNAME=corp.example.com\n"
+	if got := parseNetworkdLease(strings.NewReader(lease)); got != "corp.example.com" {
+		t.Errorf("parseNetworkdLease() = %q, want corp.example.com", got)
+	}
+}
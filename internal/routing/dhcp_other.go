@@ -0,0 +1,10 @@
+//go:build !darwin && !linux
+
+package routing
+
+import "context"
+
+// dhcpDomain is not supported on this platform
+func dhcpDomain(context.Context, string) string {
+	return ""
+}
@@ -0,0 +1,189 @@
+package routing
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what Publish does when a subscriber's buffer is full
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest buffered event to make room, so publishing never waits
+	DropOldest OverflowPolicy = iota
+	// Block waits until the subscriber has room; for consumers that must not miss events
+	Block
+)
+
+// EventFilter selects the events a subscriber receives
+type EventFilter func(event NetworkEvent) bool
+
+// EventTypes returns a filter that accepts only the given event types
+func EventTypes(eventTypes ...EventType) EventFilter {
+	accepted := make(map[EventType]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		accepted[eventType] = true
+	}
+	return func(event NetworkEvent) bool {
+		return accepted[event.EventType]
+	}
+}
+
+// SubscribeOptions configures a subscription
+type SubscribeOptions struct {
+	Buffer int            // events buffered for the subscriber, at least 1
+	Policy OverflowPolicy // what to do when the buffer is full
+	Filter EventFilter    // nil receives every event
+}
+
+// EventBus fans network events out to any number of independent subscribers. Each subscriber
+// has its own buffer, so a slow one only delays the publisher if it asked for the Block policy.
+type EventBus struct {
+	mutex       sync.RWMutex
+	subscribers map[*EventSubscription]struct{}
+	closed      bool
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*EventSubscription]struct{})}
+}
+
+// EventSubscription receives the events published on a bus
+type EventSubscription struct {
+	name    string
+	bus     *EventBus
+	events  chan NetworkEvent
+	policy  OverflowPolicy
+	filter  EventFilter
+	done    chan struct{} // closed first on Close, releases a publisher blocked on this subscriber
+	once    sync.Once
+	mutex   sync.Mutex // serializes deliveries with each other and with Close
+	closed  bool
+	dropped atomic.Int64
+}
+
+// Subscribe registers a new subscriber; name identifies it in logs and status.
+// Subscribing to a closed bus returns a subscription whose channel is already closed.
+func (b *EventBus) Subscribe(name string, options SubscribeOptions) *EventSubscription {
+	s := &EventSubscription{
+		name:   name,
+		bus:    b,
+		events: make(chan NetworkEvent, max(options.Buffer, 1)),
+		policy: options.Policy,
+		filter: options.Filter,
+		done:   make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		s.close()
+		return s
+	}
+	b.subscribers[s] = struct{}{}
+	return s
+}
+
+// Publish delivers event to every subscriber whose filter accepts it
+func (b *EventBus) Publish(event NetworkEvent) {
+	b.mutex.RLock()
+	subscribers := make([]*EventSubscription, 0, len(b.subscribers))
+	for s := range b.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	b.mutex.RUnlock()
+
+	for _, s := range subscribers {
+		if s.filter == nil || s.filter(event) {
+			s.deliver(event)
+		}
+	}
+}
+
+// Close closes every subscription; later publishes are ignored
+func (b *EventBus) Close() {
+	b.mutex.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[*EventSubscription]struct{})
+	b.closed = true
+	b.mutex.Unlock()
+
+	for s := range subscribers {
+		s.close()
+	}
+}
+
+// Stats returns the number of events dropped per subscriber
+func (b *EventBus) Stats() map[string]int64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	stats := make(map[string]int64, len(b.subscribers))
+	for s := range b.subscribers {
+		stats[s.name] += s.Dropped()
+	}
+	return stats
+}
+
+// Events returns the channel events are delivered on; it is closed when the subscription ends
+func (s *EventSubscription) Events() <-chan NetworkEvent {
+	return s.events
+}
+
+// Dropped returns how many events were discarded because the subscriber fell behind
+func (s *EventSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes; events already buffered can still be read
+func (s *EventSubscription) Close() {
+	s.bus.mutex.Lock()
+	delete(s.bus.subscribers, s)
+	s.bus.mutex.Unlock()
+
+	s.close()
+}
+
+// close releases a blocked publisher, then closes the channel once no delivery is in progress
+func (s *EventSubscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.closed = true
+		close(s.events)
+	})
+}
+
+// deliver sends event according to the overflow policy
+func (s *EventSubscription) deliver(event NetworkEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+
+	if s.policy == Block {
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+		return
+	}
+
+	for {
+		select {
+		case s.events <- event:
+			return
+		default:
+		}
+
+		select {
+		case <-s.events:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
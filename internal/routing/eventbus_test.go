@@ -0,0 +1,74 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventBusFanOut(t *testing.T) {
+	bus := NewEventBus()
+	all := bus.Subscribe("all", SubscribeOptions{Buffer: 4})
+	vpn := bus.Subscribe("vpn", SubscribeOptions{Buffer: 4, Filter: EventTypes(VPNConnected, VPNDisconnected)})
+
+	bus.Publish(NetworkEvent{EventType: PhysicalGatewayChanged})
+	bus.Publish(NetworkEvent{EventType: VPNConnected})
+
+	if got := len(all.Events()); got != 2 {
+		t.Errorf("Expected both events for the unfiltered subscriber, got %d", got)
+	}
+	if got := len(vpn.Events()); got != 1 {
+		t.Fatalf("Expected only the VPN event for the filtered subscriber, got %d", got)
+	}
+	if event := <-vpn.Events(); event.EventType != VPNConnected {
+		t.Errorf("Expected VPNConnected, got %s", event.EventType)
+	}
+}
+
+func TestEventBusDropOldest(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe("slow", SubscribeOptions{Buffer: 2, Policy: DropOldest})
+
+	for _, eventType := range []EventType{VPNConnected, VPNDisconnected, NetworkAddressChanged} {
+		bus.Publish(NetworkEvent{EventType: eventType})
+	}
+
+	if slow.Dropped() != 1 || bus.Stats()["slow"] != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", slow.Dropped())
+	}
+	if event := <-slow.Events(); event.EventType != VPNDisconnected {
+		t.Errorf("Expected the oldest event to be dropped, got %s first", event.EventType)
+	}
+}
+
+func TestEventBusBlock(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("blocking", SubscribeOptions{Buffer: 1, Policy: Block})
+	bus.Publish(NetworkEvent{EventType: VPNConnected})
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(NetworkEvent{EventType: VPNDisconnected})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("Expected Publish to wait for a full blocking subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-sub.Events()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Publish to complete once the subscriber caught up")
+	}
+
+	// Closing the subscription releases a blocked publisher and closes the channel
+	go bus.Publish(NetworkEvent{EventType: VPNConnected})
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	for range sub.Events() {
+	}
+	bus.Publish(NetworkEvent{EventType: VPNConnected})
+}
@@ -0,0 +1,93 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	icmpEchoRequest = 8
+	icmpEchoReply   = 0
+)
+
+// icmpEcho sends an ICMP echo request to ip and waits for the matching reply until ctx is done.
+// It needs a raw socket, so it only works with the privileges the daemon runs with.
+func icmpEcho(ctx context.Context, ip net.IP) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("ICMP echo to %s: only IPv4 is supported", ip)
+	}
+
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return fmt.Errorf("ICMP echo to %s: %w", ip, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Unblock the read if ctx is cancelled before its deadline
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	id := uint16(os.Getpid())
+	seq := uint16(time.Now().UnixNano())
+	if _, err := conn.WriteTo(icmpEchoMessage(id, seq), &net.IPAddr{IP: ip4}); err != nil {
+		return fmt.Errorf("ICMP echo to %s: %w", ip, err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return fmt.Errorf("no ICMP echo reply from %s: %w", ip, err)
+		}
+		if addr, ok := peer.(*net.IPAddr); ok && addr.IP.Equal(ip4) && isEchoReply(buf[:n], id, seq) {
+			return nil
+		}
+	}
+}
+
+// icmpEchoMessage builds an ICMP echo request with the given identifier and sequence number
+func icmpEchoMessage(id, seq uint16) []byte {
+	msg := []byte{
+		icmpEchoRequest, 0, 0, 0,
+		byte(id >> 8), byte(id),
+		byte(seq >> 8), byte(seq),
+	}
+	msg = append(msg, "smart-route"...)
+
+	sum := icmpChecksum(msg)
+	msg[2] = byte(sum >> 8)
+	msg[3] = byte(sum)
+	return msg
+}
+
+// isEchoReply reports whether msg is the echo reply to the request with id and seq
+func isEchoReply(msg []byte, id, seq uint16) bool {
+	return len(msg) >= 8 &&
+		msg[0] == icmpEchoReply &&
+		uint16(msg[4])<<8|uint16(msg[5]) == id &&
+		uint16(msg[6])<<8|uint16(msg[7]) == seq
+}
+
+// icmpChecksum computes the Internet checksum (RFC 1071) of msg
+func icmpChecksum(msg []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(msg); i += 2 {
+		sum += uint32(msg[i])<<8 | uint32(msg[i+1])
+	}
+	if len(msg)%2 == 1 {
+		sum += uint32(msg[len(msg)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
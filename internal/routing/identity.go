@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// identifyTimeout bounds the lookups made to identify a network
+const identifyTimeout = 2 * time.Second
+
+// NetworkIdentity describes the network the physical uplink is attached to, so that
+// per-network settings can be chosen. Parts that cannot be determined are left empty.
+type NetworkIdentity struct {
+	Interface  string
+	Gateway    net.IP
+	GatewayMAC net.HardwareAddr // from the neighbor table
+	Addresses  []*net.IPNet     // of the interface, with their prefix lengths
+	DHCPDomain string           // domain name handed out by DHCP, where a lease can be read
+}
+
+// IdentifyNetwork collects the identity of the network reached through gateway on iface.
+// The gateway's neighbor entry is resolved if it is not known yet.
+func IdentifyNetwork(ctx context.Context, gateway net.IP, iface string) NetworkIdentity {
+	ctx, cancel := context.WithTimeout(ctx, identifyTimeout)
+	defer cancel()
+
+	identity := NetworkIdentity{
+		Interface:  iface,
+		Gateway:    gateway,
+		DHCPDomain: dhcpDomain(ctx, iface),
+	}
+	if netIface, err := net.InterfaceByName(iface); err == nil {
+		if addrs, err := netIface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				if ipNet, ok := addr.(*net.IPNet); ok {
+					identity.Addresses = append(identity.Addresses, ipNet)
+				}
+			}
+		}
+	}
+	if gateway != nil {
+		identity.GatewayMAC = resolveNeighborMAC(ctx, gateway)
+	}
+	return identity
+}
+
+// resolveNeighborMAC returns the hardware address of ip, triggering address resolution if the
+// neighbor table has no entry yet; nil if it cannot be resolved before ctx is done
+func resolveNeighborMAC(ctx context.Context, ip net.IP) net.HardwareAddr {
+	for triggered := false; ; triggered = true {
+		mac, err := neighborMAC(ctx, ip)
+		if err != nil || mac != nil {
+			return mac
+		}
+
+		if !triggered {
+			triggerResolution(ip)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(neighborPollInterval):
+		}
+	}
+}
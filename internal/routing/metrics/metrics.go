@@ -14,13 +14,43 @@ type Metrics struct {
 	NetworkChanges  int64
 	LastUpdate      time.Time
 	MemoryUsage     int64
-	mutex           sync.RWMutex
+	Retries         int64
+
+	// Drift reconciliation counters
+	DriftChecks   int64
+	DriftDetected int64
+	DriftRepairs  int64
+	MissingRoutes int64
+	StaleRoutes   int64
+	LastDrift     time.Time
+
+	// Direct path health counters, latency and loss are from the latest round
+	PathChecks         int64
+	PathDegradations   int64
+	PathRecoveries     int64
+	DirectLatency      time.Duration
+	DirectLoss         float64
+	VPNLatency         time.Duration
+	VPNLoss            float64
+	WithdrawnNetworks  int
+	LastPathTransition time.Time
+
+	// Connection state machine, time in state counts completed stays only
+	CurrentState     string
+	StateSince       time.Time
+	StateTransitions int64
+	StateEntries     map[string]int64
+	TimeInState      map[string]time.Duration
+
+	mutex sync.RWMutex
 }
 
 // NewMetrics creates a new metrics instance
 func NewMetrics() *Metrics {
 	return &Metrics{
-		LastUpdate: time.Now(),
+		LastUpdate:   time.Now(),
+		StateEntries: make(map[string]int64),
+		TimeInState:  make(map[string]time.Duration),
 	}
 }
 
@@ -45,6 +75,36 @@ func (m *Metrics) RecordOperation(duration time.Duration, success bool) {
 	m.LastUpdate = time.Now()
 }
 
+// RecordRetry records a retried route operation attempt
+func (m *Metrics) RecordRetry() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.Retries++
+}
+
+// GetRetries returns the number of retried route operation attempts
+func (m *Metrics) GetRetries() int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.Retries
+}
+
+// GetOperationStats returns the route operation statistics
+func (m *Metrics) GetOperationStats() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"route_operations": m.RouteOperations,
+		"successful_ops":   m.SuccessfulOps,
+		"failed_ops":       m.FailedOps,
+		"average_op_time":  m.AverageOpTime,
+		"retries":          m.Retries,
+	}
+}
+
 // RecordNetworkChange records the network change metrics
 func (m *Metrics) RecordNetworkChange() {
 	m.mutex.Lock()
@@ -53,10 +113,140 @@ func (m *Metrics) RecordNetworkChange() {
 	m.NetworkChanges++
 }
 
+// GetNetworkChanges returns the number of network events observed
+func (m *Metrics) GetNetworkChanges() int64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.NetworkChanges
+}
+
 // GetStats returns the metrics statistics
 func (m *Metrics) GetStats() (int64, int64, int64, time.Duration, int64) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 	
 	return m.RouteOperations, m.SuccessfulOps, m.FailedOps, m.AverageOpTime, m.NetworkChanges
-}
\ No newline at end of file
+}
+
+// RecordDriftCheck records the result of a drift reconciliation pass
+func (m *Metrics) RecordDriftCheck(missing, stale int, repaired bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.DriftChecks++
+	if missing == 0 && stale == 0 {
+		return
+	}
+
+	m.DriftDetected++
+	m.MissingRoutes += int64(missing)
+	m.StaleRoutes += int64(stale)
+	m.LastDrift = time.Now()
+	if repaired {
+		m.DriftRepairs++
+	}
+}
+
+// GetDriftStats returns the drift reconciliation statistics
+func (m *Metrics) GetDriftStats() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"drift_checks":   m.DriftChecks,
+		"drift_detected": m.DriftDetected,
+		"drift_repairs":  m.DriftRepairs,
+		"missing_routes": m.MissingRoutes,
+		"stale_routes":   m.StaleRoutes,
+		"last_drift":     m.LastDrift,
+	}
+}
+
+// RecordPathCheck records the averaged result of a direct path health round
+func (m *Metrics) RecordPathCheck(directLatency time.Duration, directLoss float64, vpnLatency time.Duration, vpnLoss float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.PathChecks++
+	m.DirectLatency = directLatency
+	m.DirectLoss = directLoss
+	m.VPNLatency = vpnLatency
+	m.VPNLoss = vpnLoss
+}
+
+// RecordPathTransition records managed routes being withdrawn from or restored to the direct path
+func (m *Metrics) RecordPathTransition(degraded bool, withdrawnNetworks int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if degraded {
+		m.PathDegradations++
+	} else {
+		m.PathRecoveries++
+	}
+	m.WithdrawnNetworks = withdrawnNetworks
+	m.LastPathTransition = time.Now()
+}
+
+// GetPathHealthStats returns the direct path health statistics
+func (m *Metrics) GetPathHealthStats() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"path_checks":          m.PathChecks,
+		"path_degradations":    m.PathDegradations,
+		"path_recoveries":      m.PathRecoveries,
+		"direct_latency":       m.DirectLatency,
+		"direct_loss":          m.DirectLoss,
+		"vpn_latency":          m.VPNLatency,
+		"vpn_loss":             m.VPNLoss,
+		"withdrawn_networks":   m.WithdrawnNetworks,
+		"last_path_transition": m.LastPathTransition,
+	}
+}
+
+// RecordStateTransition records the connection state machine entering state to; from is empty
+// when the state is set initially rather than reached through a transition
+func (m *Metrics) RecordStateTransition(from, to string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	if m.CurrentState != "" {
+		m.TimeInState[m.CurrentState] += now.Sub(m.StateSince)
+	}
+	if from != "" {
+		m.StateTransitions++
+	}
+	m.StateEntries[to]++
+	m.CurrentState = to
+	m.StateSince = now
+}
+
+// GetStateStats returns the connection state statistics, with the current stay included in the time in state
+func (m *Metrics) GetStateStats() map[string]interface{} {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entries := make(map[string]int64, len(m.StateEntries))
+	for state, count := range m.StateEntries {
+		entries[state] = count
+	}
+	timeInState := make(map[string]time.Duration, len(m.TimeInState)+1)
+	for state, duration := range m.TimeInState {
+		timeInState[state] = duration
+	}
+	if m.CurrentState != "" {
+		timeInState[m.CurrentState] += time.Since(m.StateSince)
+	}
+
+	return map[string]interface{}{
+		"state":             m.CurrentState,
+		"state_since":       m.StateSince,
+		"state_transitions": m.StateTransitions,
+		"state_entries":     entries,
+		"time_in_state":     timeInState,
+	}
+}
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"sync"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/wesleywu/smart-route/internal/logger"
 	"github.com/wesleywu/smart-route/internal/routing/types"
+	"github.com/wesleywu/smart-route/internal/utils"
 )
 
 // NetworkMonitor monitors network changes and VPN state using event-driven architecture
@@ -15,25 +17,23 @@ type NetworkMonitor struct {
 	// Physical network state (for route management)
 	physicalGateway   net.IP
 	physicalInterface string
+	physicalUplinks   []types.Uplink // every physical uplink, most preferred first, only kept if trackUplinks
+	trackUplinks      bool
 	
-	// Route socket for real-time monitoring
-	routeSocket    int
-	eventChannel   chan NetworkEvent
+	events         *EventBus
 	stopChannel    chan struct{}
+	ctx            context.Context // cancelled on Stop, bounds route manager queries and sources
+	cancel         context.CancelFunc
 	mutex          sync.RWMutex
 	isRunning      bool
 	
-	// Polling fallback mechanism
-	pollInterval        time.Duration
-	pollEnabled         bool
-	pollTicker          *time.Ticker
-	pollStopChannel     chan struct{}
-	
-	// Health monitoring
-	lastRouteEventTime  time.Time
-	routeSocketErrors   int
-	maxSocketErrors     int
-	healthCheckInterval time.Duration
+	// Sources of change notifications, with polling as the fallback
+	sources         []EventSource
+	sourceErrors    map[string]string
+	pollInterval    time.Duration
+	pollEnabled     bool
+	lastTrigger     time.Time
+	lastTriggerFrom string
 	
 	// Route manager for gateway queries
 	routeManager types.RouteManager
@@ -45,17 +45,30 @@ type NetworkMonitor struct {
 	lastVPNInterface string
 	lastVPNConnected bool
 	
-	// Rate limiting
-	lastGatewayCheck time.Time
+	// Check requests are coalesced and served by a single goroutine, together with observed states
+	checkTrigger chan struct{}
+	observations chan observation
+
+	// Records the inputs and events of the monitor, nil unless tracing
+	recorder *TraceRecorder
+}
+
+// observation is a state to apply in the check loop; the known flags tell which parts are valid
+type observation struct {
+	source        string
+	state         NetworkState
+	physicalKnown bool
+	defaultKnown  bool
 }
 
 // NetworkEvent represents a network state change event
 type NetworkEvent struct {
 	EventType         EventType
-	PhysicalInterface string    // Physical network interface (e.g., en0, eth0)
-	VPNInterface      string    // VPN interface if applicable (e.g., utun0)
-	PhysicalGateway   net.IP    // Physical network gateway (for route management)
-	CurrentGateway    net.IP    // Current system default gateway (includes VPN)
+	PhysicalInterface string         // Physical network interface (e.g., en0, eth0)
+	VPNInterface      string         // VPN interface if applicable (e.g., utun0)
+	PhysicalGateway   net.IP         // Physical network gateway (for route management)
+	CurrentGateway    net.IP         // Current system default gateway (includes VPN)
+	Uplinks           []types.Uplink // Every physical uplink, most preferred first; nil unless uplinks are tracked
 	Timestamp         time.Time
 	
 	// VPN state information
@@ -78,6 +91,12 @@ const (
 	NetworkInterfaceDown
 	// NetworkAddressChanged indicates network address change
 	NetworkAddressChanged
+	// DirectPathDegraded indicates managed routes were withdrawn because the direct path degraded
+	DirectPathDegraded
+	// DirectPathRecovered indicates withdrawn managed routes are restored
+	DirectPathRecovered
+	// PollFallbackEnabled indicates route socket monitoring failed and polling took over
+	PollFallbackEnabled
 )
 
 // String returns the string representation of the event type
@@ -95,6 +114,12 @@ func (e EventType) String() string {
 		return "NetworkInterfaceDown"
 	case NetworkAddressChanged:
 		return "NetworkAddressChanged"
+	case DirectPathDegraded:
+		return "DirectPathDegraded"
+	case DirectPathRecovered:
+		return "DirectPathRecovered"
+	case PollFallbackEnabled:
+		return "PollFallbackEnabled"
 	default:
 		return "UnknownEvent"
 	}
@@ -106,17 +131,20 @@ func NewNetworkMonitor(pollInterval time.Duration, routeManager types.RouteManag
 		return nil, fmt.Errorf("route manager cannot be nil")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	// Get initial physical gateway for route management
-	physicalGW, physicalIface, err := routeManager.GetPhysicalGateway()
+	physicalGW, physicalIface, err := routeManager.GetPhysicalGateway(ctx)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to get initial physical gateway: %w", err)
 	}
 
 	// Check current VPN state for initialization
 	initialVPNState := false
 	initialVPNInterface := ""
-	if _, currentIface, err := routeManager.GetSystemDefaultRoute(); err == nil {
-		if isVPNInterface(currentIface) {
+	if _, currentIface, err := routeManager.GetSystemDefaultRoute(ctx); err == nil {
+		if utils.IsVPNInterface(currentIface) {
 			initialVPNState = true
 			initialVPNInterface = currentIface
 		}
@@ -128,18 +156,17 @@ func NewNetworkMonitor(pollInterval time.Duration, routeManager types.RouteManag
 		physicalInterface: physicalIface,
 		
 		// Event handling
-		eventChannel:        make(chan NetworkEvent, 100),
+		events:              NewEventBus(),
 		stopChannel:         make(chan struct{}),
+		ctx:                 ctx,
+		cancel:              cancel,
+		checkTrigger:        make(chan struct{}, 1),
+		observations:        make(chan observation),
 		
-		// Polling fallback
-		pollInterval:    pollInterval,
-		pollEnabled:     false, // Start with event-driven mode
-		pollStopChannel: make(chan struct{}),
-		
-		// Health monitoring
-		maxSocketErrors:     5,              // 增加容错次数
-		healthCheckInterval: 60 * time.Second, // 延长检查间隔
-		lastRouteEventTime:  time.Now(),
+		// Event sources, platform notifications first and polling as the fallback
+		sources:      platformSources(),
+		sourceErrors: make(map[string]string),
+		pollInterval: pollInterval,
 		
 		// Dependencies
 		routeManager: routeManager,
@@ -151,6 +178,58 @@ func NewNetworkMonitor(pollInterval time.Duration, routeManager types.RouteManag
 	}, nil
 }
 
+// AddSource adds an event source; it must be called before Start
+func (nm *NetworkMonitor) AddSource(source EventSource) {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	nm.sources = append(nm.sources, source)
+}
+
+// SetRecorder records the monitor's inputs and events to a trace, starting with its current state;
+// it must be called before Start
+func (nm *NetworkMonitor) SetRecorder(recorder *TraceRecorder) {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+
+	nm.recorder = recorder
+	state := NetworkState{
+		PhysicalGateway:   nm.physicalGateway,
+		PhysicalInterface: nm.physicalInterface,
+		DefaultGateway:    nm.physicalGateway,
+		DefaultInterface:  nm.physicalInterface,
+		Uplinks:           nm.physicalUplinks,
+	}
+	if nm.lastVPNConnected {
+		state.DefaultGateway = nil
+		state.DefaultInterface = nm.lastVPNInterface
+	}
+	recorder.Record(TraceEntry{Kind: TraceKindInit, State: &state})
+}
+
+// SetSources replaces all event sources including the platform ones, e.g. to drive the monitor
+// from a script in tests; it must be called before Start. Without sources the monitor polls.
+func (nm *NetworkMonitor) SetSources(sources ...EventSource) {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	nm.sources = sources
+}
+
+// TrackUplinks makes the monitor track every physical uplink rather than only the preferred one,
+// and report a change of any of them as PhysicalGatewayChanged; it must be called before Start
+// and before SetRecorder
+func (nm *NetworkMonitor) TrackUplinks() {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+
+	nm.trackUplinks = true
+	uplinks, err := ListUplinks(nm.ctx, nm.routeManager)
+	if err != nil {
+		nm.logger.Debug("Failed to list physical uplinks", "error", err)
+		return
+	}
+	nm.physicalUplinks = uplinks
+}
+
 // Start starts the network monitor
 func (nm *NetworkMonitor) Start() error {
 	nm.mutex.Lock()
@@ -160,15 +239,15 @@ func (nm *NetworkMonitor) Start() error {
 		return fmt.Errorf("network monitor is already running")
 	}
 
-	// Try to create route socket for real-time monitoring
-	nm.startPlatformMonitoring()
-
-	// Start health check goroutine
-	go nm.healthCheck()
+	// Start the single goroutine that performs network state checks
+	go nm.checkLoop()
 
-	// If polling is enabled, start polling
-	if nm.pollEnabled {
-		nm.startPolling()
+	for _, source := range nm.sources {
+		go nm.runSource(source)
+	}
+	if len(nm.sources) == 0 {
+		nm.logger.Debug("No event source available, polling for network changes")
+		nm.startPollingLocked()
 	}
 
 	nm.isRunning = true
@@ -184,20 +263,53 @@ func (nm *NetworkMonitor) Stop() error {
 		return nil
 	}
 
+	// Cancelling the context stops the sources, including polling
 	close(nm.stopChannel)
+	nm.cancel()
 
-	// 停止轮询
-	nm.stopPolling()
-
-	nm.closeRouteSocket()
+	// Releases subscribers waiting for events and publishers blocked on them
+	nm.events.Close()
 
 	nm.isRunning = false
 	return nil
 }
 
-// Events returns the read-only channel for network events
-func (nm *NetworkMonitor) Events() <-chan NetworkEvent {
-	return nm.eventChannel
+// runSource runs an event source; polling takes over if it fails
+func (nm *NetworkMonitor) runSource(source EventSource) {
+	nm.logger.Debug("Event source started", "source", source.Name())
+	err := source.Run(nm.ctx, nm)
+	if err == nil || nm.ctx.Err() != nil {
+		return
+	}
+
+	nm.logger.Warn("Event source failed, enabling polling as fallback", "source", source.Name(), "error", err)
+	nm.mutex.Lock()
+	nm.sourceErrors[source.Name()] = err.Error()
+	if nm.startPollingLocked() {
+		nm.reportPollFallbackLocked()
+	}
+	nm.mutex.Unlock()
+}
+
+// startPollingLocked starts the poll source unless it is running; the caller holds the mutex
+func (nm *NetworkMonitor) startPollingLocked() bool {
+	if nm.pollEnabled {
+		return false
+	}
+	nm.pollEnabled = true
+	go nm.runSource(&PollSource{Interval: nm.pollInterval})
+	return true
+}
+
+// Subscribe registers an independent consumer of the network events
+func (nm *NetworkMonitor) Subscribe(name string, options SubscribeOptions) *EventSubscription {
+	return nm.events.Subscribe(name, options)
+}
+
+// Publish delivers an event produced outside the monitor, e.g. by path health checks,
+// to the same subscribers as the monitor's own events
+func (nm *NetworkMonitor) Publish(event NetworkEvent) {
+	nm.events.Publish(event)
 }
 
 // GetPhysicalGateway returns the current physical gateway and interface
@@ -212,142 +324,111 @@ func (nm *NetworkMonitor) GetPhysicalGateway() (net.IP, string) {
 	return gateway, nm.physicalInterface
 }
 
-// monitorRouteSocket monitors the route socket
-func (nm *NetworkMonitor) monitorRouteSocket() {
-	buffer := make([]byte, 4096)
-	for {
-		select {
-		case <-nm.stopChannel:
-			return
-		default:
-			// Use blocking read to avoid busy waiting
-			n, err := nm.readRouteSocket(buffer)
-			if err != nil {
-				// Check if the error is due to monitor stop
-				select {
-				case <-nm.stopChannel:
-					return
-				default:
-				}
-
-				// Only count actual read errors
-				if nm.isSocketError(err) {
-					nm.mutex.Lock()
-					nm.routeSocketErrors++
-					nm.mutex.Unlock()
-				}
-
-				// Short delay before retrying to avoid busy waiting
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
-
-			// 成功读取数据，重置错误计数
-			nm.mutex.Lock()
-			nm.routeSocketErrors = 0
-			nm.lastRouteEventTime = time.Now()
-			nm.mutex.Unlock()
-
-			if event := nm.parseRouteMessage(buffer[:n]); event != nil {
-				select {
-				case nm.eventChannel <- *event:
-				case <-nm.stopChannel:
-					return
-				}
-			}
-		}
+// reportPollFallbackLocked emits a PollFallbackEnabled event carrying the last known network
+// state, so that subscribers do not mistake it for a loss of the VPN and the gateway. The caller
+// holds the mutex; the event is published in the background, as a subscriber with the Block
+// policy may delay its delivery.
+func (nm *NetworkMonitor) reportPollFallbackLocked() {
+	vpnInterface := ""
+	if nm.lastVPNConnected {
+		vpnInterface = nm.lastVPNInterface
 	}
+	go nm.events.Publish(NetworkEvent{
+		EventType:         PollFallbackEnabled,
+		PhysicalInterface: nm.physicalInterface,
+		VPNInterface:      vpnInterface,
+		PhysicalGateway:   nm.physicalGateway,
+		Uplinks:           nm.physicalUplinks,
+		Timestamp:         time.Now(),
+		VPNConnected:      nm.lastVPNConnected,
+	})
 }
 
-// healthCheck is a health check for the network monitor
-// It only enables polling when the route socket has consecutive errors
-// Note: No network events are normal and should not trigger polling
-func (nm *NetworkMonitor) healthCheck() {
-	ticker := time.NewTicker(nm.healthCheckInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-nm.stopChannel:
-			return
-		case <-ticker.C:
-			nm.mutex.RLock()
-			routeSocketErrors := nm.routeSocketErrors
-			pollEnabled := nm.pollEnabled
-			nm.mutex.RUnlock()
-
-			// 只有在route socket连续出错时才启用轮询
-			// 没有网络变化时不收到事件是正常的，不应该视为错误
-			if !pollEnabled && routeSocketErrors >= nm.maxSocketErrors {
-				nm.mutex.Lock()
-				nm.pollEnabled = true
-				nm.mutex.Unlock()
-				nm.startPolling()
-			}
-
-			// 如果route socket恢复正常，可以考虑禁用轮询
-			if pollEnabled && routeSocketErrors == 0 {
-				// 等待一段时间确保socket稳定后再禁用轮询
-				time.Sleep(5 * time.Second)
-				nm.mutex.RLock()
-				stillNoErrors := nm.routeSocketErrors == 0
-				nm.mutex.RUnlock()
-
-				if stillNoErrors {
-					nm.logger.Debug("Route socket appears stable, disabling polling")
-					nm.mutex.Lock()
-					nm.pollEnabled = false
-					nm.mutex.Unlock()
-					nm.stopPolling()
-				}
-			}
-		}
+// Trigger implements EventSink. Triggers are rate limited and coalesced, a burst of
+// route messages results in a single check.
+func (nm *NetworkMonitor) Trigger(source string) {
+	nm.mutex.Lock()
+	now := time.Now()
+	if !nm.lastTrigger.IsZero() && now.Sub(nm.lastTrigger) <= 200*time.Millisecond {
+		nm.mutex.Unlock()
+		return
 	}
+	nm.lastTrigger = now
+	nm.lastTriggerFrom = source
+	nm.mutex.Unlock()
+
+	nm.recorder.Record(TraceEntry{Kind: TraceKindTrigger, Source: source})
+	nm.requestCheck()
 }
 
-// startPolling starts polling
-func (nm *NetworkMonitor) startPolling() {
-	nm.mutex.Lock()
-	defer nm.mutex.Unlock()
+// Observe implements EventSink. The state is applied by the check loop like a queried one.
+func (nm *NetworkMonitor) Observe(source string, state NetworkState) {
+	nm.logger.Debug("Network state observed", "source", source)
+	nm.recorder.Record(TraceEntry{Kind: TraceKindObserve, Source: source, State: &state})
+	nm.observe(observation{source: source, state: state, physicalKnown: true, defaultKnown: true})
+}
 
-	if nm.pollTicker != nil {
-		return // 已经在运行
+// observe hands an observation to the check loop
+func (nm *NetworkMonitor) observe(obs observation) {
+	select {
+	case nm.observations <- obs:
+	case <-nm.stopChannel:
 	}
+}
 
-	nm.pollTicker = time.NewTicker(nm.pollInterval)
-	nm.pollStopChannel = make(chan struct{})
+// requestCheck schedules a network state check; requests arriving while one is pending are coalesced
+func (nm *NetworkMonitor) requestCheck() {
+	select {
+	case nm.checkTrigger <- struct{}{}:
+	default:
+	}
+}
 
-	go func() {
-		defer nm.pollTicker.Stop()
-		for {
-			select {
-			case <-nm.stopChannel:
-				return
-			case <-nm.pollStopChannel:
-				return
-			case <-nm.pollTicker.C:
-				nm.checkNetworkChanges()
-			}
+// checkLoop serves check requests one at a time so that concurrent checks can never
+// observe and report the same transition twice, or report transitions out of order
+func (nm *NetworkMonitor) checkLoop() {
+	for {
+		select {
+		case <-nm.stopChannel:
+			return
+		case obs := <-nm.observations:
+			nm.applyState(obs.state, obs.physicalKnown, obs.defaultKnown)
+			continue
+		case <-nm.checkTrigger:
 		}
-	}()
 
-	nm.logger.Debug("Polling started", "interval", nm.pollInterval)
-}
+		// Small delay to allow network stack to settle
+		select {
+		case <-nm.stopChannel:
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
 
-// stopPolling stops polling
-func (nm *NetworkMonitor) stopPolling() {
-	if nm.pollTicker != nil {
-		close(nm.pollStopChannel)
-		nm.pollTicker = nil
-		nm.logger.Debug("Polling stopped")
+		nm.checkNetworkChanges()
 	}
 }
 
 // checkNetworkChanges checks for both physical gateway changes and VPN state changes
 func (nm *NetworkMonitor) checkNetworkChanges() {
 	// Check both physical gateway and current default route
-	physicalGW, physicalIface, err1 := nm.routeManager.GetPhysicalGateway()
-	currentGW, currentIface, err2 := nm.routeManager.GetSystemDefaultRoute()
+	start := time.Now()
+	physicalGW, physicalIface, err1 := nm.routeManager.GetPhysicalGateway(nm.ctx)
+	currentGW, currentIface, err2 := nm.routeManager.GetSystemDefaultRoute(nm.ctx)
+	state := NetworkState{
+		PhysicalGateway:   physicalGW,
+		PhysicalInterface: physicalIface,
+		DefaultGateway:    currentGW,
+		DefaultInterface:  currentIface,
+	}
+	if nm.trackUplinks && err1 == nil {
+		// Unknown uplinks are left out, the last known ones stay valid
+		if uplinks, err := ListUplinks(nm.ctx, nm.routeManager); err == nil {
+			state.Uplinks = uplinks
+		} else {
+			nm.logger.Debug("Failed to list physical uplinks", "error", err)
+		}
+	}
+	nm.recordCheck(state, err1, err2, time.Since(start))
 
 	if err1 != nil {
 		nm.logger.Debug("Failed to get physical gateway", "error", err1)
@@ -360,6 +441,30 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 		return
 	}
 
+	nm.applyState(state, err1 == nil, err2 == nil)
+}
+
+// recordCheck records the route manager results of a check
+func (nm *NetworkMonitor) recordCheck(state NetworkState, physicalErr, defaultErr error, duration time.Duration) {
+	if nm.recorder == nil {
+		return
+	}
+	entry := TraceEntry{Kind: TraceKindCheck, State: &state, Duration: duration}
+	if physicalErr != nil {
+		entry.PhysicalError = physicalErr.Error()
+	}
+	if defaultErr != nil {
+		entry.DefaultError = defaultErr.Error()
+	}
+	nm.recorder.Record(entry)
+}
+
+// applyState compares a state with the last one and publishes an event for a transition.
+// physicalKnown and defaultKnown tell which parts of the state are valid.
+func (nm *NetworkMonitor) applyState(state NetworkState, physicalKnown, defaultKnown bool) {
+	physicalGW, physicalIface := state.PhysicalGateway, state.PhysicalInterface
+	currentGW, currentIface := state.DefaultGateway, state.DefaultInterface
+
 	nm.mutex.Lock()
 	oldPhysicalGW := nm.physicalGateway
 	oldPhysicalIface := nm.physicalInterface
@@ -367,9 +472,11 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 	// Physical gateway change detection (for WiFi switching)
 	physicalGWChanged := false
 	physicalIfaceChanged := false
-	if err1 == nil {
+	uplinksChanged := false
+	if physicalKnown {
 		physicalGWChanged = !nm.physicalGateway.Equal(physicalGW)
 		physicalIfaceChanged = nm.physicalInterface != physicalIface
+		uplinksChanged = nm.trackUplinks && state.Uplinks != nil && !types.SameUplinks(nm.physicalUplinks, state.Uplinks)
 	}
 
 	// VPN state detection (from current default route)
@@ -378,14 +485,14 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 	lastIsVPN := nm.lastVPNConnected
 	lastVPNIface := nm.lastVPNInterface
 
-	if err2 == nil {
-		currentIsVPN = isVPNInterface(currentIface)
+	if defaultKnown {
+		currentIsVPN = utils.IsVPNInterface(currentIface)
 		vpnStateChanged = currentIsVPN != lastIsVPN ||
 			(currentIsVPN && currentIface != lastVPNIface)
 	}
 
 	// Debug logging
-	if err1 == nil && err2 == nil {
+	if physicalKnown && defaultKnown {
 		nm.logger.Debug("Network state check",
 			"physical_gateway", physicalGW.String(),
 			"physical_interface", physicalIface,
@@ -399,8 +506,15 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 	hasChanges := false
 	var event NetworkEvent
 
-	if physicalGWChanged || physicalIfaceChanged {
-		// Physical gateway change (WiFi switching)
+	if uplinksChanged {
+		nm.logger.Debug("Physical uplinks changed",
+			"old_uplinks", len(nm.physicalUplinks),
+			"new_uplinks", len(state.Uplinks))
+		nm.physicalUplinks = state.Uplinks
+	}
+
+	if physicalGWChanged || physicalIfaceChanged || uplinksChanged {
+		// Physical gateway change (WiFi switching), or another uplink came up or went down
 		nm.physicalGateway = physicalGW
 		nm.physicalInterface = physicalIface
 		hasChanges = true
@@ -411,6 +525,7 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 			VPNInterface:      getVPNInterface(currentIface, currentIsVPN),
 			PhysicalGateway:   physicalGW,
 			CurrentGateway:    currentGW,
+			Uplinks:           nm.physicalUplinks,
 			Timestamp:         time.Now(),
 			VPNConnected:      currentIsVPN,
 		}
@@ -449,6 +564,7 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 			VPNInterface:      getVPNInterface(currentIface, currentIsVPN),
 			PhysicalGateway:   physicalGW,
 			CurrentGateway:    currentGW,
+			Uplinks:           nm.physicalUplinks,
 			Timestamp:         time.Now(),
 			VPNConnected:      currentIsVPN,
 		}
@@ -457,71 +573,25 @@ func (nm *NetworkMonitor) checkNetworkChanges() {
 	nm.mutex.Unlock()
 
 	if hasChanges {
-		select {
-		case nm.eventChannel <- event:
-		case <-nm.stopChannel:
-			return
-		}
-	}
-}
-
-// parseRouteMessage parses route socket messages and triggers network checks
-func (nm *NetworkMonitor) parseRouteMessage(data []byte) *NetworkEvent {
-	if len(data) < 4 {
-		return nil
-	}
-
-	// Rate limit: only trigger checks every 200ms to avoid spam
-	nm.mutex.Lock()
-	now := time.Now()
-	if nm.lastGatewayCheck.IsZero() || now.Sub(nm.lastGatewayCheck) > 200*time.Millisecond {
-		nm.lastGatewayCheck = now
-		nm.mutex.Unlock()
-
-		// Trigger immediate network check when route messages are received
-		go func() {
-			// Small delay to allow network stack to settle
-			time.Sleep(100 * time.Millisecond)
-			nm.checkNetworkChanges()
-		}()
-	} else {
-		nm.mutex.Unlock()
+		nm.recorder.Record(TraceEntry{Kind: TraceKindEvent, Event: &TraceEvent{
+			Type:              event.EventType.String(),
+			VPNConnected:      event.VPNConnected,
+			PhysicalGateway:   event.PhysicalGateway,
+			PhysicalInterface: event.PhysicalInterface,
+			VPNInterface:      event.VPNInterface,
+		}})
+		nm.events.Publish(event)
 	}
-
-	return nil // Don't return unparsed events to avoid spam
 }
 
 // getVPNInterface returns the VPN interface name if VPN is connected, otherwise empty string
 func getVPNInterface(currentIface string, isVPNConnected bool) string {
-	if isVPNConnected && isVPNInterface(currentIface) {
+	if isVPNConnected && utils.IsVPNInterface(currentIface) {
 		return currentIface
 	}
 	return ""
 }
 
-// isVPNInterface checks if the given interface name is a VPN interface
-func isVPNInterface(interfaceName string) bool {
-	// Common VPN interface patterns
-	if len(interfaceName) >= 4 {
-		prefix := interfaceName[:4]
-		switch prefix {
-		case "utun", "tun0", "tap0":
-			return true
-		}
-	}
-
-	// Check for other common VPN interface patterns
-	if len(interfaceName) >= 3 {
-		prefix := interfaceName[:3]
-		switch prefix {
-		case "tun", "tap", "ppp":
-			return true
-		}
-	}
-
-	return false
-}
-
 // GetMonitorStatus returns the current status of the network monitor
 func (nm *NetworkMonitor) GetMonitorStatus() map[string]interface{} {
 	nm.mutex.RLock()
@@ -530,15 +600,24 @@ func (nm *NetworkMonitor) GetMonitorStatus() map[string]interface{} {
 	return map[string]interface{}{
 		"is_running":            nm.isRunning,
 		"poll_enabled":          nm.pollEnabled,
-		"route_socket":          nm.routeSocket > 0,
-		"route_socket_errors":   nm.routeSocketErrors,
-		"last_event_time":       nm.lastRouteEventTime,
-		"time_since_last_event": time.Since(nm.lastRouteEventTime),
-		"health_check_interval": nm.healthCheckInterval,
+		"sources":               sourceNames(nm.sources),
+		"source_errors":         nm.sourceErrors,
+		"last_trigger_time":     nm.lastTrigger,
+		"last_trigger_source":   nm.lastTriggerFrom,
 		"poll_interval":         nm.pollInterval,
 		"physical_gateway":      nm.physicalGateway.String(),
 		"physical_interface":    nm.physicalInterface,
 		"vpn_connected":         nm.lastVPNConnected,
 		"vpn_interface":         nm.lastVPNInterface,
+		"dropped_events":        nm.events.Stats(),
+	}
+}
+
+// sourceNames returns the names of event sources
+func sourceNames(sources []EventSource) []string {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.Name())
 	}
+	return names
 }
@@ -0,0 +1,130 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+)
+
+// newTestMonitor creates a monitor over the fake backend, whose physical and default gateway is 192.0.2.1 on fake0
+func newTestMonitor(t *testing.T, sources ...EventSource) (*NetworkMonitor, *platform.FakeRouteManager, *EventSubscription) {
+	t.Helper()
+	rm := platform.NewFakeRouteManager(config.NewConfig())
+	nm, err := NewNetworkMonitor(time.Hour, rm, logger.New("error"))
+	if err != nil {
+		t.Fatalf("Failed to create monitor: %v", err)
+	}
+	nm.SetSources(sources...)
+	sub := nm.Subscribe("test", SubscribeOptions{Buffer: 10, Policy: Block})
+	if err := nm.Start(); err != nil {
+		t.Fatalf("Failed to start monitor: %v", err)
+	}
+	t.Cleanup(func() { nm.Stop() })
+	return nm, rm, sub
+}
+
+// nextEvent waits for the next event of a subscription
+func nextEvent(t *testing.T, sub *EventSubscription) NetworkEvent {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a network event")
+		return NetworkEvent{}
+	}
+}
+
+func TestMonitorScriptedSource(t *testing.T) {
+	physical := net.ParseIP("192.0.2.1")
+	wifi := net.ParseIP("198.51.100.1")
+	done := make(chan struct{})
+	script := &ScriptedSource{
+		Done: done,
+		Steps: []ScriptStep{
+			{State: NetworkState{PhysicalGateway: physical, PhysicalInterface: "fake0", DefaultInterface: "utun3"}},
+			// Reported twice, e.g. by overlapping sources: only one event
+			{State: NetworkState{PhysicalGateway: physical, PhysicalInterface: "fake0", DefaultInterface: "utun3"}},
+			{State: NetworkState{PhysicalGateway: wifi, PhysicalInterface: "wlan0", DefaultInterface: "utun3"}},
+			{State: NetworkState{PhysicalGateway: wifi, PhysicalInterface: "wlan0", DefaultGateway: wifi, DefaultInterface: "wlan0"}},
+		},
+	}
+	_, _, sub := newTestMonitor(t, script)
+
+	if event := nextEvent(t, sub); event.EventType != VPNConnected || event.VPNInterface != "utun3" {
+		t.Errorf("Expected VPNConnected on utun3, got %+v", event)
+	}
+	if event := nextEvent(t, sub); event.EventType != PhysicalGatewayChanged || !event.PhysicalGateway.Equal(wifi) || !event.VPNConnected {
+		t.Errorf("Expected PhysicalGatewayChanged to %s with the VPN up, got %+v", wifi, event)
+	}
+	if event := nextEvent(t, sub); event.EventType != VPNDisconnected {
+		t.Errorf("Expected VPNDisconnected, got %+v", event)
+	}
+
+	<-done
+	select {
+	case event := <-sub.Events():
+		t.Errorf("Expected no further events, got %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMonitorExternalTrigger(t *testing.T) {
+	external := NewExternalSource()
+	_, rm, sub := newTestMonitor(t, external)
+
+	rm.SetDefaultRoute(net.ParseIP("10.8.0.1"), "tun0")
+	external.Trigger()
+
+	if event := nextEvent(t, sub); event.EventType != VPNConnected || event.VPNInterface != "tun0" {
+		t.Errorf("Expected VPNConnected on tun0 after the trigger, got %+v", event)
+	}
+}
+
+// failingSource reports a state, then fails once release is closed
+type failingSource struct {
+	state   NetworkState
+	release chan struct{}
+}
+
+func (s *failingSource) Name() string {
+	return "failing"
+}
+
+func (s *failingSource) Run(ctx context.Context, sink EventSink) error {
+	sink.Observe(s.Name(), s.state)
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-s.release:
+		return errors.New("route socket closed")
+	}
+}
+
+func TestMonitorPollFallbackKeepsState(t *testing.T) {
+	physical := net.ParseIP("192.0.2.1")
+	source := &failingSource{
+		state:   NetworkState{PhysicalGateway: physical, PhysicalInterface: "fake0", DefaultInterface: "utun3"},
+		release: make(chan struct{}),
+	}
+	_, _, sub := newTestMonitor(t, source)
+
+	if event := nextEvent(t, sub); event.EventType != VPNConnected {
+		t.Fatalf("Expected VPNConnected, got %+v", event)
+	}
+	close(source.release)
+
+	event := nextEvent(t, sub)
+	if event.EventType != PollFallbackEnabled {
+		t.Fatalf("Expected PollFallbackEnabled, got %+v", event)
+	}
+	if !event.VPNConnected || event.VPNInterface != "utun3" || !event.PhysicalGateway.Equal(physical) || event.PhysicalInterface != "fake0" {
+		t.Errorf("Expected the fallback event to carry the last known state, got %+v", event)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build darwin || freebsd
+
+package routing
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// lookupNeighbor reports whether the ARP table has a resolved entry for ip
+func lookupNeighbor(ctx context.Context, ip net.IP) (bool, error) {
+	mac, err := neighborMAC(ctx, ip)
+	return mac != nil, err
+}
+
+// neighborMAC returns the resolved hardware address of ip in the ARP table, or nil
+func neighborMAC(ctx context.Context, ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return nil, errNeighborUnsupported
+	}
+
+	// arp exits non-zero when there is no entry; the output says so as well
+	output, _ := exec.CommandContext(ctx, "arp", "-n", ip.String()).CombinedOutput()
+	return parseARPOutput(string(output)), nil
+}
+
+// parseARPOutput returns the hardware address of the resolved entry in `arp -n <ip>` output, or nil.
+// Leading zeros of each octet are omitted:
+//
+//	? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]
+//	? (192.168.1.1) at (incomplete) on en0 ifscope [ethernet]
+//	192.168.1.1 (192.168.1.1) -- no entry
+func parseARPOutput(output string) net.HardwareAddr {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if fields[i] != "at" {
+				continue
+			}
+			octets := strings.Split(fields[i+1], ":")
+			for j, octet := range octets {
+				if len(octet) == 1 {
+					octets[j] = "0" + octet
+				}
+			}
+			if mac, err := net.ParseMAC(strings.Join(octets, ":")); err == nil {
+				return mac
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build darwin || freebsd
+
+package routing
+
+import "testing"
+
+func TestParseARPOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"? (192.168.1.1) at 0:11:22:33:44:55 on en0 ifscope [ethernet]\n", "00:11:22:33:44:55"},
+		{"? (192.168.1.1) at (incomplete) on en0 ifscope [ethernet]\n", ""},
+		{"192.168.1.1 (192.168.1.1) -- no entry\n", ""},
+	}
+	for _, tt := range tests {
+		if got := parseARPOutput(tt.output); got.String() != tt.want {
+			t.Errorf("parseARPOutput(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+//go:build linux
+
+package routing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	procNetARP = "/proc/net/arp"
+	// linuxATFComplete is ATF_COM: the entry holds a resolved hardware address
+	linuxATFComplete = 0x2
+)
+
+// lookupNeighbor reports whether the kernel ARP table has a resolved entry for ip
+func lookupNeighbor(ctx context.Context, ip net.IP) (bool, error) {
+	mac, err := neighborMAC(ctx, ip)
+	return mac != nil, err
+}
+
+// neighborMAC returns the resolved hardware address of ip in the kernel ARP table, or nil
+func neighborMAC(_ context.Context, ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return nil, errNeighborUnsupported
+	}
+
+	f, err := os.Open(procNetARP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ARP table: %w", err)
+	}
+	defer f.Close()
+
+	return parseProcNetARP(f, ip)
+}
+
+// parseProcNetARP returns the hardware address of the complete entry for ip in the /proc/net/arp
+// table in r, or nil:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+func parseProcNetARP(r io.Reader, ip net.IP) (net.HardwareAddr, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if err != nil {
+			continue
+		}
+		if flags&linuxATFComplete == 0 || fields[3] == "00:00:00:00:00:00" {
+			continue
+		}
+		if mac, err := net.ParseMAC(fields[3]); err == nil {
+			return mac, nil
+		}
+	}
+	return nil, scanner.Err()
+}
@@ -0,0 +1,35 @@
+//go:build linux
+
+package routing
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseProcNetARP(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         52:54:00:12:34:56     *        eth0
+192.168.1.2      0x1         0x0         00:00:00:00:00:00     *        eth0
+192.168.1.3      0x1         0x6         52:54:00:12:34:57     *        eth0
+`
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.168.1.1", "52:54:00:12:34:56"},
+		{"192.168.1.2", ""},                  // incomplete or failed
+		{"192.168.1.3", "52:54:00:12:34:57"}, // permanent
+		{"192.168.1.4", ""},                  // no entry
+	}
+	for _, tt := range tests {
+		got, err := parseProcNetARP(strings.NewReader(table), net.ParseIP(tt.ip))
+		if err != nil {
+			t.Fatalf("parseProcNetARP failed: %v", err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("parseProcNetARP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package routing
+
+import (
+	"context"
+	"net"
+	"os/exec"
+	"strings"
+)
+
+// lookupNeighbor reports whether the ARP cache has a resolved entry for ip
+func lookupNeighbor(ctx context.Context, ip net.IP) (bool, error) {
+	mac, err := neighborMAC(ctx, ip)
+	return mac != nil, err
+}
+
+// neighborMAC returns the resolved hardware address of ip in the ARP cache, or nil
+func neighborMAC(ctx context.Context, ip net.IP) (net.HardwareAddr, error) {
+	if ip.To4() == nil {
+		return nil, errNeighborUnsupported
+	}
+
+	// arp exits non-zero with "No ARP Entries Found." when there is no entry
+	output, _ := exec.CommandContext(ctx, "arp", "-a", ip.String()).CombinedOutput()
+	return parseARPOutputWindows(string(output), ip), nil
+}
+
+// parseARPOutputWindows returns the hardware address of the resolved entry for ip in
+// `arp -a <ip>` output, or nil:
+//
+//	Interface: 192.168.1.100 --- 0x6
+//	  Internet Address      Physical Address      Type
+//	  192.168.1.1           00-11-22-33-44-55     dynamic
+func parseARPOutputWindows(output string, ip net.IP) net.HardwareAddr {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		if fields[1] == "00-00-00-00-00-00" || fields[2] == "invalid" {
+			continue
+		}
+		if mac, err := net.ParseMAC(fields[1]); err == nil {
+			return mac
+		}
+	}
+	return nil
+}
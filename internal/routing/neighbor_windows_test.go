@@ -0,0 +1,33 @@
+//go:build windows
+
+package routing
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseARPOutputWindows(t *testing.T) {
+	output := `
+Interface: 192.168.1.100 --- 0x6
+  Internet Address      Physical Address      Type
+  192.168.1.1           00-11-22-33-44-55     dynamic
+  192.168.1.2           00-00-00-00-00-00     invalid
+`
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.168.1.1", "00:11:22:33:44:55"},
+		{"192.168.1.2", ""},
+		{"192.168.1.3", ""},
+	}
+	for _, tt := range tests {
+		if got := parseARPOutputWindows(output, net.ParseIP(tt.ip)); got.String() != tt.want {
+			t.Errorf("parseARPOutputWindows(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+	if parseARPOutputWindows("No ARP Entries Found.\n", net.ParseIP("192.168.1.1")) != nil {
+		t.Error("Expected no entry to be unresolved")
+	}
+}
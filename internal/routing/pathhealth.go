@@ -0,0 +1,220 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// PathSample is the result of probing one target over one path
+type PathSample struct {
+	Latency time.Duration // Mean connect time of the successful probes
+	Loss    float64       // Fraction of failed probes, 1 if the target did not answer at all
+}
+
+// exceeds reports whether the sample is worse than the thresholds
+func (s PathSample) exceeds(maxLatency time.Duration, maxLoss float64) bool {
+	return s.Loss > maxLoss || (s.Loss < 1 && s.Latency > maxLatency)
+}
+
+// betterThan reports whether s is a clearly better path than other
+func (s PathSample) betterThan(other PathSample) bool {
+	if s.Loss != other.Loss {
+		return s.Loss < other.Loss
+	}
+	return s.Loss < 1 && s.Latency < other.Latency
+}
+
+// TargetHealth is the latest measurement and verdict for one probe target
+type TargetHealth struct {
+	Target   string
+	IP       net.IP
+	Direct   PathSample // Through the physical interface
+	VPN      PathSample // Through the VPN interface
+	Degraded bool       // The direct path is withdrawn for this target
+}
+
+// PathHealthReport is the outcome of one health check round
+type PathHealthReport struct {
+	Targets []TargetHealth
+	Changed bool // At least one target became degraded or recovered in this round
+}
+
+// Degraded returns the targets whose direct path is currently degraded
+func (r PathHealthReport) Degraded() []TargetHealth {
+	var degraded []TargetHealth
+	for _, target := range r.Targets {
+		if target.Degraded {
+			degraded = append(degraded, target)
+		}
+	}
+	return degraded
+}
+
+// AllDegraded reports whether the direct path to every target is degraded
+func (r PathHealthReport) AllDegraded() bool {
+	return len(r.Targets) > 0 && len(r.Degraded()) == len(r.Targets)
+}
+
+// targetState tracks the consecutive rounds that disagree with a target's current verdict
+type targetState struct {
+	ip       net.IP
+	degraded bool
+	streak   int
+}
+
+// PathHealthChecker measures latency and loss to probe targets through the physical interface
+// and through the VPN. A target is degraded once the direct path exceeds the thresholds while the
+// VPN does better for a number of consecutive rounds, and recovers after a number of good rounds.
+type PathHealthChecker struct {
+	samples       int
+	timeout       time.Duration
+	maxLatency    time.Duration
+	maxLoss       float64
+	degradeRounds int
+	recoverRounds int
+	measure       func(ctx context.Context, target, iface string) PathSample
+	logger        *logger.Logger
+	targets       []string
+	states        map[string]*targetState
+	uplink        types.Uplink // the physical network the verdicts belong to
+}
+
+// NewPathHealthChecker creates a checker for the configured targets, which must be "ip:port"
+func NewPathHealthChecker(cfg *config.Config, log *logger.Logger) (*PathHealthChecker, error) {
+	if len(cfg.PathHealthTargets) == 0 {
+		return nil, fmt.Errorf("no path health targets configured")
+	}
+
+	c := &PathHealthChecker{
+		samples:       max(cfg.PathHealthSamples, 1),
+		timeout:       cfg.PathHealthTimeout,
+		maxLatency:    cfg.PathHealthMaxLatency,
+		maxLoss:       cfg.PathHealthMaxLoss,
+		degradeRounds: max(cfg.PathHealthDegradeRounds, 1),
+		recoverRounds: max(cfg.PathHealthRecoverRounds, 1),
+		logger:        log,
+		states:        make(map[string]*targetState),
+	}
+	c.measure = c.measureTCP
+
+	for _, target := range cfg.PathHealthTargets {
+		host, _, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path health target %q: %w", target, err)
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid path health target %q: host must be an IP address", target)
+		}
+		c.targets = append(c.targets, target)
+		c.states[target] = &targetState{ip: ip}
+	}
+	return c, nil
+}
+
+// Check runs one round against every target. Verdicts are kept per physical uplink;
+// switching to another network starts over with every target healthy.
+func (c *PathHealthChecker) Check(ctx context.Context, uplink types.Uplink, vpnIface string) PathHealthReport {
+	var report PathHealthReport
+	if !uplink.Equal(c.uplink) {
+		report.Changed = c.reset()
+		c.uplink = uplink
+	}
+
+	for _, target := range c.targets {
+		state := c.states[target]
+		direct := c.measure(ctx, target, uplink.Interface)
+		vpn := c.measure(ctx, target, vpnIface)
+		if ctx.Err() != nil {
+			return report
+		}
+
+		if c.observe(state, direct, vpn) {
+			report.Changed = true
+			c.logger.Info("Direct path health changed",
+				"target", target,
+				"degraded", state.degraded,
+				"direct_latency", direct.Latency,
+				"direct_loss", direct.Loss,
+				"vpn_latency", vpn.Latency,
+				"vpn_loss", vpn.Loss)
+		}
+
+		report.Targets = append(report.Targets, TargetHealth{
+			Target:   target,
+			IP:       state.ip,
+			Direct:   direct,
+			VPN:      vpn,
+			Degraded: state.degraded,
+		})
+	}
+	return report
+}
+
+// observe feeds one round into a target's state and reports whether its verdict flipped
+func (c *PathHealthChecker) observe(state *targetState, direct, vpn PathSample) bool {
+	bad := direct.exceeds(c.maxLatency, c.maxLoss) && vpn.betterThan(direct)
+	if bad != state.degraded {
+		state.streak++
+	} else {
+		state.streak = 0
+	}
+
+	needed := c.degradeRounds
+	if state.degraded {
+		needed = c.recoverRounds
+	}
+	if state.streak < needed {
+		return false
+	}
+
+	state.degraded = !state.degraded
+	state.streak = 0
+	return true
+}
+
+// reset marks every target healthy and reports whether any was degraded
+func (c *PathHealthChecker) reset() bool {
+	changed := false
+	for _, state := range c.states {
+		changed = changed || state.degraded
+		state.degraded = false
+		state.streak = 0
+	}
+	return changed
+}
+
+// measureTCP connects to target through iface a number of times and returns the mean connect
+// time and the failure rate. A TCP connect needs no privileges and passes networks that drop ICMP.
+func (c *PathHealthChecker) measureTCP(ctx context.Context, target, iface string) PathSample {
+	if iface == "" {
+		return PathSample{Loss: 1}
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout, Control: bindToInterface(iface)}
+	var total time.Duration
+	failed := 0
+	for i := 0; i < c.samples; i++ {
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			c.logger.Debug("Path health probe failed", "target", target, "interface", iface, "error", err)
+			failed++
+			continue
+		}
+		total += time.Since(start)
+		conn.Close()
+	}
+
+	sample := PathSample{Loss: float64(failed) / float64(c.samples)}
+	if answered := c.samples - failed; answered > 0 {
+		sample.Latency = total / time.Duration(answered)
+	}
+	return sample
+}
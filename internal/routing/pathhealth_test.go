@@ -0,0 +1,109 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func TestPathHealthCheckerHysteresis(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.PathHealthTargets = []string{"223.5.5.5:53"}
+	cfg.PathHealthDegradeRounds = 2
+	cfg.PathHealthRecoverRounds = 2
+
+	checker, err := NewPathHealthChecker(cfg, logger.New("error"))
+	if err != nil {
+		t.Fatalf("NewPathHealthChecker failed: %v", err)
+	}
+
+	good := PathSample{Latency: 20 * time.Millisecond}
+	slow := PathSample{Latency: time.Second}
+	lossy := PathSample{Latency: 30 * time.Millisecond, Loss: 2.0 / 3}
+	vpn := PathSample{Latency: 150 * time.Millisecond}
+
+	var direct PathSample
+	checker.measure = func(_ context.Context, _, iface string) PathSample {
+		if iface == "utun0" {
+			return vpn
+		}
+		return direct
+	}
+
+	uplink := types.Uplink{Gateway: net.ParseIP("192.168.1.1"), Interface: "en0"}
+	rounds := []struct {
+		direct   PathSample
+		degraded bool
+		changed  bool
+	}{
+		{good, false, false},
+		{slow, false, false}, // one bad round is not enough
+		{good, false, false}, // streak broken
+		{slow, false, false},
+		{lossy, true, true},
+		{good, true, false}, // one good round is not enough
+		{good, false, true},
+	}
+	for i, round := range rounds {
+		direct = round.direct
+		report := checker.Check(context.Background(), uplink, "utun0")
+		if report.Changed != round.changed || report.Targets[0].Degraded != round.degraded {
+			t.Fatalf("Round %d: got changed=%v degraded=%v, want changed=%v degraded=%v",
+				i, report.Changed, report.Targets[0].Degraded, round.changed, round.degraded)
+		}
+	}
+
+	// A direct path that is slow but still better than the VPN is kept
+	vpn = PathSample{Loss: 1}
+	direct = slow
+	for i := 0; i < 3; i++ {
+		if report := checker.Check(context.Background(), uplink, "utun0"); report.Targets[0].Degraded {
+			t.Fatal("Expected the direct path to be kept when the VPN is worse")
+		}
+	}
+}
+
+func TestPathHealthCheckerResetsOnNetworkChange(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.PathHealthTargets = []string{"223.5.5.5:53", "119.29.29.29:53"}
+	cfg.PathHealthDegradeRounds = 1
+
+	checker, err := NewPathHealthChecker(cfg, logger.New("error"))
+	if err != nil {
+		t.Fatalf("NewPathHealthChecker failed: %v", err)
+	}
+	checker.measure = func(_ context.Context, _, iface string) PathSample {
+		if iface == "utun0" {
+			return PathSample{Latency: 100 * time.Millisecond}
+		}
+		return PathSample{Loss: 1}
+	}
+
+	hotel := types.Uplink{Gateway: net.ParseIP("10.0.0.1"), Interface: "en0"}
+	if report := checker.Check(context.Background(), hotel, "utun0"); !report.AllDegraded() {
+		t.Fatalf("Expected every target to be degraded, got %+v", report.Targets)
+	}
+
+	checker.measure = func(context.Context, string, string) PathSample {
+		return PathSample{Latency: 10 * time.Millisecond}
+	}
+	home := types.Uplink{Gateway: net.ParseIP("192.168.1.1"), Interface: "en0"}
+	report := checker.Check(context.Background(), home, "utun0")
+	if !report.Changed || len(report.Degraded()) != 0 {
+		t.Errorf("Expected the verdicts to reset on a new network, got changed=%v degraded=%d",
+			report.Changed, len(report.Degraded()))
+	}
+}
+
+func TestNewPathHealthCheckerRejectsHostnames(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.PathHealthTargets = []string{"www.baidu.com:443"}
+	if _, err := NewPathHealthChecker(cfg, logger.New("error")); err == nil {
+		t.Error("Expected an error for a target that is not an IP address")
+	}
+}
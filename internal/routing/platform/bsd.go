@@ -4,13 +4,15 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/wesleywu/smart-route/internal/config"
 	"github.com/wesleywu/smart-route/internal/logger"
 	"github.com/wesleywu/smart-route/internal/routing/batch"
 	"github.com/wesleywu/smart-route/internal/routing/types"
@@ -22,15 +24,44 @@ import (
 // BSDRouteManager is a route manager for BSD-based systems
 type BSDRouteManager struct {
 	socket           int
-	mutex            sync.Mutex
 	concurrencyLimit int
-	maxRetries       int
+	retryPolicy      batch.RetryPolicy
+	routeTimeout     time.Duration
+	uplinkPriority   []string
+	batchOptions     batch.Options
 	metrics          *metrics.Metrics
-	seqNum           int32 // Add sequence number counter
+	seqNum           atomic.Int32 // Route message sequence number, shared by concurrent workers
 }
 
-// NewPlatformRouteManager creates a platform-specific route manager (BSD implementation)
-func NewPlatformRouteManager(concurrencyLimit, maxRetries int) (types.RouteManager, error) {
+func init() {
+	Register(Backend{
+		Name:        "route-socket",
+		Description: "PF_ROUTE socket messages, no process per route",
+		Priority:    20,
+		New: func(cfg *config.Config) (types.RouteManager, error) {
+			rm, err := newBSDRouteManager(cfg)
+			if err != nil {
+				return nil, err
+			}
+			return rm, nil
+		},
+	})
+	Register(Backend{
+		Name:        "route-exec",
+		Description: "route(8), one process per route",
+		Priority:    10,
+		New: func(cfg *config.Config) (types.RouteManager, error) {
+			rm, err := newBSDRouteManager(cfg)
+			if err != nil {
+				return nil, err
+			}
+			return &BSDExecRouteManager{BSDRouteManager: rm}, nil
+		},
+	})
+}
+
+// newBSDRouteManager creates a route manager that talks to the kernel through a routing socket
+func newBSDRouteManager(cfg *config.Config) (*BSDRouteManager, error) {
 	sock, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create route socket: %w", err)
@@ -38,47 +69,78 @@ func NewPlatformRouteManager(concurrencyLimit, maxRetries int) (types.RouteManag
 
 	return &BSDRouteManager{
 		socket:           sock,
-		concurrencyLimit: concurrencyLimit,
-		maxRetries:       maxRetries,
+		concurrencyLimit: cfg.ConcurrencyLimit,
+		retryPolicy:      batch.NewRetryPolicy(cfg),
+		routeTimeout:     cfg.RouteTimeout,
+		uplinkPriority:   cfg.UplinkPriority,
+		batchOptions:     batchOptions(cfg),
 		metrics:          metrics.NewMetrics(),
-		seqNum:           1, // Initialize sequence number
 	}, nil
 }
 
 // AddRoute adds a route to the system
-func (rm *BSDRouteManager) AddRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.addRouteWithRetry(network, gateway, log)
+func (rm *BSDRouteManager) AddRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.addRouteWithRetry(ctx, network, gateway, options, log)
 }
 
 // DeleteRoute deletes a route from the system
-func (rm *BSDRouteManager) DeleteRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.deleteRouteWithRetry(network, gateway, log)
+func (rm *BSDRouteManager) DeleteRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.deleteRouteWithRetry(ctx, network, gateway, options, log)
 }
 
 // BatchAddRoutes adds multiple routes to the system
-func (rm *BSDRouteManager) BatchAddRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.AddRoute, rm.concurrencyLimit, log)
+func (rm *BSDRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "add", routes, rm.AddRoute, rm.batchOptions, log)
 }
 
 // BatchDeleteRoutes deletes multiple routes from the system
-func (rm *BSDRouteManager) BatchDeleteRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.DeleteRoute, rm.concurrencyLimit, log)
+func (rm *BSDRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "delete", routes, rm.DeleteRoute, rm.batchOptions, log)
 }
 
 // GetPhysicalGateway gets the physical gateway from the system (for route management)
-func (rm *BSDRouteManager) GetPhysicalGateway() (net.IP, string, error) {
+func (rm *BSDRouteManager) GetPhysicalGateway(ctx context.Context) (net.IP, string, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
 	// ALWAYS look for physical interface gateway, never rely on default route
 	// In VPN scenarios, default route will point to VPN, but we need the physical gateway
-	return utils.GetPhysicalGatewayBSD()
+	if len(rm.uplinkPriority) > 0 {
+		uplinks, err := physicalUplinks(ctx, rm.ListSystemRoutes, utils.GetPhysicalGatewayBSD, rm.uplinkPriority)
+		if err != nil {
+			return nil, "", err
+		}
+		return uplinks[0].Gateway, uplinks[0].Interface, nil
+	}
+	return utils.GetPhysicalGatewayBSD(ctx)
+}
+
+// ListPhysicalUplinks gets every active physical uplink, in the configured order of preference
+func (rm *BSDRouteManager) ListPhysicalUplinks(ctx context.Context) ([]types.Uplink, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	return physicalUplinks(ctx, rm.ListSystemRoutes, utils.GetPhysicalGatewayBSD, rm.uplinkPriority)
 }
 
 // GetSystemDefaultRoute gets the current default route (including VPN) from the system
-func (rm *BSDRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
+func (rm *BSDRouteManager) GetSystemDefaultRoute(ctx context.Context) (net.IP, string, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
 	// Use 'route get default' to get the actual current default route
-	cmd := exec.Command("route", "-n", "get", "default")
+	cmd := exec.CommandContext(ctx, "route", "-n", "get", "default")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get current default route: %w", err)
+		return nil, "", fmt.Errorf("failed to get current default route: %w", queryError(ctx, err))
 	}
 
 	outputStr := string(output)
@@ -107,7 +169,7 @@ func (rm *BSDRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
 	if iface == "" {
 		// During network transitions, route output might be incomplete
 		// Try to fall back to physical gateway information
-		physGW, physIface, physErr := utils.GetPhysicalGatewayBSD()
+		physGW, physIface, physErr := utils.GetPhysicalGatewayBSD(ctx)
 		if physErr == nil && physIface != "" {
 			// Use physical interface as fallback, but keep the current gateway if found
 			if gateway == nil {
@@ -131,14 +193,22 @@ func (rm *BSDRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
 }
 
 // ListSystemRoutes gets all routes from the system
-func (rm *BSDRouteManager) ListSystemRoutes() ([]*types.Route, error) {
-	cmd := exec.Command("netstat", "-rn")
+func (rm *BSDRouteManager) ListSystemRoutes(ctx context.Context) ([]*types.Route, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "netstat", "-rn")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list routes: %w", err)
+		return nil, fmt.Errorf("failed to list routes: %w", queryError(ctx, err))
 	}
 
-	return parseNetstatOutputBSD(string(output))
+	return parseNetstatOutputBSD(string(output), pointToPointInterfaces())
+}
+
+// Metrics returns the route operation metrics, including retries
+func (rm *BSDRouteManager) Metrics() *metrics.Metrics {
+	return rm.metrics
 }
 
 // Close closes the route manager
@@ -147,58 +217,22 @@ func (rm *BSDRouteManager) Close() error {
 }
 
 // addRouteWithRetry adds a route to the system with retry logic
-func (rm *BSDRouteManager) addRouteWithRetry(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	var lastErr error
-	start := time.Now()
-
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.addRouteNative(network, gateway, log)
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
-		}
-
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
-		}
-
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
-	}
-
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+func (rm *BSDRouteManager) addRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "add", network, gateway, func(ctx context.Context) error {
+		return rm.addRouteNative(ctx, network, gateway, options, log)
+	}, log, rm.metrics)
 }
 
 // deleteRouteWithRetry deletes a route from the system with retry logic
-func (rm *BSDRouteManager) deleteRouteWithRetry(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	var lastErr error
-	start := time.Now()
-
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.deleteRouteNative(network, gateway, log)
-
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
-		}
-
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
-		}
-
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
-	}
-
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+func (rm *BSDRouteManager) deleteRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "delete", network, gateway, func(ctx context.Context) error {
+		return rm.deleteRouteNative(ctx, network, gateway, options, log)
+	}, log, rm.metrics)
 }
 
-// parseNetstatOutputBSD parses the output of netstat -rn for BSD systems
-func parseNetstatOutputBSD(output string) ([]*types.Route, error) {
+// parseNetstatOutputBSD parses the output of netstat -rn for BSD systems. Routes without a
+// gateway IP are only kept on the pointToPoint interfaces, where they are interface routes.
+func parseNetstatOutputBSD(output string, pointToPoint map[string]bool) ([]*types.Route, error) {
 	var routes []*types.Route
 	lines := strings.Split(output, "\n")
 
@@ -257,9 +291,9 @@ func parseNetstatOutputBSD(output string) ([]*types.Route, error) {
 			continue // Skip unparseable destinations
 		}
 
-		// Parse gateway IP
+		// Parse gateway IP; an interface route shows link#<index> or the interface name instead
 		gwIP := net.ParseIP(gateway)
-		if gwIP == nil {
+		if gwIP == nil && (len(fields) < 4 || !pointToPoint[fields[3]]) {
 			continue // Skip unparseable gateways (like link# formats)
 		}
 
@@ -267,9 +301,56 @@ func parseNetstatOutputBSD(output string) ([]*types.Route, error) {
 			Destination: *network,
 			Gateway:     gwIP,
 		}
+		if len(fields) >= 4 {
+			route.Flags = parseNetstatFlags(fields[2])
+			route.Protocol = netstatRouteProtocol(route.Flags)
+			route.Interface = fields[3]
+		}
 
 		routes = append(routes, route)
 	}
 
 	return routes, nil
 }
+
+// netstatFlags maps the flag letters printed by netstat -rn to route flags
+var netstatFlags = map[rune]uint32{
+	'U': RTF_UP,
+	'G': RTF_GATEWAY,
+	'H': RTF_HOST,
+	'R': RTF_REJECT,
+	'D': RTF_DYNAMIC,
+	'M': RTF_MODIFIED,
+	'C': RTF_CLONING,
+	'c': RTF_PRCLONING,
+	'L': RTF_LLINFO,
+	'S': RTF_STATIC,
+	'B': RTF_BLACKHOLE,
+	'W': RTF_WASCLONED,
+	'I': RTF_IFSCOPE,
+	'b': RTF_BROADCAST,
+	'm': RTF_MULTICAST,
+	'l': RTF_LOCAL,
+	'r': RTF_ROUTER,
+}
+
+// parseNetstatFlags converts netstat flag letters such as "UGSc" to route flags, ignoring unknown letters
+func parseNetstatFlags(letters string) uint32 {
+	var flags uint32
+	for _, letter := range letters {
+		flags |= netstatFlags[letter]
+	}
+	return flags
+}
+
+// netstatRouteProtocol derives the route protocol from its flags
+func netstatRouteProtocol(flags uint32) string {
+	switch {
+	case flags&RTF_STATIC != 0:
+		return types.RouteProtocolStatic
+	case flags&RTF_DYNAMIC != 0:
+		return types.RouteProtocolRedirect
+	default:
+		return types.RouteProtocolKernel
+	}
+}
@@ -0,0 +1,113 @@
+//go:build darwin || freebsd
+
+package platform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// BSDExecRouteManager programs routes by running route(8) once per route instead of writing
+// to the routing socket. Queries are inherited from BSDRouteManager.
+type BSDExecRouteManager struct {
+	*BSDRouteManager
+}
+
+// AddRoute adds a route to the system
+func (rm *BSDExecRouteManager) AddRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.retryPolicy.Do(ctx, "add", network, gateway, func(ctx context.Context) error {
+		return rm.routeCommand(ctx, "add", network, gateway, options)
+	}, log, rm.metrics)
+}
+
+// DeleteRoute deletes a route from the system
+func (rm *BSDExecRouteManager) DeleteRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.retryPolicy.Do(ctx, "delete", network, gateway, func(ctx context.Context) error {
+		return rm.routeCommand(ctx, "delete", network, gateway, options)
+	}, log, rm.metrics)
+}
+
+// BatchAddRoutes adds multiple routes to the system
+func (rm *BSDExecRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "add", routes, rm.AddRoute, rm.batchOptions, log)
+}
+
+// BatchDeleteRoutes deletes multiple routes from the system
+func (rm *BSDExecRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "delete", routes, rm.DeleteRoute, rm.batchOptions, log)
+}
+
+// routeCommand runs `route -n <action> -net <network> <gateway> [-ifp <interface>] [-hopcount <metric>]`,
+// or `route -n <action> -net <network> -interface <interface>` for a route without a gateway
+func (rm *BSDExecRouteManager) routeCommand(ctx context.Context, action string, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	args := []string{"-n", action, "-net", network.String()}
+	switch {
+	case gateway == nil:
+		args = append(args, "-interface", options.Interface)
+	case options.Interface != "":
+		args = append(args, gateway.String(), "-ifp", options.Interface)
+	default:
+		args = append(args, gateway.String())
+	}
+	if options.Metric != 0 {
+		args = append(args, "-hopcount", strconv.Itoa(options.Metric))
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "route", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
+		}
+		return &types.RouteOperationError{
+			ErrorType:   classifyRouteCommandError(stderr.String()),
+			Destination: *network,
+			Gateway:     gateway,
+			Cause:       fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String())),
+		}
+	}
+	return nil
+}
+
+// classifyRouteCommandError determines the error type from the output of a failed route(8) command
+func classifyRouteCommandError(stderr string) types.RouteErrorType {
+	message := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(message, "file exists"):
+		return types.RouteErrExists
+	case strings.Contains(message, "not in table"):
+		return types.RouteErrNotFound
+	case strings.Contains(message, "must be root"), strings.Contains(message, "permission denied"),
+		strings.Contains(message, "operation not permitted"):
+		return types.RouteErrPermission
+	case strings.Contains(message, "network is unreachable"):
+		return types.RouteErrGatewayUnreachable
+	case strings.Contains(message, "no buffer space available"):
+		return types.RouteErrNetwork
+	case strings.Contains(message, "bad address"), strings.Contains(message, "invalid argument"):
+		return types.RouteErrInvalidRoute
+	default:
+		return types.RouteErrSystemCall
+	}
+}
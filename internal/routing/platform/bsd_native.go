@@ -3,6 +3,7 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"syscall"
@@ -115,26 +116,77 @@ type sockaddrInet struct {
 	zero   [8]int8
 }
 
-func (rm *BSDRouteManager) addRouteNative(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.sendRouteMessage(RTM_ADD, network, gateway, log)
+// Link-level socket address, used to name the outgoing interface (RTA_IFP) by index
+type sockaddrDatalink struct {
+	len    uint8
+	family uint8
+	index  uint16
+	typ    uint8
+	nlen   uint8
+	alen   uint8
+	slen   uint8
+	data   [12]int8
 }
 
-func (rm *BSDRouteManager) deleteRouteNative(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.sendRouteMessage(RTM_DELETE, network, gateway, log)
+func (rm *BSDRouteManager) addRouteNative(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.sendRouteMessage(ctx, RTM_ADD, network, gateway, options, log)
 }
 
-func (rm *BSDRouteManager) sendRouteMessage(msgType uint8, network *net.IPNet, gateway net.IP, log *logger.Logger) error {
+func (rm *BSDRouteManager) deleteRouteNative(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.sendRouteMessage(ctx, RTM_DELETE, network, gateway, options, log)
+}
+
+func (rm *BSDRouteManager) sendRouteMessage(ctx context.Context, msgType uint8, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	// The route socket write itself cannot be interrupted, so honour the context before sending
+	if err := ctx.Err(); err != nil {
+		return types.NewContextError(err, network, gateway)
+	}
+
 	// For deletion, ensure we use the canonical network address (network IP masked with netmask)
 	networkAddr := network.IP.Mask(network.Mask)
 
-	// Convert network and gateway to sockaddr structures
+	// Convert network and netmask to sockaddr structures
 	dst := ipToSockaddr(networkAddr)
-	gw := ipToSockaddr(gateway)
 	mask := maskToSockaddr(network.Mask)
 
+	// The outgoing interface is passed by index as a link-level address
+	var ifp *sockaddrDatalink
+	if options.Interface != "" {
+		iface, err := net.InterfaceByName(options.Interface)
+		if err != nil {
+			return &types.RouteOperationError{
+				ErrorType:   types.RouteErrInvalidRoute,
+				Destination: *network,
+				Gateway:     gateway,
+				Cause:       fmt.Errorf("unknown interface %s: %w", options.Interface, err),
+			}
+		}
+		ifp = &sockaddrDatalink{
+			len:    uint8(unsafe.Sizeof(sockaddrDatalink{})),
+			family: unix.AF_LINK,
+			index:  uint16(iface.Index),
+		}
+	}
+
+	// A route without a gateway is an interface route: like route -interface, the
+	// gateway is the link-level address of the interface and RTF_GATEWAY is not set
+	flags := int32(RTF_UP | RTF_GATEWAY | RTF_STATIC)
+	var gw []byte
+	if gateway != nil {
+		sa := ipToSockaddr(gateway)
+		gw = (*[16]byte)(unsafe.Pointer(sa))[:sa.len]
+	} else {
+		gw = (*[unsafe.Sizeof(sockaddrDatalink{})]byte)(unsafe.Pointer(ifp))[:ifp.len]
+		ifp = nil
+		flags = RTF_UP | RTF_STATIC
+	}
+
 	// Calculate message size
 	msgSize := int(unsafe.Sizeof(rtMsghdr{})) +
-		int(dst.len) + int(gw.len) + int(mask.len)
+		int(dst.len) + roundUp(len(gw)) + int(mask.len)
+	if ifp != nil {
+		msgSize += roundUp(int(ifp.len))
+	}
 
 	// Align to 4-byte boundary
 	msgSize = (msgSize + 3) &^ 3
@@ -152,20 +204,27 @@ func (rm *BSDRouteManager) sendRouteMessage(msgType uint8, network *net.IPNet, g
 
 	// Set appropriate flags based on operation type
 	if msgType == RTM_ADD {
-		hdr.flags = RTF_UP | RTF_GATEWAY | RTF_STATIC
+		hdr.flags = flags
 	} else if msgType == RTM_DELETE {
 		// For deletion, match the existing route flags exactly
 		// Routes created with RTF_UP + RTF_GATEWAY + RTF_STATIC often get RTF_CLONING added by system
-		hdr.flags = RTF_UP | RTF_GATEWAY | RTF_STATIC
+		hdr.flags = flags
 	}
 
 	hdr.addrs = RTA_DST | RTA_GATEWAY | RTA_NETMASK
+	if ifp != nil {
+		hdr.addrs |= RTA_IFP
+	}
+
+	// BSD has no route metric; the hop count is the closest equivalent, as in route -hopcount
+	if options.Metric != 0 {
+		hdr.inits = unix.RTV_HOPCOUNT
+		hdr.rmx.hopcount = uint32(options.Metric)
+	}
 	hdr.pid = int32(syscall.Getpid())
 	
-	// Use atomic increment for sequence number to avoid conflicts
-	// This must be done within the mutex-protected section
-	rm.seqNum++
-	hdr.seq = rm.seqNum
+	// Sequence numbers only need to be unique, so an atomic counter lets workers send concurrently
+	hdr.seq = rm.seqNum.Add(1)
 
 	// Add socket addresses
 	offset := int(unsafe.Sizeof(rtMsghdr{}))
@@ -175,25 +234,35 @@ func (rm *BSDRouteManager) sendRouteMessage(msgType uint8, network *net.IPNet, g
 	offset += roundUp(int(dst.len))
 
 	// Gateway
-	copy(buf[offset:], (*[16]byte)(unsafe.Pointer(gw))[:gw.len])
-	offset += roundUp(int(gw.len))
+	copy(buf[offset:], gw)
+	offset += roundUp(len(gw))
 
 	// Netmask
 	copy(buf[offset:], (*[16]byte)(unsafe.Pointer(mask))[:mask.len])
+	offset += roundUp(int(mask.len))
 
-	// Send message
-	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
+	// Interface, which follows the netmask in RTA_* order
+	if ifp != nil {
+		copy(buf[offset:], (*[unsafe.Sizeof(sockaddrDatalink{})]byte)(unsafe.Pointer(ifp))[:ifp.len])
+	}
 
+	// Send message; each write carries a complete message, so concurrent writers don't interleave
 	_, err := unix.Write(rm.socket, buf)
 	if err != nil {
 		operation := "add"
 		if msgType == RTM_DELETE {
 			operation = "delete"
 		}
-		log.Error("Failed to send route message", "error", err, "operation", operation, "network", network.String(), "gateway", gateway.String())
+
+		// The route socket runs out of buffer space under load; the batch backs off and retries
+		errorType := types.RouteErrSystemCall
+		if types.IsThrottled(err) {
+			errorType = types.RouteErrNetwork
+		} else {
+			log.Error("Failed to send route message", "error", err, "operation", operation, "network", network.String(), "gateway", gateway.String())
+		}
 		return &types.RouteOperationError{
-			ErrorType:   types.RouteErrSystemCall,
+			ErrorType:   errorType,
 			Destination: *network,
 			Gateway:     gateway,
 			Cause:       fmt.Errorf("failed to send route message: %w", err),
@@ -4,6 +4,8 @@ package platform
 
 import (
 	"testing"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
 )
 
 // Test complete netstat line parsing with simplified formats
@@ -24,7 +26,7 @@ default            192.168.32.1       UGScIg                en0
 192.168.1.100      192.168.32.1       UGHS                  en0       
 `
 
-	routes, err := parseNetstatOutputBSD(netstatOutput)
+	routes, err := parseNetstatOutputBSD(netstatOutput, nil)
 	if err != nil {
 		t.Fatalf("Failed to parse netstat output: %v", err)
 	}
@@ -68,3 +70,41 @@ default            192.168.32.1       UGScIg                en0
 		t.Logf("  %s -> %s", route.Destination.String(), route.Gateway.String())
 	}
 }
+
+// Test that flags, protocol and interface are kept
+func TestParseNetstatOutput_RouteAttributes(t *testing.T) {
+	netstatOutput := `Internet:
+Destination        Gateway            Flags               Netif Expire
+default            192.168.32.1       UGScg                 en0
+1.0.1/24           10.8.0.1           UGSc                utun3
+8.8.8.8            192.168.32.1       UGHD                  en0
+192.168.32         link#6             UCS                   en0
+1.0.2/24           ppp0               USc                  ppp0
+`
+
+	routes, err := parseNetstatOutputBSD(netstatOutput, map[string]bool{"ppp0": true})
+	if err != nil {
+		t.Fatalf("Failed to parse netstat output: %v", err)
+	}
+	if len(routes) != 4 {
+		t.Fatalf("Expected 3 gateway routes and 1 interface route, got %d", len(routes))
+	}
+
+	tests := []struct {
+		iface    string
+		flags    uint32
+		protocol string
+	}{
+		{"en0", RTF_UP | RTF_GATEWAY | RTF_STATIC | RTF_PRCLONING, types.RouteProtocolStatic},
+		{"utun3", RTF_UP | RTF_GATEWAY | RTF_STATIC | RTF_PRCLONING, types.RouteProtocolStatic},
+		{"en0", RTF_UP | RTF_GATEWAY | RTF_HOST | RTF_DYNAMIC, types.RouteProtocolRedirect},
+		{"ppp0", RTF_UP | RTF_STATIC | RTF_PRCLONING, types.RouteProtocolStatic},
+	}
+	for i, tt := range tests {
+		route := routes[i]
+		if route.Interface != tt.iface || route.Flags != tt.flags || route.Protocol != tt.protocol {
+			t.Errorf("Route %s: expected %s flags=%#x %s, got %s flags=%#x %s", route.Destination.String(),
+				tt.iface, tt.flags, tt.protocol, route.Interface, route.Flags, route.Protocol)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+)
+
+// batchOptions derives the batch processing options from the configuration
+func batchOptions(cfg *config.Config) batch.Options {
+	return batch.Options{
+		ConcurrencyLimit: cfg.ConcurrencyLimit,
+		MinConcurrency:   cfg.MinConcurrency,
+		Adaptive:         cfg.AdaptiveConcurrency,
+		BatchSize:        cfg.BatchSize,
+		BatchTimeout:     cfg.BatchTimeout,
+	}
+}
+
+// withRouteTimeout bounds a single operation by the configured route timeout.
+// A zero timeout leaves the parent deadline (if any) in charge.
+func withRouteTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// queryError annotates a failed query command with the context error that killed it, if any
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w (%v)", ctxErr, err)
+	}
+	return err
+}
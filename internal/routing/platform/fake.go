@@ -0,0 +1,223 @@
+package platform
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// Default addresses of the fake backend, from the documentation range (RFC 5737)
+var (
+	fakeGateway   = net.IPv4(192, 0, 2, 1)
+	fakeInterface = "fake0"
+)
+
+func init() {
+	Register(Backend{
+		Name:         "fake",
+		Description:  "in-memory routing table for tests and dry runs",
+		Capabilities: types.Capabilities{Replace: true, IPv6: true},
+		New: func(cfg *config.Config) (types.RouteManager, error) {
+			return NewFakeRouteManager(cfg), nil
+		},
+	})
+}
+
+// FakeRouteManager keeps routes in memory instead of the system routing table.
+// It holds at most one route per destination, like the kernel main table.
+type FakeRouteManager struct {
+	mutex             sync.RWMutex
+	routes            map[string]*types.Route
+	physicalGateway   net.IP
+	physicalInterface string
+	uplinks           []types.Uplink // every physical uplink, nil if only the physical gateway is up
+	defaultGateway    net.IP
+	defaultInterface  string
+	batchOptions      batch.Options
+}
+
+// NewFakeRouteManager creates an empty in-memory route manager whose physical and default gateway is 192.0.2.1
+func NewFakeRouteManager(cfg *config.Config) *FakeRouteManager {
+	return &FakeRouteManager{
+		routes:            make(map[string]*types.Route),
+		physicalGateway:   fakeGateway,
+		physicalInterface: fakeInterface,
+		defaultGateway:    fakeGateway,
+		defaultInterface:  fakeInterface,
+		batchOptions:      batchOptions(cfg),
+	}
+}
+
+// SetPhysicalGateway changes the gateway reported by GetPhysicalGateway
+func (rm *FakeRouteManager) SetPhysicalGateway(gateway net.IP, iface string) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.physicalGateway, rm.physicalInterface = gateway, iface
+	rm.uplinks = nil
+}
+
+// SetPhysicalUplinks changes the uplinks reported by ListPhysicalUplinks, most preferred first;
+// GetPhysicalGateway reports the first of them
+func (rm *FakeRouteManager) SetPhysicalUplinks(uplinks ...types.Uplink) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.uplinks = append([]types.Uplink(nil), uplinks...)
+	rm.physicalGateway, rm.physicalInterface = nil, ""
+	if len(uplinks) > 0 {
+		rm.physicalGateway, rm.physicalInterface = uplinks[0].Gateway, uplinks[0].Interface
+	}
+}
+
+// SetDefaultRoute changes the default route reported by GetSystemDefaultRoute, e.g. to simulate a VPN
+func (rm *FakeRouteManager) SetDefaultRoute(gateway net.IP, iface string) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.defaultGateway, rm.defaultInterface = gateway, iface
+}
+
+// AddRoute adds a route, failing with RouteErrExists if another route for the destination exists
+func (rm *FakeRouteManager) AddRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	return rm.setRoute(ctx, network, gateway, types.NewRouteOptions(opts...), false)
+}
+
+// ReplaceRoute adds a route, replacing any existing route for the destination
+func (rm *FakeRouteManager) ReplaceRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	return rm.setRoute(ctx, network, gateway, types.NewRouteOptions(opts...), true)
+}
+
+// DeleteRoute deletes a route, failing with RouteErrNotFound if it does not exist.
+// A gateway-less route is matched by its interface.
+func (rm *FakeRouteManager) DeleteRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	if err := ctx.Err(); err != nil {
+		return types.NewContextError(err, network, gateway)
+	}
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	key := network.String()
+	nextHop := types.Uplink{Gateway: gateway, Interface: options.Interface}
+	if route, ok := rm.routes[key]; !ok || !nextHop.Carries(route) {
+		return &types.RouteOperationError{ErrorType: types.RouteErrNotFound, Destination: *network, Gateway: gateway}
+	}
+	delete(rm.routes, key)
+	return nil
+}
+
+// BatchAddRoutes adds multiple routes
+func (rm *FakeRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "add", routes, rm.AddRoute, rm.batchOptions, log)
+}
+
+// BatchReplaceRoutes adds multiple routes, replacing existing routes for the same destinations
+func (rm *FakeRouteManager) BatchReplaceRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "replace", routes, rm.ReplaceRoute, rm.batchOptions, log)
+}
+
+// BatchDeleteRoutes deletes multiple routes
+func (rm *FakeRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "delete", routes, rm.DeleteRoute, rm.batchOptions, log)
+}
+
+// GetPhysicalGateway returns the configured physical gateway
+func (rm *FakeRouteManager) GetPhysicalGateway(ctx context.Context) (net.IP, string, error) {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.physicalGateway, rm.physicalInterface, nil
+}
+
+// ListPhysicalUplinks returns the configured uplinks, or the physical gateway if none are configured
+func (rm *FakeRouteManager) ListPhysicalUplinks(ctx context.Context) ([]types.Uplink, error) {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	if rm.uplinks == nil {
+		return []types.Uplink{{Gateway: rm.physicalGateway, Interface: rm.physicalInterface}}, nil
+	}
+	return append([]types.Uplink(nil), rm.uplinks...), nil
+}
+
+// GetSystemDefaultRoute returns the configured default route
+func (rm *FakeRouteManager) GetSystemDefaultRoute(ctx context.Context) (net.IP, string, error) {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.defaultGateway, rm.defaultInterface, nil
+}
+
+// ListSystemRoutes returns the routes in the in-memory table, ordered by destination
+func (rm *FakeRouteManager) ListSystemRoutes(ctx context.Context) ([]*types.Route, error) {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	routes := make([]*types.Route, 0, len(rm.routes))
+	for _, route := range rm.routes {
+		copied := *route
+		routes = append(routes, &copied)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Destination.String() < routes[j].Destination.String()
+	})
+	return routes, nil
+}
+
+// Close does nothing for the in-memory table
+func (rm *FakeRouteManager) Close() error {
+	return nil
+}
+
+// setRoute installs a route, optionally replacing a route to the same destination via another next hop.
+// Gateway routes without an interface leave through the uplink of their gateway, or else the physical interface.
+func (rm *FakeRouteManager) setRoute(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, replace bool) error {
+	if err := ctx.Err(); err != nil {
+		return types.NewContextError(err, network, gateway)
+	}
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	key := network.String()
+	nextHop := types.Uplink{Gateway: gateway, Interface: options.Interface}
+	if existing, ok := rm.routes[key]; ok && !replace && !nextHop.Carries(existing) {
+		return &types.RouteOperationError{ErrorType: types.RouteErrExists, Destination: *network, Gateway: gateway}
+	}
+	if options.Interface == "" {
+		options.Interface = rm.gatewayInterface(gateway)
+	}
+	rm.routes[key] = &types.Route{
+		Destination: *network,
+		Gateway:     gateway,
+		Metric:      options.Metric,
+		Interface:   options.Interface,
+		Protocol:    types.RouteProtocolStatic,
+	}
+	return nil
+}
+
+// gatewayInterface returns the interface of the uplink with gateway, or the physical interface;
+// the caller holds the mutex
+func (rm *FakeRouteManager) gatewayInterface(gateway net.IP) string {
+	for _, uplink := range rm.uplinks {
+		if uplink.HasGateway() && uplink.Gateway.Equal(gateway) {
+			return uplink.Interface
+		}
+	}
+	return rm.physicalInterface
+}
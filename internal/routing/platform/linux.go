@@ -3,13 +3,16 @@
 package platform
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/wesleywu/smart-route/internal/config"
 	"github.com/wesleywu/smart-route/internal/logger"
 	"github.com/wesleywu/smart-route/internal/routing/batch"
 	"github.com/wesleywu/smart-route/internal/routing/types"
@@ -18,163 +21,268 @@ import (
 )
 
 type LinuxRouteManager struct {
-	mutex            sync.Mutex
 	concurrencyLimit int
-	maxRetries       int
+	retryPolicy      batch.RetryPolicy
+	routeTimeout     time.Duration
+	uplinkPriority   []string
+	batchOptions     batch.Options
 	metrics          *metrics.Metrics
 }
 
-// NewPlatformRouteManager creates a platform-specific route manager (Linux implementation)
-func NewPlatformRouteManager(concurrencyLimit, maxRetries int) (types.RouteManager, error) {
+func init() {
+	Register(Backend{
+		Name:         "ip-batch",
+		Description:  "iproute2, one ip -batch process per chunk of routes",
+		Capabilities: types.Capabilities{Replace: true, IPv6: true},
+		Priority:     20,
+		Available:    ipBatchSupported,
+		New: func(cfg *config.Config) (types.RouteManager, error) {
+			return &LinuxBatchRouteManager{LinuxRouteManager: newLinuxRouteManager(cfg)}, nil
+		},
+	})
+	Register(Backend{
+		Name:         "ip",
+		Description:  "iproute2, one ip process per route",
+		Capabilities: types.Capabilities{IPv6: true},
+		Priority:     10,
+		Available:    ipSupported,
+		New: func(cfg *config.Config) (types.RouteManager, error) {
+			return newLinuxRouteManager(cfg), nil
+		},
+	})
+}
+
+// newLinuxRouteManager creates a route manager that runs one ip command per route
+func newLinuxRouteManager(cfg *config.Config) *LinuxRouteManager {
 	return &LinuxRouteManager{
-		concurrencyLimit: concurrencyLimit,
-		maxRetries:       maxRetries,
+		concurrencyLimit: cfg.ConcurrencyLimit,
+		retryPolicy:      batch.NewRetryPolicy(cfg),
+		routeTimeout:     cfg.RouteTimeout,
+		uplinkPriority:   cfg.UplinkPriority,
+		batchOptions:     batchOptions(cfg),
 		metrics:          metrics.NewMetrics(),
-	}, nil
+	}
 }
 
-func (rm *LinuxRouteManager) AddRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.addRouteWithRetry(network, gateway)
+// ipSupported reports whether the ip command is installed
+func ipSupported() bool {
+	_, err := exec.LookPath("ip")
+	return err == nil
 }
 
-func (rm *LinuxRouteManager) DeleteRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.deleteRouteWithRetry(network, gateway)
+func (rm *LinuxRouteManager) AddRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.addRouteWithRetry(ctx, network, gateway, options, log)
 }
 
-func (rm *LinuxRouteManager) BatchAddRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.AddRoute, rm.concurrencyLimit, log)
+// ReplaceRoute adds a route, replacing any existing route for the destination
+func (rm *LinuxRouteManager) ReplaceRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.retryPolicy.Do(ctx, "replace", network, gateway, func(ctx context.Context) error {
+		return rm.replaceRouteDirect(ctx, network, gateway, options)
+	}, log, rm.metrics)
 }
 
-func (rm *LinuxRouteManager) BatchDeleteRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.DeleteRoute, rm.concurrencyLimit, log)
+func (rm *LinuxRouteManager) DeleteRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.deleteRouteWithRetry(ctx, network, gateway, options, log)
+}
+
+func (rm *LinuxRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "add", routes, rm.AddRoute, rm.batchOptions, log)
+}
+
+func (rm *LinuxRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "delete", routes, rm.DeleteRoute, rm.batchOptions, log)
 }
 
 // GetPhysicalGateway gets the underlying physical network gateway (for route management)
-func (rm *LinuxRouteManager) GetPhysicalGateway() (net.IP, string, error) {
+func (rm *LinuxRouteManager) GetPhysicalGateway(ctx context.Context) (net.IP, string, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
 	// ALWAYS look for physical interface gateway, never rely on default route
 	// In VPN scenarios, default route will point to VPN, but we need the physical gateway
 	// TODO: Implement Linux specific physical gateway detection
-	return utils.GetPhysicalGatewayBSD()
+	if len(rm.uplinkPriority) > 0 {
+		uplinks, err := physicalUplinks(ctx, rm.ListSystemRoutes, nil, rm.uplinkPriority)
+		if err != nil {
+			return nil, "", err
+		}
+		return uplinks[0].Gateway, uplinks[0].Interface, nil
+	}
+	return utils.GetPhysicalGatewayBSD(ctx)
+}
+
+// ListPhysicalUplinks gets every active physical uplink, in the configured order of preference
+func (rm *LinuxRouteManager) ListPhysicalUplinks(ctx context.Context) ([]types.Uplink, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	return physicalUplinks(ctx, rm.ListSystemRoutes, nil, rm.uplinkPriority)
 }
 
 // GetSystemDefaultRoute gets the current default route (including VPN) from the system
-func (rm *LinuxRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
-	cmd := exec.Command("ip", "route", "show", "default")
+func (rm *LinuxRouteManager) GetSystemDefaultRoute(ctx context.Context) (net.IP, string, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "ip", "route", "show", "default")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get default route: %w", err)
+		return nil, "", fmt.Errorf("failed to get default route: %w", queryError(ctx, err))
 	}
 
 	return rm.parseDefaultRouteLinux(string(output))
 }
 
-// ListSystemRoutes gets all routes from the system routing table
-func (rm *LinuxRouteManager) ListSystemRoutes() ([]*types.Route, error) {
-	cmd := exec.Command("netstat", "-rn")
-	output, err := cmd.Output()
+// ListSystemRoutes gets all gateway routes, and interface routes on point-to-point links, from the system routing table
+func (rm *LinuxRouteManager) ListSystemRoutes(ctx context.Context) ([]*types.Route, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	routes, err := readProcRoutes()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list routes: %w", err)
 	}
 
-	return parseNetstatOutputLinux(string(output))
+	// Managed routes go via a gateway, or out of a gateway-less point-to-point uplink;
+	// other routes without a gateway are connected networks
+	pointToPoint := pointToPointInterfaces()
+	managedRoutes := make([]*types.Route, 0, len(routes))
+	for _, route := range routes {
+		if route.Gateway != nil || pointToPoint[route.Interface] {
+			managedRoutes = append(managedRoutes, route)
+		}
+	}
+	return managedRoutes, nil
+}
+
+// Metrics returns the route operation metrics, including retries
+func (rm *LinuxRouteManager) Metrics() *metrics.Metrics {
+	return rm.metrics
 }
 
 func (rm *LinuxRouteManager) Close() error {
 	return nil
 }
 
-func (rm *LinuxRouteManager) addRouteWithRetry(network *net.IPNet, gateway net.IP) error {
-	var lastErr error
-	start := time.Now()
-
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.addRouteDirect(network, gateway)
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
-		}
-
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
-		}
-
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
-	}
+func (rm *LinuxRouteManager) addRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "add", network, gateway, func(ctx context.Context) error {
+		return rm.addRouteDirect(ctx, network, gateway, options)
+	}, log, rm.metrics)
+}
 
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+func (rm *LinuxRouteManager) deleteRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "delete", network, gateway, func(ctx context.Context) error {
+		return rm.deleteRouteDirect(ctx, network, gateway, options)
+	}, log, rm.metrics)
 }
 
-func (rm *LinuxRouteManager) deleteRouteWithRetry(network *net.IPNet, gateway net.IP) error {
-	var lastErr error
-	start := time.Now()
+func (rm *LinuxRouteManager) addRouteDirect(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
 
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.deleteRouteDirect(network, gateway)
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ip", ipRouteArgs("add", network, gateway, options)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
 		}
 
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
+		routeErr := newIPRouteError(network, gateway, stderr.String(), err)
+		if routeErr.ErrorType == types.RouteErrExists && rm.routeInstalled(ctx, network, gateway, options.Interface) {
+			// The route we wanted is already there
+			return nil
 		}
-
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
+		return routeErr
 	}
 
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+	return nil
 }
 
-func (rm *LinuxRouteManager) addRouteDirect(network *net.IPNet, gateway net.IP) error {
-	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
+func (rm *LinuxRouteManager) replaceRouteDirect(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
 
-	cmd := exec.Command("ip", "route", "add", network.String(), "via", gateway.String())
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ip", ipRouteArgs("replace", network, gateway, options)...)
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			switch exitErr.ExitCode() {
-			case 1:
-				return &types.RouteOperationError{ErrorType: types.RouteErrPermission, Destination: *network, Gateway: gateway, Cause: err}
-			case 2:
-				return &types.RouteOperationError{ErrorType: types.RouteErrInvalidRoute, Destination: *network, Gateway: gateway, Cause: err}
-			default:
-				return &types.RouteOperationError{ErrorType: types.RouteErrSystemCall, Destination: *network, Gateway: gateway, Cause: err}
-			}
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
 		}
-		return &types.RouteOperationError{ErrorType: types.RouteErrSystemCall, Destination: *network, Gateway: gateway, Cause: err}
+		return newIPRouteError(network, gateway, stderr.String(), err)
 	}
 
 	return nil
 }
 
-func (rm *LinuxRouteManager) deleteRouteDirect(network *net.IPNet, gateway net.IP) error {
-	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
+// routeInstalled reports whether the kernel already has a route for network via gateway,
+// or out of iface for a gateway-less route
+func (rm *LinuxRouteManager) routeInstalled(ctx context.Context, network *net.IPNet, gateway net.IP, iface string) bool {
+	output, err := exec.CommandContext(ctx, "ip", "route", "show", "exact", network.String()).Output()
+	if err != nil {
+		return false
+	}
+	return routeShowMatches(string(output), gateway, iface)
+}
+
+func (rm *LinuxRouteManager) deleteRouteDirect(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
 
-	cmd := exec.Command("ip", "route", "del", network.String(), "via", gateway.String())
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ip", ipRouteArgs("del", network, gateway, options)...)
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if exitErr.ExitCode() == 2 {
-				return nil
-			}
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
 		}
-		return &types.RouteOperationError{ErrorType: types.RouteErrSystemCall, Destination: *network, Gateway: gateway, Cause: err}
+		return newIPRouteError(network, gateway, stderr.String(), err)
 	}
 
 	return nil
 }
 
+// ipRouteArgs builds the arguments of `ip route <action>` for a route, scoped to
+// options.Interface and with options.Metric when set. A route without a gateway
+// is an interface route (`dev <iface>`).
+func ipRouteArgs(action string, network *net.IPNet, gateway net.IP, options types.RouteOptions) []string {
+	args := []string{"route", action, network.String()}
+	if gateway != nil {
+		args = append(args, "via", gateway.String())
+	}
+	if options.Interface != "" {
+		args = append(args, "dev", options.Interface)
+	}
+	if options.Metric != 0 {
+		args = append(args, "metric", strconv.Itoa(options.Metric))
+	}
+	return args
+}
+
 func (rm *LinuxRouteManager) parseDefaultRouteLinux(output string) (net.IP, string, error) {
 	// Parse "default via 192.168.1.1 dev eth0" format
 	lines := strings.Split(output, "\n")
 	for _, line := range lines {
 		fields := strings.Fields(line)
+
+		// A point-to-point default route has no gateway: "default dev ppp0 scope link"
+		if len(fields) >= 3 && fields[0] == "default" && fields[1] == "dev" {
+			return nil, fields[2], nil
+		}
 		if len(fields) >= 5 && fields[0] == "default" && fields[1] == "via" {
 			gateway := net.ParseIP(fields[2])
 			if gateway == nil {
@@ -195,80 +303,3 @@ func (rm *LinuxRouteManager) parseDefaultRouteLinux(output string) (net.IP, stri
 
 	return nil, "", fmt.Errorf("no default gateway found")
 }
-
-// parseNetstatOutputLinux parses the output of netstat -rn for Linux systems
-func parseNetstatOutputLinux(output string) ([]*types.Route, error) {
-	var routes []*types.Route
-	lines := strings.Split(output, "\n")
-
-	// Skip header lines and find the start of routing table
-	start := -1
-	for i, line := range lines {
-		if strings.Contains(line, "Destination") && strings.Contains(line, "Gateway") {
-			start = i + 1
-			break
-		}
-	}
-
-	if start == -1 {
-		// Try alternative header format
-		for i, line := range lines {
-			if strings.Contains(line, "Internet:") {
-				start = i + 2 // Skip "Internet:" and the header line
-				break
-			}
-		}
-	}
-
-	if start == -1 {
-		return routes, nil // No routing table found
-	}
-
-	for i := start; i < len(lines); i++ {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
-
-		// Skip non-route lines (like "Internet6:" section)
-		if strings.Contains(line, ":") && !strings.Contains(line, ".") {
-			break
-		}
-
-		fields := strings.Fields(line)
-		if len(fields) < 3 {
-			continue
-		}
-
-		destination := fields[0]
-		gateway := fields[1]
-
-		// Parse fields based on standard netstat format:
-		// Destination Gateway Flags Netif [Expire]
-		// Skip if no gateway
-		if gateway == "" || gateway == "-" {
-			continue
-		}
-
-		// Parse destination network
-		network, err := utils.ParseDestination(destination)
-		if err != nil {
-			continue // Skip unparseable destinations
-		}
-
-		// Parse gateway IP
-		gwIP := net.ParseIP(gateway)
-		if gwIP == nil {
-			continue // Skip unparseable gateways (like link# formats)
-		}
-
-		route := &types.Route{
-			Destination: *network,
-			Gateway:     gwIP,
-		}
-
-		routes = append(routes, route)
-	}
-
-	return routes, nil
-}
@@ -0,0 +1,263 @@
+//go:build linux
+
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// ipBatchProbeTimeout bounds the check whether `ip -batch` works on this host
+const ipBatchProbeTimeout = 5 * time.Second
+
+// LinuxBatchRouteManager programs routes by streaming each chunk of a batch into a single
+// `ip -force -batch -` process instead of forking once per route. It is meant for hosts
+// where raw netlink is unavailable; single-route operations and queries are inherited.
+type LinuxBatchRouteManager struct {
+	*LinuxRouteManager
+}
+
+// ipBatchSupported reports whether this host can run `ip -batch` at all
+func ipBatchSupported() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), ipBatchProbeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "ip", "-force", "-batch", "-")
+	cmd.Stdin = strings.NewReader("")
+	return cmd.Run() == nil
+}
+
+// BatchAddRoutes adds multiple routes through `ip -batch`
+func (rm *LinuxBatchRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return rm.batch(ctx, "add", routes, log), nil
+}
+
+// BatchReplaceRoutes adds multiple routes through `ip -batch`, replacing any existing route for the same destination
+func (rm *LinuxBatchRouteManager) BatchReplaceRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return rm.batch(ctx, "replace", routes, log), nil
+}
+
+// BatchDeleteRoutes deletes multiple routes through `ip -batch`
+func (rm *LinuxBatchRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return rm.batch(ctx, "delete", routes, log), nil
+}
+
+// batch runs the routes through `ip -batch` one chunk at a time
+func (rm *LinuxBatchRouteManager) batch(ctx context.Context, action string, routes []*types.Route, log *logger.Logger) *types.BatchResult {
+	return batch.ProcessChunks(ctx, action, routes, func(ctx context.Context, chunk []*types.Route) []types.RouteOutcome {
+		return rm.runBatch(ctx, action, chunk, log)
+	}, rm.batchOptions, log)
+}
+
+// runBatch streams one chunk into `ip -force -batch -` and maps per-line failures back to routes
+func (rm *LinuxBatchRouteManager) runBatch(ctx context.Context, action string, routes []*types.Route, log *logger.Logger) []types.RouteOutcome {
+	start := time.Now()
+
+	var stdin, stderr bytes.Buffer
+	for _, route := range routes {
+		args := ipRouteArgs(action, &route.Destination, route.Gateway, types.NewRouteOptions(route.Options()...))
+		fmt.Fprintln(&stdin, strings.Join(args, " "))
+	}
+
+	cmd := exec.CommandContext(ctx, "ip", "-force", "-batch", "-")
+	cmd.Stdin = &stdin
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+
+	outcomes := make([]types.RouteOutcome, len(routes))
+	duration := time.Since(start) / time.Duration(max(len(routes), 1))
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		for i, route := range routes {
+			outcomes[i] = types.NewRouteOutcome(route, types.NewContextError(ctx.Err(), &route.Destination, route.Gateway), duration)
+		}
+		return outcomes
+	case runErr != nil && !errors.As(runErr, &exitErr):
+		// ip could not be started at all, fall back to one command per route
+		log.Warn("ip -batch failed to start, falling back to per-route commands", "error", runErr)
+		return rm.fallback(ctx, action, routes, log)
+	}
+
+	failures, aborted := parseIPBatchErrors(stderr.String())
+	if runErr != nil && len(failures) == 0 && aborted == "" {
+		// ip failed without reporting any line
+		aborted = runErr.Error()
+	}
+	if runErr == nil {
+		runErr = errors.New("ip -batch command failed")
+	}
+
+	// ip stops at the first line it cannot parse without naming it, so every line
+	// after the last reported failure has an unknown outcome and is reported as failed
+	if aborted != "" {
+		unknownFrom := 1
+		for line := range failures {
+			unknownFrom = max(unknownFrom, line+1)
+		}
+		for line := unknownFrom; line <= len(routes); line++ {
+			failures[line] = aborted
+		}
+	}
+
+	var installed map[string]bool
+	for i, route := range routes {
+		message, failed := failures[i+1]
+		if !failed {
+			outcomes[i] = types.NewRouteOutcome(route, nil, duration)
+			rm.metrics.RecordOperation(duration, true)
+			continue
+		}
+
+		routeErr := newIPRouteError(&route.Destination, route.Gateway, message, runErr)
+		if routeErr.ErrorType == types.RouteErrExists && action == "add" {
+			// One route dump covers all duplicates in the chunk
+			if installed == nil {
+				installed = rm.installedRoutes(ctx, routes)
+			}
+			if installed[routeKey(&route.Destination, route.Gateway, route.Interface)] {
+				outcomes[i] = types.NewRouteOutcome(route, nil, duration)
+				rm.metrics.RecordOperation(duration, true)
+				continue
+			}
+		}
+
+		outcomes[i] = types.NewRouteOutcome(route, routeErr, duration)
+		rm.metrics.RecordOperation(duration, outcomes[i].Status != types.OutcomeFailed)
+	}
+
+	return outcomes
+}
+
+// fallback programs routes one command per route when `ip -batch` cannot be used
+func (rm *LinuxBatchRouteManager) fallback(ctx context.Context, action string, routes []*types.Route, log *logger.Logger) []types.RouteOutcome {
+	var result *types.BatchResult
+	var err error
+	switch action {
+	case "delete":
+		result, err = rm.LinuxRouteManager.BatchDeleteRoutes(ctx, routes, log)
+	case "replace":
+		result, err = batch.ProcessUsingAnts(ctx, action, routes, rm.ReplaceRoute, rm.batchOptions, log)
+	default:
+		result, err = rm.LinuxRouteManager.BatchAddRoutes(ctx, routes, log)
+	}
+
+	if err != nil {
+		outcomes := make([]types.RouteOutcome, len(routes))
+		for i, route := range routes {
+			outcomes[i] = types.NewRouteOutcome(route, err, 0)
+		}
+		return outcomes
+	}
+	return result.Outcomes
+}
+
+// installedRoutes returns the routes currently in the main table keyed by routeKey.
+// `ip route show` only lists IPv4 routes, so the IPv6 table is dumped as well when
+// any of routes is IPv6.
+func (rm *LinuxBatchRouteManager) installedRoutes(ctx context.Context, routes []*types.Route) map[string]bool {
+	installed := make(map[string]bool)
+	for _, args := range installedRouteQueries(routes) {
+		output, err := exec.CommandContext(ctx, "ip", args...).Output()
+		if err != nil {
+			continue
+		}
+		for key := range parseIPRouteShow(string(output)) {
+			installed[key] = true
+		}
+	}
+	return installed
+}
+
+// installedRouteQueries returns the `ip` arguments that dump the tables holding routes
+func installedRouteQueries(routes []*types.Route) [][]string {
+	queries := [][]string{{"route", "show"}}
+	for _, route := range routes {
+		if route.Destination.IP.To4() == nil {
+			return append(queries, []string{"-6", "route", "show"})
+		}
+	}
+	return queries
+}
+
+// routeKey identifies a route by destination and gateway, or by destination and
+// interface for a gateway-less route
+func routeKey(destination *net.IPNet, gateway net.IP, iface string) string {
+	if gateway == nil {
+		return destination.String() + " dev " + iface
+	}
+	return destination.String() + " via " + gateway.String()
+}
+
+// parseIPRouteShow parses `ip route show` output into a set of routeKeys.
+// Host routes are printed without a prefix length, e.g. "198.18.0.1 via 192.168.1.1 dev eth0".
+func parseIPRouteShow(output string) map[string]bool {
+	routes := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		destination := fields[0]
+		if !strings.Contains(destination, "/") {
+			if strings.Contains(destination, ":") {
+				destination += "/128"
+			} else {
+				destination += "/32"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(destination)
+		gateway, iface := ipRouteNextHop(fields)
+		if err != nil || (gateway == nil && iface == "") {
+			continue
+		}
+		routes[routeKey(network, gateway, iface)] = true
+	}
+	return routes
+}
+
+// parseIPBatchErrors maps the stderr of `ip -force -batch -` to the failing input lines.
+// ip prints the error for a command followed by "Command failed -:<line>", with lines numbered from 1.
+// A line ip cannot parse aborts the batch without a line number; its message is returned as aborted.
+func parseIPBatchErrors(stderr string) (failures map[int]string, aborted string) {
+	failures = make(map[int]string)
+	var message []string
+
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		rest, ok := strings.CutPrefix(line, "Command failed ")
+		if !ok {
+			message = append(message, line)
+			continue
+		}
+
+		colon := strings.LastIndex(rest, ":")
+		number, err := strconv.Atoi(rest[colon+1:])
+		if colon < 0 || err != nil {
+			message = append(message, line)
+			continue
+		}
+		failures[number] = strings.Join(message, "; ")
+		message = nil
+	}
+
+	return failures, strings.Join(message, "; ")
+}
@@ -0,0 +1,124 @@
+//go:build linux
+
+package platform
+
+import (
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// Stderr captured from `ip -force -batch -` (iproute2 6.1)
+func TestParseIPBatchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		stderr   string
+		failures map[int]string
+		aborted  string
+	}{
+		{
+			name:     "all succeeded",
+			stderr:   "",
+			failures: map[int]string{},
+		},
+		{
+			name: "per-line failures",
+			stderr: "RTNETLINK answers: File exists\nCommand failed -:2\n" +
+				"Error: Nexthop has invalid gateway.\nCommand failed -:3\n",
+			failures: map[int]string{
+				2: "RTNETLINK answers: File exists",
+				3: "Error: Nexthop has invalid gateway.",
+			},
+		},
+		{
+			name: "aborted on unparseable line",
+			stderr: "RTNETLINK answers: No such process\nCommand failed -:1\n" +
+				"Error: any valid prefix is expected rather than \"198.18.0.300/32\".\n",
+			failures: map[int]string{1: "RTNETLINK answers: No such process"},
+			aborted:  "Error: any valid prefix is expected rather than \"198.18.0.300/32\".",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures, aborted := parseIPBatchErrors(tt.stderr)
+			if len(failures) != len(tt.failures) {
+				t.Fatalf("Expected %d failures, got %v", len(tt.failures), failures)
+			}
+			for line, message := range tt.failures {
+				if failures[line] != message {
+					t.Errorf("Line %d: expected %q, got %q", line, message, failures[line])
+				}
+			}
+			if aborted != tt.aborted {
+				t.Errorf("Expected aborted message %q, got %q", tt.aborted, aborted)
+			}
+		})
+	}
+}
+
+func TestParseIPRouteShow(t *testing.T) {
+	output := `default via 192.0.2.1 dev eth0
+192.0.2.0/24 dev eth0 proto kernel scope link src 192.0.2.2
+198.18.0.1 via 192.0.2.1 dev eth0
+10.0.0.0/8 via 192.0.2.1 dev eth0 proto static metric 100
+1.0.1.0/24 dev ppp0 scope link
+`
+	routes := parseIPRouteShow(output)
+
+	for _, cidr := range []string{"198.18.0.1/32", "10.0.0.0/8"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		if !routes[routeKey(network, net.ParseIP("192.0.2.1"), "eth0")] {
+			t.Errorf("Expected %s via 192.0.2.1 to be installed", cidr)
+		}
+	}
+	_, network, _ := net.ParseCIDR("1.0.1.0/24")
+	if !routes[routeKey(network, nil, "ppp0")] {
+		t.Error("Expected 1.0.1.0/24 dev ppp0 to be installed")
+	}
+	if len(routes) != 4 {
+		t.Errorf("Expected all routes with a parseable destination, got %v", routes)
+	}
+}
+
+func TestInstalledRouteQueries(t *testing.T) {
+	route := func(cidr string) *types.Route {
+		_, network, _ := net.ParseCIDR(cidr)
+		return &types.Route{Destination: *network}
+	}
+
+	if queries := installedRouteQueries([]*types.Route{route("10.0.0.0/8")}); len(queries) != 1 {
+		t.Errorf("Expected only the IPv4 table for IPv4 routes, got %v", queries)
+	}
+	queries := installedRouteQueries([]*types.Route{route("10.0.0.0/8"), route("2001:db8::/32")})
+	if len(queries) != 2 || queries[1][0] != "-6" {
+		t.Errorf("Expected the IPv6 table to be dumped for IPv6 routes, got %v", queries)
+	}
+
+	// `ip -6 route show` output
+	routes := parseIPRouteShow(`2001:db8::/32 via fe80::1 dev eth0 metric 1024 pref medium
+2001:db8:1::1 via fe80::1 dev eth0 metric 1024 pref medium
+`)
+	for _, cidr := range []string{"2001:db8::/32", "2001:db8:1::1/128"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		if !routes[routeKey(network, net.ParseIP("fe80::1"), "eth0")] {
+			t.Errorf("Expected %s via fe80::1 to be installed", cidr)
+		}
+	}
+}
+
+func TestBackendRouteManagerReportsRetries(t *testing.T) {
+	linux := newLinuxRouteManager(config.NewConfig())
+	var rm types.RouteManager = &backendRouteManager{RouteManager: &LinuxBatchRouteManager{LinuxRouteManager: linux}}
+
+	reporter, ok := rm.(types.MetricsReporter)
+	if !ok || reporter.Metrics() != linux.metrics {
+		t.Fatalf("Expected the backend metrics to be forwarded, got %#v", rm)
+	}
+	linux.metrics.RecordRetry()
+	if retries := reporter.Metrics().GetOperationStats()["retries"]; retries != int64(1) {
+		t.Errorf("Expected 1 retry in the operation stats, got %v", retries)
+	}
+}
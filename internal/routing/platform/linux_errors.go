@@ -0,0 +1,92 @@
+//go:build linux
+
+package platform
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// ipRouteErrors maps iproute2 error messages to route error types.
+// `ip` exits with status 2 for nearly every kernel error, so the message is the only reliable signal.
+var ipRouteErrors = []struct {
+	message   string
+	errorType types.RouteErrorType
+	errno     syscall.Errno // Kernel error preserved in the cause, if it matters to callers
+}{
+	{"file exists", types.RouteErrExists, 0},
+	{"operation not permitted", types.RouteErrPermission, 0},
+	{"permission denied", types.RouteErrPermission, 0},
+	{"network is unreachable", types.RouteErrGatewayUnreachable, 0},
+	{"nexthop has invalid gateway", types.RouteErrGatewayUnreachable, 0},
+	{"no route to host", types.RouteErrGatewayUnreachable, 0},
+	{"no such process", types.RouteErrNotFound, 0},
+	{"no such device", types.RouteErrNetwork, 0},
+	{"cannot find device", types.RouteErrNetwork, 0},
+	{"no buffer space available", types.RouteErrNetwork, syscall.ENOBUFS},
+	{"resource temporarily unavailable", types.RouteErrNetwork, syscall.EAGAIN},
+	{"is expected rather than", types.RouteErrInvalidRoute, 0},
+	{"invalid argument", types.RouteErrInvalidRoute, 0},
+}
+
+// classifyIPRouteError determines the error type and kernel errno from the stderr of a failed `ip route` command
+func classifyIPRouteError(stderr string) (types.RouteErrorType, syscall.Errno) {
+	message := strings.ToLower(stderr)
+	for _, known := range ipRouteErrors {
+		if strings.Contains(message, known.message) {
+			return known.errorType, known.errno
+		}
+	}
+	return types.RouteErrSystemCall, 0
+}
+
+// newIPRouteError builds a RouteOperationError for a failed `ip route` command
+func newIPRouteError(network *net.IPNet, gateway net.IP, stderr string, err error) *types.RouteOperationError {
+	errorType, errno := classifyIPRouteError(stderr)
+
+	cause := err
+	if errno != 0 {
+		cause = fmt.Errorf("%w: %w", err, errno)
+	} else if message := strings.TrimSpace(stderr); message != "" {
+		cause = fmt.Errorf("%w: %s", err, message)
+	}
+
+	return &types.RouteOperationError{
+		ErrorType:   errorType,
+		Destination: *network,
+		Gateway:     gateway,
+		Cause:       cause,
+	}
+}
+
+// routeShowMatches reports whether `ip route show exact` output contains a route via gateway,
+// or a route out of iface without a gateway when gateway is nil
+func routeShowMatches(output string, gateway net.IP, iface string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		via, dev := ipRouteNextHop(strings.Fields(line))
+		if gateway != nil && gateway.Equal(via) {
+			return true
+		}
+		if gateway == nil && via == nil && dev != "" && dev == iface {
+			return true
+		}
+	}
+	return false
+}
+
+// ipRouteNextHop returns the "via" gateway and "dev" interface of one `ip route show` line
+func ipRouteNextHop(fields []string) (via net.IP, dev string) {
+	for i := 0; i+1 < len(fields); i++ {
+		switch fields[i] {
+		case "via":
+			via = net.ParseIP(fields[i+1])
+		case "dev":
+			dev = fields[i+1]
+		}
+	}
+	return via, dev
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package platform
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// Stderr samples captured from iproute2 on Debian and Alpine
+func TestClassifyIPRouteError(t *testing.T) {
+	tests := []struct {
+		name      string
+		stderr    string
+		errorType types.RouteErrorType
+		retryable bool
+	}{
+		{"duplicate", "RTNETLINK answers: File exists\n", types.RouteErrExists, false},
+		{"not root", "RTNETLINK answers: Operation not permitted\n", types.RouteErrPermission, false},
+		{"unreachable gateway", "RTNETLINK answers: Network is unreachable\n", types.RouteErrGatewayUnreachable, true},
+		{"invalid nexthop", "Error: Nexthop has invalid gateway.\n", types.RouteErrGatewayUnreachable, true},
+		{"delete missing", "RTNETLINK answers: No such process\n", types.RouteErrNotFound, false},
+		{"interface gone", "Cannot find device \"eth9\"\n", types.RouteErrNetwork, true},
+		{"netlink buffer", "RTNETLINK answers: No buffer space available\n", types.RouteErrNetwork, true},
+		{"bad prefix", "Error: inet prefix is expected rather than \"10.0.0.0/33\".\n", types.RouteErrInvalidRoute, false},
+		{"bad gateway", "Error: any valid prefix is expected rather than \"gw\".\n", types.RouteErrInvalidRoute, false},
+		{"invalid argument", "RTNETLINK answers: Invalid argument\n", types.RouteErrInvalidRoute, false},
+		{"unknown", "Segmentation fault\n", types.RouteErrSystemCall, false},
+		{"empty", "", types.RouteErrSystemCall, false},
+	}
+
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	gateway := net.ParseIP("192.168.1.1")
+	cause := errors.New("exit status 2")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newIPRouteError(network, gateway, tt.stderr, cause)
+			if err.ErrorType != tt.errorType {
+				t.Errorf("Expected %s, got %s", tt.errorType, err.ErrorType)
+			}
+			if err.IsRetryable() != tt.retryable {
+				t.Errorf("Expected retryable=%v for %s", tt.retryable, err.ErrorType)
+			}
+			if !errors.Is(err.Cause, cause) {
+				t.Errorf("Expected the exit error to be preserved in the cause")
+			}
+			if types.IsThrottled(err) != (tt.name == "netlink buffer") {
+				t.Errorf("Expected only ENOBUFS to be reported as throttling")
+			}
+		})
+	}
+}
+
+func TestRouteShowMatches(t *testing.T) {
+	output := "10.0.0.0/24 via 192.168.1.1 dev eth0 proto static\n"
+
+	if !routeShowMatches(output, net.ParseIP("192.168.1.1"), "") {
+		t.Error("Expected the existing route to match the intended gateway")
+	}
+	if routeShowMatches(output, net.ParseIP("10.8.0.1"), "") {
+		t.Error("A route via another gateway must not count as the intended route")
+	}
+	if routeShowMatches("", net.ParseIP("192.168.1.1"), "") {
+		t.Error("Empty output must not match")
+	}
+
+	interfaceRoute := "10.0.0.0/24 dev ppp0 scope link\n"
+	if !routeShowMatches(interfaceRoute, nil, "ppp0") {
+		t.Error("Expected the interface route to match the intended interface")
+	}
+	if routeShowMatches(interfaceRoute, nil, "wwan0") || routeShowMatches(output, nil, "eth0") {
+		t.Error("Only a gateway-less route on the same interface may match an interface route")
+	}
+}
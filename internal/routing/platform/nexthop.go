@@ -0,0 +1,44 @@
+package platform
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+	"github.com/wesleywu/smart-route/internal/utils"
+)
+
+// checkNextHop rejects a route that has neither a gateway nor an interface to send traffic to
+func checkNextHop(network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	if gateway == nil && options.Interface == "" {
+		return &types.RouteOperationError{
+			ErrorType:   types.RouteErrInvalidRoute,
+			Destination: *network,
+			Cause:       fmt.Errorf("route needs a gateway or an interface"),
+		}
+	}
+	return nil
+}
+
+// pointToPointInterfaces returns the names of the physical point-to-point interfaces, such as
+// PPPoE and cellular links. Gateway-less routes on them are interface routes to a physical uplink
+// rather than connected networks. VPN tunnels are left out so that their routes are never taken
+// for ours.
+func pointToPointInterfaces() map[string]bool {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return map[string]bool{}
+	}
+	return physicalPointToPoint(interfaces)
+}
+
+// physicalPointToPoint returns the names of the physical point-to-point interfaces among interfaces
+func physicalPointToPoint(interfaces []net.Interface) map[string]bool {
+	names := make(map[string]bool)
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagPointToPoint != 0 && utils.IsPhysicalInterface(iface.Name) {
+			names[iface.Name] = true
+		}
+	}
+	return names
+}
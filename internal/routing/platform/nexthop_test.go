@@ -0,0 +1,22 @@
+package platform
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPhysicalPointToPoint(t *testing.T) {
+	interfaces := []net.Interface{
+		{Name: "eth0", Flags: net.FlagUp | net.FlagBroadcast},
+		{Name: "ppp0", Flags: net.FlagUp | net.FlagPointToPoint},
+		{Name: "wwan0", Flags: net.FlagUp | net.FlagPointToPoint},
+		{Name: "tun0", Flags: net.FlagUp | net.FlagPointToPoint},
+		{Name: "utun3", Flags: net.FlagUp | net.FlagPointToPoint},
+		{Name: "wg0", Flags: net.FlagUp | net.FlagPointToPoint},
+	}
+
+	names := physicalPointToPoint(interfaces)
+	if len(names) != 2 || !names["ppp0"] || !names["wwan0"] {
+		t.Errorf("Expected only ppp0 and wwan0, got %v", names)
+	}
+}
@@ -0,0 +1,222 @@
+//go:build linux
+
+package platform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+const (
+	procNetRoute     = "/proc/net/route"
+	procNetIPv6Route = "/proc/net/ipv6_route"
+)
+
+// Linux route flags (linux/route.h, linux/ipv6_route.h) that tell where a route came from
+const (
+	linuxRTFDynamic  = 0x0010     // RTF_DYNAMIC: learned from an ICMP redirect
+	linuxRTFAddrconf = 0x40000    // RTF_ADDRCONF: learned from a router advertisement
+	linuxRTFLocal    = 0x80000000 // RTF_LOCAL: a local address, kept in the local table
+)
+
+// Linux routing table IDs (RT_TABLE_*)
+const (
+	linuxTableMain  = 254
+	linuxTableLocal = 255
+)
+
+// readProcRoutes reads the IPv4 and IPv6 main routing tables from procfs
+func readProcRoutes() ([]*types.Route, error) {
+	routes, err := readProcFile(procNetRoute, parseProcNetRoute)
+	if err != nil {
+		return nil, err
+	}
+
+	ipv6Routes, err := readProcFile(procNetIPv6Route, parseProcNetIPv6Route)
+	if errors.Is(err, fs.ErrNotExist) {
+		// IPv6 is disabled on this host
+		return routes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(routes, ipv6Routes...), nil
+}
+
+// readProcFile opens a procfs file and parses it with parse
+func readProcFile(path string, parse func(io.Reader) ([]*types.Route, error)) ([]*types.Route, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	routes, err := parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return routes, nil
+}
+
+// parseProcNetRoute parses /proc/net/route. Addresses are the raw network-order
+// words printed as host-order hex, i.e. little-endian on x86 and ARM.
+//
+//	Iface  Destination  Gateway   Flags  RefCnt  Use  Metric  Mask      MTU  Window  IRTT
+//	eth0   00000000     010200C0  0003   0       0    0       00000000  0    0       0
+func parseProcNetRoute(r io.Reader) ([]*types.Route, error) {
+	var routes []*types.Route
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if lineNumber == 1 || len(fields) == 0 {
+			continue // header
+		}
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("line %d: expected at least 8 fields, got %d", lineNumber, len(fields))
+		}
+
+		destination, err := parseProcIPv4(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: destination: %w", lineNumber, err)
+		}
+		gateway, err := parseProcIPv4(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: gateway: %w", lineNumber, err)
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: flags: %w", lineNumber, err)
+		}
+		metric, err := strconv.Atoi(fields[6])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: metric: %w", lineNumber, err)
+		}
+		mask, err := parseProcIPv4(fields[7])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: mask: %w", lineNumber, err)
+		}
+
+		route := &types.Route{
+			Destination: net.IPNet{IP: destination, Mask: net.IPMask(mask)},
+			Metric:      metric,
+			Interface:   fields[0],
+			Flags:       uint32(flags),
+			Protocol:    procRouteProtocol(uint32(flags)),
+			Table:       linuxTableMain, // /proc/net/route only shows the main table
+		}
+		if !gateway.IsUnspecified() {
+			route.Gateway = gateway
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, scanner.Err()
+}
+
+// parseProcNetIPv6Route parses /proc/net/ipv6_route, which has no header and
+// prints addresses in network order:
+//
+//	dest prefixlen src src_prefixlen nexthop metric refcnt use flags iface
+func parseProcNetIPv6Route(r io.Reader) ([]*types.Route, error) {
+	var routes []*types.Route
+	scanner := bufio.NewScanner(r)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 10 {
+			return nil, fmt.Errorf("line %d: expected 10 fields, got %d", lineNumber, len(fields))
+		}
+
+		destination, err := parseProcIPv6(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: destination: %w", lineNumber, err)
+		}
+		prefixLength, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil || prefixLength > 128 {
+			return nil, fmt.Errorf("line %d: invalid prefix length %q", lineNumber, fields[1])
+		}
+		nextHop, err := parseProcIPv6(fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: next hop: %w", lineNumber, err)
+		}
+		metric, err := strconv.ParseUint(fields[5], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: metric: %w", lineNumber, err)
+		}
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: flags: %w", lineNumber, err)
+		}
+
+		route := &types.Route{
+			Destination: net.IPNet{IP: destination, Mask: net.CIDRMask(int(prefixLength), 128)},
+			Metric:      int(metric),
+			Interface:   fields[9],
+			Flags:       uint32(flags),
+			Protocol:    procRouteProtocol(uint32(flags)),
+			Table:       linuxTableMain,
+		}
+		if flags&linuxRTFLocal != 0 {
+			route.Table = linuxTableLocal
+		}
+		if !nextHop.IsUnspecified() {
+			route.Gateway = nextHop
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, scanner.Err()
+}
+
+// procRouteProtocol derives the route protocol from its flags. procfs does not
+// tell static routes from kernel routes, so those are reported as unknown.
+func procRouteProtocol(flags uint32) string {
+	switch {
+	case flags&linuxRTFLocal != 0:
+		return types.RouteProtocolKernel
+	case flags&linuxRTFDynamic != 0:
+		return types.RouteProtocolRedirect
+	case flags&linuxRTFAddrconf != 0:
+		return types.RouteProtocolRA
+	default:
+		return ""
+	}
+}
+
+// parseProcIPv4 decodes an IPv4 address printed as a host-order 32-bit hex word
+func parseProcIPv4(field string) (net.IP, error) {
+	value, err := strconv.ParseUint(field, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.NativeEndian.PutUint32(ip, uint32(value))
+	return ip, nil
+}
+
+// parseProcIPv6 decodes an IPv6 address printed as 32 hex digits in network order
+func parseProcIPv6(field string) (net.IP, error) {
+	ip, err := hex.DecodeString(field)
+	if err != nil {
+		return nil, err
+	}
+	if len(ip) != net.IPv6len {
+		return nil, fmt.Errorf("expected %d bytes, got %d", net.IPv6len, len(ip))
+	}
+	return net.IP(ip), nil
+}
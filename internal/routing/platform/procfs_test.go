@@ -0,0 +1,84 @@
+//go:build linux
+
+package platform
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// formatRoutes renders routes one per line for comparison with golden files
+func formatRoutes(routes []*types.Route) string {
+	var b strings.Builder
+	for _, route := range routes {
+		gateway := "-"
+		if route.Gateway != nil {
+			gateway = route.Gateway.String()
+		}
+		protocol := route.Protocol
+		if protocol == "" {
+			protocol = "-"
+		}
+		fmt.Fprintf(&b, "%-24s %-18s %-5s flags=%#06x metric=%d proto=%s table=%d\n",
+			route.Destination.String(), gateway, route.Interface, route.Flags, route.Metric, protocol, route.Table)
+	}
+	return b.String()
+}
+
+func TestParseProcRoutesGolden(t *testing.T) {
+	tests := []struct {
+		file  string
+		parse func(io.Reader) ([]*types.Route, error)
+	}{
+		{"proc_net_route", parseProcNetRoute},
+		{"proc_net_ipv6_route", parseProcNetIPv6Route},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			input, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer input.Close()
+
+			routes, err := tt.parse(input)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			got := formatRoutes(routes)
+
+			golden := filepath.Join("testdata", tt.file+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("Parsed routes differ from %s:\ngot:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
+
+func TestParseProcNetRouteRejectsMalformed(t *testing.T) {
+	input := "Iface\tDestination\tGateway\tFlags\tRefCnt\tUse\tMetric\tMask\n" +
+		"eth0\tZZZZZZZZ\t0120A8C0\t0003\t0\t0\t0\t00FFFFFF\n"
+
+	if _, err := parseProcNetRoute(strings.NewReader(input)); err == nil {
+		t.Error("Expected an error for a malformed destination")
+	}
+}
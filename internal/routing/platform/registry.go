@@ -0,0 +1,155 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// Backend describes a RouteManager implementation that can be selected at runtime
+type Backend struct {
+	Name         string
+	Description  string
+	Capabilities types.Capabilities
+	// Priority orders automatic selection, the highest available backend wins.
+	// Backends with zero priority are only used when selected by name.
+	Priority int
+	// Available reports whether the backend can run on this host, nil means always
+	Available func() bool
+	New       func(cfg *config.Config) (types.RouteManager, error)
+}
+
+// IsAvailable reports whether the backend can run on this host
+func (b Backend) IsAvailable() bool {
+	return b.Available == nil || b.Available()
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Backend)
+)
+
+// Register makes a backend selectable by name. It panics if the name is already taken.
+func Register(backend Backend) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[backend.Name]; exists {
+		panic(fmt.Sprintf("route backend %q registered twice", backend.Name))
+	}
+	registry[backend.Name] = backend
+}
+
+// Backends returns all registered backends, in the order automatic selection tries them
+func Backends() []Backend {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	backends := make([]Backend, 0, len(registry))
+	for _, backend := range registry {
+		backends = append(backends, backend)
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		if backends[i].Priority != backends[j].Priority {
+			return backends[i].Priority > backends[j].Priority
+		}
+		return backends[i].Name < backends[j].Name
+	})
+	return backends
+}
+
+// NewRouteManager creates a route manager using the named backend.
+// An empty name selects the highest priority backend available on this host.
+func NewRouteManager(name string, cfg *config.Config) (types.RouteManager, error) {
+	backend, err := selectBackend(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rm, err := backend.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s route backend: %w", backend.Name, err)
+	}
+	return &backendRouteManager{RouteManager: rm, backend: backend}, nil
+}
+
+// NewPlatformRouteManager creates the route manager for the backend configured in cfg
+func NewPlatformRouteManager(cfg *config.Config) (types.RouteManager, error) {
+	return NewRouteManager(cfg.RouteBackend, cfg)
+}
+
+// selectBackend looks up the named backend, or picks one automatically if name is empty
+func selectBackend(name string) (Backend, error) {
+	if name != "" {
+		registryMutex.RLock()
+		backend, ok := registry[name]
+		registryMutex.RUnlock()
+
+		if !ok {
+			return Backend{}, fmt.Errorf("unknown route backend %q", name)
+		}
+		if !backend.IsAvailable() {
+			return Backend{}, fmt.Errorf("route backend %q is not available on this host", name)
+		}
+		return backend, nil
+	}
+
+	for _, backend := range Backends() {
+		if backend.Priority > 0 && backend.IsAvailable() {
+			return backend, nil
+		}
+	}
+	return Backend{}, fmt.Errorf("no route backend available on this host")
+}
+
+// backendRouteManager annotates a route manager with the backend it was created from
+type backendRouteManager struct {
+	types.RouteManager
+	backend Backend
+}
+
+// Backend returns the name of the backend
+func (rm *backendRouteManager) Backend() string {
+	return rm.backend.Name
+}
+
+// Capabilities returns the optional features of the backend
+func (rm *backendRouteManager) Capabilities() types.Capabilities {
+	return rm.backend.Capabilities
+}
+
+// Metrics forwards to the backend if it keeps route operation metrics
+func (rm *backendRouteManager) Metrics() *metrics.Metrics {
+	if reporter, ok := rm.RouteManager.(types.MetricsReporter); ok {
+		return reporter.Metrics()
+	}
+	return nil
+}
+
+// ListPhysicalUplinks forwards to the backend if it can enumerate uplinks, and otherwise
+// reports the physical gateway as the only uplink
+func (rm *backendRouteManager) ListPhysicalUplinks(ctx context.Context) ([]types.Uplink, error) {
+	if lister, ok := rm.RouteManager.(types.UplinkLister); ok {
+		return lister.ListPhysicalUplinks(ctx)
+	}
+	gateway, iface, err := rm.GetPhysicalGateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []types.Uplink{{Gateway: gateway, Interface: iface}}, nil
+}
+
+// BatchReplaceRoutes forwards to the backend if it supports replacing routes
+func (rm *backendRouteManager) BatchReplaceRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	replacer, ok := rm.RouteManager.(types.RouteReplacer)
+	if !ok || !rm.backend.Capabilities.Replace {
+		return nil, fmt.Errorf("route backend %s does not support replacing routes", rm.backend.Name)
+	}
+	return replacer.BatchReplaceRoutes(ctx, routes, log)
+}
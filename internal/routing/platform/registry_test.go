@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func TestNewRouteManagerSelectsBackend(t *testing.T) {
+	cfg := config.NewConfig()
+
+	rm, err := NewRouteManager("fake", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create fake backend: %v", err)
+	}
+	reporter, ok := rm.(types.CapabilityReporter)
+	if !ok || reporter.Backend() != "fake" || !reporter.Capabilities().Replace {
+		t.Fatalf("Expected the fake backend with replace capability, got %#v", rm)
+	}
+
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	routes := []*types.Route{{Destination: *network, Gateway: net.ParseIP("192.0.2.1")}}
+	result, err := rm.(types.RouteReplacer).BatchReplaceRoutes(context.Background(), routes, logger.New("error"))
+	if err != nil || result.Succeeded != 1 {
+		t.Errorf("Expected replace to be forwarded to the backend, got %v, %v", result, err)
+	}
+
+	if _, err := NewRouteManager("no-such-backend", cfg); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+func TestFakeRouteManagerSemantics(t *testing.T) {
+	ctx := context.Background()
+	rm := NewFakeRouteManager(config.NewConfig())
+	_, network, _ := net.ParseCIDR("10.0.0.0/24")
+	oldGateway, newGateway := net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.254")
+
+	if err := rm.AddRoute(ctx, network, oldGateway, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := rm.AddRoute(ctx, network, oldGateway, nil); err != nil {
+		t.Errorf("Adding the same route again should succeed, got %v", err)
+	}
+	if err, ok := rm.AddRoute(ctx, network, newGateway, nil).(*types.RouteOperationError); !ok || err.ErrorType != types.RouteErrExists {
+		t.Errorf("Expected RouteErrExists for a conflicting route, got %v", err)
+	}
+	if err := rm.ReplaceRoute(ctx, network, newGateway, nil); err != nil {
+		t.Errorf("Unexpected error replacing route: %v", err)
+	}
+	if err, ok := rm.DeleteRoute(ctx, network, oldGateway, nil).(*types.RouteOperationError); !ok || err.ErrorType != types.RouteErrNotFound {
+		t.Errorf("Expected RouteErrNotFound for the replaced route, got %v", err)
+	}
+
+	routes, _ := rm.ListSystemRoutes(ctx)
+	if len(routes) != 1 || !routes[0].Gateway.Equal(newGateway) {
+		t.Errorf("Expected one route via the new gateway, got %v", routes)
+	}
+}
+
+func TestFakeRouteManagerRouteOptions(t *testing.T) {
+	ctx := context.Background()
+	rm := NewFakeRouteManager(config.NewConfig())
+	_, scoped, _ := net.ParseCIDR("10.0.0.0/24")
+	_, unscoped, _ := net.ParseCIDR("10.0.1.0/24")
+	gateway := net.ParseIP("192.0.2.1")
+
+	// Batch operations pass the route's own metric and interface on
+	routes := []*types.Route{
+		{Destination: *scoped, Gateway: gateway, Metric: 50, Interface: "eth1"},
+		{Destination: *unscoped, Gateway: gateway},
+	}
+	if _, err := rm.BatchAddRoutes(ctx, routes, logger.New("error")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	listed, _ := rm.ListSystemRoutes(ctx)
+	if len(listed) != 2 {
+		t.Fatalf("Expected 2 routes, got %d", len(listed))
+	}
+	if listed[0].Metric != 50 || listed[0].Interface != "eth1" || listed[0].Protocol != types.RouteProtocolStatic {
+		t.Errorf("Expected metric 50 on eth1, got %+v", listed[0])
+	}
+	if listed[1].Metric != 0 || listed[1].Interface != fakeInterface {
+		t.Errorf("Expected an unscoped route on the physical interface, got %+v", listed[1])
+	}
+}
+
+func TestNewRouteManagerForwardsUplinks(t *testing.T) {
+	rm, err := NewRouteManager("fake", config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create fake backend: %v", err)
+	}
+	wired := types.Uplink{Gateway: net.ParseIP("192.168.1.1"), Interface: "eth0"}
+	second := types.Uplink{Gateway: net.ParseIP("202.112.10.1"), Interface: "eth1"}
+	rm.(*backendRouteManager).RouteManager.(*FakeRouteManager).SetPhysicalUplinks(wired, second)
+
+	lister, ok := rm.(types.UplinkLister)
+	if !ok {
+		t.Fatalf("Expected the route manager to list uplinks, got %#v", rm)
+	}
+	uplinks, err := lister.ListPhysicalUplinks(context.Background())
+	if err != nil || !types.SameUplinks(uplinks, []types.Uplink{wired, second}) {
+		t.Errorf("Expected both uplinks of the backend, got %v, %v", uplinks, err)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build linux || darwin || freebsd
+
+package platform
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+	"github.com/wesleywu/smart-route/internal/utils"
+)
+
+// errNoPhysicalUplink is returned when no physical interface has a default route
+var errNoPhysicalUplink = errors.New("no physical uplink found")
+
+// physicalUplinks enumerates the physical uplinks from the default routes in the routing table,
+// ordered by priority. If no physical interface has a default route, e.g. because a VPN client
+// replaced it, the physical gateway found by fallback is the only uplink; without a fallback
+// errNoPhysicalUplink is returned.
+func physicalUplinks(ctx context.Context, listRoutes func(context.Context) ([]*types.Route, error), fallback func(context.Context) (net.IP, string, error), priority []string) ([]types.Uplink, error) {
+	routes, err := listRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uplinks := defaultRouteUplinks(routes, interfaceFlags)
+	if len(uplinks) == 0 {
+		if fallback == nil {
+			return nil, errNoPhysicalUplink
+		}
+		gateway, iface, err := fallback(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []types.Uplink{{Gateway: gateway, Interface: iface}}, nil
+	}
+	return types.OrderUplinks(uplinks, priority), nil
+}
+
+// defaultRouteUplinks returns an uplink for every physical interface that is up and has an IPv4
+// default route, through the route with the lowest metric, ordered by that metric. Gateway-less
+// default routes are only listed on point-to-point links, such as PPPoE.
+func defaultRouteUplinks(routes []*types.Route, flags func(string) (net.Flags, bool)) []types.Uplink {
+	best := make(map[string]*types.Route)
+	var interfaces []string
+	for _, route := range routes {
+		ones, _ := route.Destination.Mask.Size()
+		if ones != 0 || route.Destination.IP.To4() == nil || !utils.IsPhysicalInterface(route.Interface) {
+			continue
+		}
+		ifaceFlags, ok := flags(route.Interface)
+		if !ok || ifaceFlags&net.FlagUp == 0 {
+			continue
+		}
+		if route.Gateway == nil && ifaceFlags&net.FlagPointToPoint == 0 {
+			continue
+		}
+		current, ok := best[route.Interface]
+		if !ok {
+			interfaces = append(interfaces, route.Interface)
+		}
+		if !ok || route.Metric < current.Metric {
+			best[route.Interface] = route
+		}
+	}
+
+	sort.SliceStable(interfaces, func(i, j int) bool {
+		return best[interfaces[i]].Metric < best[interfaces[j]].Metric
+	})
+	uplinks := make([]types.Uplink, 0, len(interfaces))
+	for _, name := range interfaces {
+		uplinks = append(uplinks, types.Uplink{Gateway: best[name].Gateway, Interface: name})
+	}
+	return uplinks
+}
+
+// interfaceFlags returns the flags of the named interface, and false if it does not exist
+func interfaceFlags(name string) (net.Flags, bool) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return 0, false
+	}
+	return iface.Flags, true
+}
@@ -0,0 +1,53 @@
+//go:build linux || darwin || freebsd
+
+package platform
+
+import (
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func TestDefaultRouteUplinks(t *testing.T) {
+	route := func(cidr, gateway, iface string, metric int) *types.Route {
+		_, destination, _ := net.ParseCIDR(cidr)
+		return &types.Route{Destination: *destination, Gateway: net.ParseIP(gateway), Interface: iface, Metric: metric}
+	}
+	routes := []*types.Route{
+		route("0.0.0.0/0", "10.8.0.1", "tun0", 0),        // VPN
+		route("0.0.0.0/0", "192.168.1.1", "en1", 600),    // Wi-Fi
+		route("0.0.0.0/0", "202.112.10.1", "eth1", 200),  // second ISP with a public address
+		route("0.0.0.0/0", "192.168.2.254", "eth0", 300), // replaced by the lower metric route of eth0
+		route("0.0.0.0/0", "192.168.2.1", "eth0", 100),
+		route("0.0.0.0/0", "", "ppp0", 50),            // PPPoE, gateway-less
+		route("0.0.0.0/0", "", "eth0", 20),            // gateway-less on a broadcast link
+		route("0.0.0.0/0", "", "eth3", 30),            // gateway-less on a broadcast link
+		route("0.0.0.0/0", "192.168.3.1", "eth2", 10), // down
+		route("0.0.0.0/0", "192.168.4.1", "eth4", 10), // interface gone
+		route("::/0", "fe80::1", "eth0", 100),         // IPv6
+		route("1.0.1.0/24", "192.168.2.1", "eth0", 0), // not a default route
+	}
+	flags := func(name string) (net.Flags, bool) {
+		switch name {
+		case "eth2":
+			return 0, true
+		case "eth4":
+			return 0, false
+		case "ppp0":
+			return net.FlagUp | net.FlagPointToPoint, true
+		default:
+			return net.FlagUp | net.FlagBroadcast, true
+		}
+	}
+
+	want := []types.Uplink{
+		{Interface: "ppp0"},
+		{Gateway: net.ParseIP("192.168.2.1"), Interface: "eth0"},
+		{Gateway: net.ParseIP("202.112.10.1"), Interface: "eth1"},
+		{Gateway: net.ParseIP("192.168.1.1"), Interface: "en1"},
+	}
+	if got := defaultRouteUplinks(routes, flags); !types.SameUplinks(got, want) {
+		t.Errorf("Expected uplinks %v, got %v", want, got)
+	}
+}
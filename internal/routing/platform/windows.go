@@ -3,14 +3,15 @@
 package platform
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/wesleywu/smart-route/internal/config"
 	"github.com/wesleywu/smart-route/internal/logger"
 	"github.com/wesleywu/smart-route/internal/routing/batch"
 	"github.com/wesleywu/smart-route/internal/routing/types"
@@ -18,50 +19,73 @@ import (
 )
 
 type WindowsRouteManager struct {
-	mutex            sync.Mutex
 	concurrencyLimit int
-	maxRetries       int
+	retryPolicy      batch.RetryPolicy
+	routeTimeout     time.Duration
+	batchOptions     batch.Options
 	metrics          *metrics.Metrics
 }
 
-// NewPlatformRouteManager creates a platform-specific route manager (Windows implementation)
-func NewPlatformRouteManager(concurrencyLimit, maxRetries int) (types.RouteManager, error) {
+func init() {
+	Register(Backend{
+		Name:        "route-exe",
+		Description: "route.exe, one process per route",
+		Priority:    10,
+		New:         newWindowsRouteManager,
+	})
+}
+
+// newWindowsRouteManager creates a route manager backed by route.exe
+func newWindowsRouteManager(cfg *config.Config) (types.RouteManager, error) {
 	return &WindowsRouteManager{
-		concurrencyLimit: concurrencyLimit,
-		maxRetries:       maxRetries,
+		concurrencyLimit: cfg.ConcurrencyLimit,
+		retryPolicy:      batch.NewRetryPolicy(cfg),
+		routeTimeout:     cfg.RouteTimeout,
+		batchOptions:     batchOptions(cfg),
 		metrics:          metrics.NewMetrics(),
 	}, nil
 }
 
-func (rm *WindowsRouteManager) AddRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.addRouteWithRetry(network, gateway)
+func (rm *WindowsRouteManager) AddRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.addRouteWithRetry(ctx, network, gateway, options, log)
 }
 
-func (rm *WindowsRouteManager) DeleteRoute(network *net.IPNet, gateway net.IP, log *logger.Logger) error {
-	return rm.deleteRouteWithRetry(network, gateway)
+func (rm *WindowsRouteManager) DeleteRoute(ctx context.Context, network *net.IPNet, gateway net.IP, log *logger.Logger, opts ...types.RouteOption) error {
+	options := types.NewRouteOptions(opts...)
+	if err := checkNextHop(network, gateway, options); err != nil {
+		return err
+	}
+	return rm.deleteRouteWithRetry(ctx, network, gateway, options, log)
 }
 
-func (rm *WindowsRouteManager) BatchAddRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.AddRoute, rm.concurrencyLimit, log)
+func (rm *WindowsRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "add", routes, rm.AddRoute, rm.batchOptions, log)
 }
 
-func (rm *WindowsRouteManager) BatchDeleteRoutes(routes []*types.Route, log *logger.Logger) error {
-	return batch.ProcessUsingAnts(routes, rm.DeleteRoute, rm.concurrencyLimit, log)
+func (rm *WindowsRouteManager) BatchDeleteRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	return batch.ProcessUsingAnts(ctx, "delete", routes, rm.DeleteRoute, rm.batchOptions, log)
 }
 
 // GetPhysicalGateway gets the underlying physical network gateway (for route management)
-func (rm *WindowsRouteManager) GetPhysicalGateway() (net.IP, string, error) {
+func (rm *WindowsRouteManager) GetPhysicalGateway(ctx context.Context) (net.IP, string, error) {
 	// For Windows, we use the same implementation as system default route
 	// In a real scenario, this would need logic to detect VPN interfaces
-	return rm.GetSystemDefaultRoute()
+	return rm.GetSystemDefaultRoute(ctx)
 }
 
 // GetSystemDefaultRoute gets the current default route (including VPN) from the system
-func (rm *WindowsRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
-	cmd := exec.Command("route", "print", "0.0.0.0")
+func (rm *WindowsRouteManager) GetSystemDefaultRoute(ctx context.Context) (net.IP, string, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "route", "print", "0.0.0.0")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get default route: %w", err)
+		return nil, "", fmt.Errorf("failed to get default route: %w", queryError(ctx, err))
 	}
 
 	return rm.parseDefaultRouteWindows(string(output))
@@ -85,20 +109,118 @@ func (rm *WindowsRouteManager) GetSystemDefaultRoute() (net.IP, string, error) {
 //   255.255.255.255  255.255.255.255         On-link         127.0.0.1    331
 //   255.255.255.255  255.255.255.255         On-link       10.211.55.9    271
 // ===========================================================================
-func (rm *WindowsRouteManager) ListSystemRoutes() ([]*types.Route, error) {
-	cmd := exec.Command("netstat", "-rn")
-	_, err := cmd.Output()
+func (rm *WindowsRouteManager) ListSystemRoutes(ctx context.Context) ([]*types.Route, error) {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "netstat", "-rn")
+	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list routes: %w", err)
+		return nil, fmt.Errorf("failed to list routes: %w", queryError(ctx, err))
+	}
+
+	// netstat names the interface by its address, report the interface name instead.
+	// On-link routes are only kept on point-to-point links, where they are interface routes.
+	names := interfaceNamesByAddress()
+	pointToPoint := pointToPointInterfaces()
+	var routes []*types.Route
+	for _, route := range parseNetstatOutputWindows(string(output)) {
+		if name, ok := names[route.Interface]; ok {
+			route.Interface = name
+		}
+		if route.Gateway != nil || pointToPoint[route.Interface] {
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+// parseNetstatOutputWindows parses the active routes of the IPv4 route table printed by
+// netstat -rn. On-link routes have no gateway, and the interface is given by its address.
+func parseNetstatOutputWindows(output string) []*types.Route {
+	var routes []*types.Route
+	inActiveRoutes := false
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Active Routes:"):
+			inActiveRoutes = true
+			continue
+		case strings.HasPrefix(line, "===="):
+			// The table ends with a separator line
+			if inActiveRoutes && len(routes) > 0 {
+				return routes
+			}
+			continue
+		case !inActiveRoutes:
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			continue // header or unrelated line
+		}
+		destination := net.ParseIP(fields[0]).To4()
+		mask := net.ParseIP(fields[1]).To4()
+		metric, err := strconv.Atoi(fields[4])
+		if destination == nil || mask == nil || err != nil {
+			continue
+		}
+
+		route := &types.Route{
+			Destination: net.IPNet{IP: destination.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)},
+			Gateway:     net.ParseIP(fields[2]).To4(), // nil for On-link
+			Metric:      metric,
+			Interface:   fields[3],
+		}
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+// interfaceNamesByAddress maps each interface address to the interface name
+func interfaceNamesByAddress() map[string]string {
+	names := make(map[string]string)
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return names
+	}
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				names[ipNet.IP.String()] = iface.Name
+			}
+		}
 	}
+	return names
+}
 
-	// TODO: Implement parsing of netstat output
+// routeCommandScope returns the route.exe arguments that set the metric and scope the route to an interface
+func routeCommandScope(options types.RouteOptions) ([]string, error) {
+	metric := options.Metric
+	if metric == 0 {
+		metric = 1
+	}
+	args := []string{"metric", strconv.Itoa(metric)}
 
-	return nil, nil
+	if options.Interface != "" {
+		iface, err := net.InterfaceByName(options.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("unknown interface %s: %w", options.Interface, err)
+		}
+		args = append(args, "IF", strconv.Itoa(iface.Index))
+	}
+	return args, nil
 }
 
-func (rm *WindowsRouteManager) FlushRoutes(gateway net.IP) error {
-	routes, err := rm.ListSystemRoutes()
+func (rm *WindowsRouteManager) FlushRoutes(ctx context.Context, gateway net.IP) error {
+	routes, err := rm.ListSystemRoutes(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list routes: %w", err)
 	}
@@ -110,68 +232,56 @@ func (rm *WindowsRouteManager) FlushRoutes(gateway net.IP) error {
 		}
 	}
 
-	return rm.BatchDeleteRoutes(routesToDelete, nil)
+	result, err := rm.BatchDeleteRoutes(ctx, routesToDelete, nil)
+	if err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+// Metrics returns the route operation metrics, including retries
+func (rm *WindowsRouteManager) Metrics() *metrics.Metrics {
+	return rm.metrics
 }
 
 func (rm *WindowsRouteManager) Close() error {
 	return nil
 }
 
-func (rm *WindowsRouteManager) addRouteWithRetry(network *net.IPNet, gateway net.IP) error {
-	var lastErr error
-	start := time.Now()
-
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.addRouteDirect(network, gateway)
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
-		}
-
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
-		}
-
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
-	}
-
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+func (rm *WindowsRouteManager) addRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "add", network, gateway, func(ctx context.Context) error {
+		return rm.addRouteDirect(ctx, network, gateway, options)
+	}, log, rm.metrics)
 }
 
-func (rm *WindowsRouteManager) deleteRouteWithRetry(network *net.IPNet, gateway net.IP) error {
-	var lastErr error
-	start := time.Now()
-
-	for attempt := 0; attempt < rm.maxRetries; attempt++ {
-		err := rm.deleteRouteDirect(network, gateway)
-		if err == nil {
-			rm.metrics.RecordOperation(time.Since(start), true)
-			return nil
-		}
+func (rm *WindowsRouteManager) deleteRouteWithRetry(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions, log *logger.Logger) error {
+	return rm.retryPolicy.Do(ctx, "delete", network, gateway, func(ctx context.Context) error {
+		return rm.deleteRouteDirect(ctx, network, gateway, options)
+	}, log, rm.metrics)
+}
 
-		if routeErr, ok := err.(*types.RouteOperationError); ok && !routeErr.IsRetryable() {
-			rm.metrics.RecordOperation(time.Since(start), false)
-			return err
-		}
+func (rm *WindowsRouteManager) addRouteDirect(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
 
-		lastErr = err
-		time.Sleep(time.Duration(attempt+1) * time.Second)
+	scope, err := routeCommandScope(options)
+	if err != nil {
+		return &types.RouteOperationError{ErrorType: types.RouteErrInvalidRoute, Destination: *network, Gateway: gateway, Cause: err}
 	}
 
-	rm.metrics.RecordOperation(time.Since(start), false)
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
-}
-
-func (rm *WindowsRouteManager) addRouteDirect(network *net.IPNet, gateway net.IP) error {
-	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
+	// route.exe treats gateway 0.0.0.0 on an interface as an on-link (interface) route
+	nextHop := net.IPv4zero.String()
+	if gateway != nil {
+		nextHop = gateway.String()
+	}
 
 	ones, _ := network.Mask.Size()
-	cmd := exec.Command("route", "add", network.IP.String(), "mask", net.IP(network.Mask).String(), gateway.String(), "metric", "1")
+	args := append([]string{"add", network.IP.String(), "mask", net.IP(network.Mask).String(), nextHop}, scope...)
+	cmd := exec.CommandContext(ctx, "route", args...)
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			switch exitErr.ExitCode() {
 			case 1:
@@ -189,12 +299,21 @@ func (rm *WindowsRouteManager) addRouteDirect(network *net.IPNet, gateway net.IP
 	return nil
 }
 
-func (rm *WindowsRouteManager) deleteRouteDirect(network *net.IPNet, gateway net.IP) error {
-	rm.mutex.Lock()
-	defer rm.mutex.Unlock()
+func (rm *WindowsRouteManager) deleteRouteDirect(ctx context.Context, network *net.IPNet, gateway net.IP, options types.RouteOptions) error {
+	ctx, cancel := withRouteTimeout(ctx, rm.routeTimeout)
+	defer cancel()
 
-	cmd := exec.Command("route", "delete", network.IP.String(), "mask", net.IP(network.Mask).String())
+	args := []string{"delete", network.IP.String(), "mask", net.IP(network.Mask).String()}
+	if options.Interface != "" {
+		if iface, err := net.InterfaceByName(options.Interface); err == nil {
+			args = append(args, "IF", strconv.Itoa(iface.Index))
+		}
+	}
+	cmd := exec.CommandContext(ctx, "route", args...)
 	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return types.NewContextError(ctx.Err(), network, gateway)
+		}
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if exitErr.ExitCode() == 1 {
 				return nil
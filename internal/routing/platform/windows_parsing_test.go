@@ -0,0 +1,54 @@
+//go:build windows
+
+package platform
+
+import (
+	"testing"
+)
+
+func TestParseNetstatOutputWindows(t *testing.T) {
+	netstatOutput := `===========================================================================
+Interface List
+  6...00 1c 42 a5 8b 3e ......Parallels VirtIO Ethernet Adapter
+  1...........................Software Loopback Interface 1
+===========================================================================
+
+IPv4 Route Table
+===========================================================================
+Active Routes:
+Network Destination        Netmask          Gateway       Interface  Metric
+          0.0.0.0          0.0.0.0      10.211.55.1      10.211.55.9     15
+      10.211.55.0    255.255.255.0         On-link       10.211.55.9    271
+        127.0.0.0        255.0.0.0         On-link         127.0.0.1    331
+===========================================================================
+Persistent Routes:
+  Network Address          Netmask  Gateway Address  Metric
+          1.0.1.0    255.255.255.0      10.211.55.1       1
+===========================================================================
+`
+
+	routes := parseNetstatOutputWindows(netstatOutput)
+	if len(routes) != 3 {
+		t.Fatalf("Expected 3 active routes, got %d", len(routes))
+	}
+
+	tests := []struct {
+		destination string
+		gateway     string
+		iface       string
+		metric      int
+	}{
+		{"0.0.0.0/0", "10.211.55.1", "10.211.55.9", 15},
+		{"10.211.55.0/24", "<nil>", "10.211.55.9", 271},
+		{"127.0.0.0/8", "<nil>", "127.0.0.1", 331},
+	}
+	for i, tt := range tests {
+		route := routes[i]
+		if route.Destination.String() != tt.destination || route.Gateway.String() != tt.gateway ||
+			route.Interface != tt.iface || route.Metric != tt.metric {
+			t.Errorf("Route %d: expected %s via %s on %s metric %d, got %s via %s on %s metric %d", i,
+				tt.destination, tt.gateway, tt.iface, tt.metric,
+				route.Destination.String(), route.Gateway.String(), route.Interface, route.Metric)
+		}
+	}
+}
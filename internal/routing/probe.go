@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+const (
+	// neighborPollInterval is how often the neighbor table is checked while address resolution is in progress
+	neighborPollInterval = 50 * time.Millisecond
+	// discardPort receives the datagram that triggers address resolution (RFC 863)
+	discardPort = 9
+)
+
+// ErrGatewayUnreachable reports that the physical gateway did not answer the reachability probe
+var ErrGatewayUnreachable = errors.New("gateway unreachable")
+
+// errNeighborUnsupported is returned by neighbor lookups for address families the platform lookup cannot check
+var errNeighborUnsupported = errors.New("neighbor lookup not supported")
+
+// GatewayProber confirms that the physical gateway answers before routes are pointed at it,
+// so that a guessed or vanished gateway does not blackhole the managed networks.
+// A gateway is reachable once the neighbor table holds its link-layer address and,
+// if ICMP probing is enabled, it answers an echo request.
+type GatewayProber struct {
+	timeout time.Duration
+	icmp    bool
+	policy  batch.RetryPolicy
+	lookup  func(ctx context.Context, ip net.IP) (bool, error)
+	echo    func(ctx context.Context, ip net.IP) error
+	logger  *logger.Logger
+}
+
+// NewGatewayProber creates a prober that retries with the configured backoff
+func NewGatewayProber(cfg *config.Config, log *logger.Logger) *GatewayProber {
+	policy := batch.NewRetryPolicy(cfg)
+	policy.MaxAttempts = max(cfg.GatewayProbeAttempts, 1)
+
+	return &GatewayProber{
+		timeout: cfg.GatewayProbeTimeout,
+		icmp:    cfg.GatewayProbeICMP,
+		policy:  policy,
+		lookup:  lookupNeighbor,
+		echo:    icmpEcho,
+		logger:  log,
+	}
+}
+
+// Probe checks that the uplink's gateway is reachable, retrying with backoff.
+// A gateway-less uplink has no next hop to probe; it only needs its interface to be up.
+// Failures wrap ErrGatewayUnreachable.
+func (p *GatewayProber) Probe(ctx context.Context, uplink types.Uplink) error {
+	if !uplink.HasGateway() {
+		iface, err := net.InterfaceByName(uplink.Interface)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrGatewayUnreachable, err)
+		}
+		if iface.Flags&net.FlagUp == 0 {
+			return fmt.Errorf("%w: interface %s is down", ErrGatewayUnreachable, uplink.Interface)
+		}
+		return nil
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= p.policy.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			delay := p.policy.Delay(attempt - 1)
+			p.logger.Debug("Retrying gateway probe",
+				"gateway", uplink.Gateway.String(),
+				"attempt", attempt,
+				"delay", delay,
+				"error", lastErr)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		lastErr = p.probeOnce(ctx, uplink.Gateway)
+		if lastErr == nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: %s did not answer %d probes: %w",
+		ErrGatewayUnreachable, uplink.Gateway, p.policy.MaxAttempts, lastErr)
+}
+
+// probeOnce runs a single probe bounded by the probe timeout
+func (p *GatewayProber) probeOnce(ctx context.Context, gateway net.IP) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	if err := p.resolveNeighbor(ctx, gateway); err != nil {
+		return err
+	}
+	if p.icmp {
+		return p.echo(ctx, gateway)
+	}
+	return nil
+}
+
+// resolveNeighbor sends a datagram to gateway to trigger address resolution and
+// waits for its neighbor entry to appear
+func (p *GatewayProber) resolveNeighbor(ctx context.Context, gateway net.IP) error {
+	for triggered := false; ; triggered = true {
+		resolved, err := p.lookup(ctx, gateway)
+		if errors.Is(err, errNeighborUnsupported) {
+			p.logger.Debug("Skipping neighbor lookup", "gateway", gateway.String(), "error", err)
+			return nil
+		}
+		if err != nil || resolved {
+			return err
+		}
+
+		if !triggered {
+			triggerResolution(gateway)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no neighbor entry for %s", gateway)
+		case <-time.After(neighborPollInterval):
+		}
+	}
+}
+
+// triggerResolution sends an empty datagram to the discard port of ip. The datagram itself
+// is irrelevant; sending it makes the kernel resolve the link-layer address of ip.
+func triggerResolution(ip net.IP) {
+	conn, err := net.Dial("udp", net.JoinHostPort(ip.String(), strconv.Itoa(discardPort)))
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = conn.Write(nil)
+}
@@ -0,0 +1,136 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// testProber returns a prober whose neighbor lookup reports resolved from the given attempt on
+// (never if resolvedFrom is 0), recording how many lookups were made
+func testProber(resolvedFrom int, lookups *int) *GatewayProber {
+	return &GatewayProber{
+		timeout: 20 * time.Millisecond,
+		policy: batch.RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			MaxDelay:    time.Millisecond,
+		},
+		lookup: func(ctx context.Context, ip net.IP) (bool, error) {
+			*lookups++
+			return resolvedFrom > 0 && *lookups >= resolvedFrom, nil
+		},
+		echo:   func(context.Context, net.IP) error { return nil },
+		logger: logger.New("error"),
+	}
+}
+
+func TestGatewayProberProbe(t *testing.T) {
+	// 127.0.0.1 keeps the resolution trigger local
+	uplink := types.Uplink{Gateway: net.ParseIP("127.0.0.1")}
+
+	t.Run("resolved", func(t *testing.T) {
+		var lookups int
+		if err := testProber(1, &lookups).Probe(context.Background(), uplink); err != nil {
+			t.Fatalf("Probe failed: %v", err)
+		}
+		if lookups != 1 {
+			t.Errorf("Expected 1 lookup, got %d", lookups)
+		}
+	})
+
+	t.Run("resolves while polling", func(t *testing.T) {
+		var lookups int
+		if err := testProber(2, &lookups).Probe(context.Background(), uplink); err != nil {
+			t.Fatalf("Probe failed: %v", err)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		var lookups int
+		err := testProber(0, &lookups).Probe(context.Background(), uplink)
+		if !errors.Is(err, ErrGatewayUnreachable) {
+			t.Fatalf("Expected ErrGatewayUnreachable, got %v", err)
+		}
+		if lookups < 3 {
+			t.Errorf("Expected lookups from 3 attempts, got %d", lookups)
+		}
+	})
+
+	t.Run("echo required", func(t *testing.T) {
+		var lookups int
+		p := testProber(1, &lookups)
+		p.icmp = true
+		p.echo = func(context.Context, net.IP) error { return errors.New("timeout") }
+		if err := p.Probe(context.Background(), uplink); !errors.Is(err, ErrGatewayUnreachable) {
+			t.Fatalf("Expected ErrGatewayUnreachable, got %v", err)
+		}
+	})
+
+	t.Run("neighbor lookup unsupported", func(t *testing.T) {
+		p := testProber(0, new(int))
+		p.lookup = func(context.Context, net.IP) (bool, error) { return false, errNeighborUnsupported }
+		if err := p.Probe(context.Background(), uplink); err != nil {
+			t.Fatalf("Probe failed: %v", err)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := testProber(0, new(int)).Probe(ctx, uplink); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestSetupRoutesKeepsVPNPathWhenGatewayUnreachable(t *testing.T) {
+	ctx := context.Background()
+	ipSet := config.NewIPSet()
+	ipSet.Add(&mustRoute(t, "1.0.1.0/24", "0.0.0.0").Destination)
+
+	rm, err := platform.NewRouteManager("fake", config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create fake backend: %v", err)
+	}
+	rs, _ := NewRouteSwitch(rm, ipSet, logger.New("error"))
+
+	uplink := types.Uplink{Gateway: net.ParseIP("127.0.0.1")}
+	if _, err := rs.SetupRoutes(ctx, uplink); err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	rs.SetGatewayProber(testProber(0, new(int)))
+	if _, err := rs.SetupRoutes(ctx, uplink); !errors.Is(err, ErrGatewayUnreachable) {
+		t.Fatalf("Expected ErrGatewayUnreachable, got %v", err)
+	}
+	if routes, _ := rm.ListSystemRoutes(ctx); len(routes) != 0 {
+		t.Errorf("Expected managed routes to be removed, got %v", routes)
+	}
+}
+
+func TestICMPEchoMessage(t *testing.T) {
+	msg := icmpEchoMessage(0x1234, 0x0001)
+	if msg[0] != icmpEchoRequest || msg[4] != 0x12 || msg[5] != 0x34 || msg[7] != 0x01 {
+		t.Errorf("Unexpected echo header % x", msg[:8])
+	}
+	if icmpChecksum(msg) != 0 {
+		t.Error("Expected the checksum of a complete message to verify to zero")
+	}
+
+	reply := append([]byte{icmpEchoReply}, msg[1:]...)
+	if !isEchoReply(reply, 0x1234, 0x0001) {
+		t.Error("Expected the reply to match its request")
+	}
+	if isEchoReply(reply, 0x1234, 0x0002) {
+		t.Error("Expected a reply with another sequence number not to match")
+	}
+}
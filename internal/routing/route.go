@@ -4,13 +4,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/wesleywu/smart-route/internal/config"
 	"github.com/wesleywu/smart-route/internal/routing/types"
 	"github.com/wesleywu/smart-route/internal/routing/platform"
 )
 
 // NewPlatformRouteManager creates a platform-specific route manager instance
-func NewPlatformRouteManager(concurrencyLimit int, maxRetries int) (types.RouteManager, error) {
-	return platform.NewPlatformRouteManager(concurrencyLimit, maxRetries)
+func NewPlatformRouteManager(cfg *config.Config) (types.RouteManager, error) {
+	return platform.NewPlatformRouteManager(cfg)
 }
 
 // RouteManagerMetrics collects performance and operational statistics
@@ -51,6 +51,9 @@ func TestRouteErrorTypeString(t *testing.T) {
 		{types.RouteErrSystemCall, "SystemCall"},
 		{types.RouteErrTimeout, "Timeout"},
 		{types.RouteErrNotFound, "NotFound"},
+		{types.RouteErrCanceled, "Canceled"},
+		{types.RouteErrExists, "Exists"},
+		{types.RouteErrGatewayUnreachable, "GatewayUnreachable"},
 	}
 	
 	for _, tt := range tests {
@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// NetworkState is a snapshot of the routing state the monitor derives its events from
+type NetworkState struct {
+	PhysicalGateway   net.IP         `json:"physical_gateway,omitempty"` // nil for a gateway-less point-to-point uplink
+	PhysicalInterface string         `json:"physical_interface"`
+	DefaultGateway    net.IP         `json:"default_gateway,omitempty"` // Current system default route, into the VPN while it is connected
+	DefaultInterface  string         `json:"default_interface"`
+	Uplinks           []types.Uplink `json:"uplinks,omitempty"` // Every physical uplink, most preferred first; nil if not known
+}
+
+// EventSink receives the signals of event sources. Both methods may be called from any goroutine.
+type EventSink interface {
+	// Trigger asks the monitor to query the route manager for the current state
+	Trigger(source string)
+	// Observe reports a state directly, without querying the route manager
+	Observe(source string, state NetworkState)
+}
+
+// EventSource tells the monitor when the network may have changed. Sources that only know that
+// "something changed" call Trigger; sources that know the resulting state call Observe. The monitor
+// serializes both and emits events only for actual transitions, so sources may overlap freely.
+type EventSource interface {
+	// Name identifies the source in logs and status
+	Name() string
+	// Run feeds sink until ctx is cancelled. An error means the source gave up.
+	Run(ctx context.Context, sink EventSink) error
+}
+
+// PollSource triggers a state check at a fixed interval. It needs no platform support and
+// is the fallback when the platform source fails.
+type PollSource struct {
+	Interval time.Duration
+}
+
+// Name implements EventSource
+func (s *PollSource) Name() string {
+	return "poll"
+}
+
+// Run implements EventSource
+func (s *PollSource) Run(ctx context.Context, sink EventSink) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			sink.Trigger(s.Name())
+		}
+	}
+}
+
+// ScriptStep is one state reported by a ScriptedSource
+type ScriptStep struct {
+	Delay time.Duration // Wait before reporting the state, counted from the previous step
+	State NetworkState
+}
+
+// ScriptedSource reports a fixed sequence of states, e.g. to drive the monitor in tests
+// without root privileges or a real network
+type ScriptedSource struct {
+	Steps []ScriptStep
+	// Done is closed once every step has been reported, if set
+	Done chan struct{}
+}
+
+// Name implements EventSource
+func (s *ScriptedSource) Name() string {
+	return "script"
+}
+
+// Run implements EventSource
+func (s *ScriptedSource) Run(ctx context.Context, sink EventSink) error {
+	for _, step := range s.Steps {
+		timer := time.NewTimer(step.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		sink.Observe(s.Name(), step.State)
+	}
+	if s.Done != nil {
+		close(s.Done)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// externalSignal is a trigger or an observed state handed to an ExternalSource
+type externalSignal struct {
+	state *NetworkState // nil for a trigger
+}
+
+// ExternalSource forwards signals from outside the daemon, e.g. a VPN client hook calling the
+// control API, so that a change is picked up without waiting for the platform source or polling
+type ExternalSource struct {
+	signals chan externalSignal
+}
+
+// NewExternalSource creates an external source
+func NewExternalSource() *ExternalSource {
+	return &ExternalSource{signals: make(chan externalSignal, 16)}
+}
+
+// Name implements EventSource
+func (s *ExternalSource) Name() string {
+	return "external"
+}
+
+// Run implements EventSource
+func (s *ExternalSource) Run(ctx context.Context, sink EventSink) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case signal := <-s.signals:
+			if signal.state == nil {
+				sink.Trigger(s.Name())
+			} else {
+				sink.Observe(s.Name(), *signal.state)
+			}
+		}
+	}
+}
+
+// Trigger asks the monitor to check the current state. It never blocks; a trigger is dropped
+// when the queue is full, since the monitor is still catching up with earlier signals then.
+func (s *ExternalSource) Trigger() {
+	select {
+	case s.signals <- externalSignal{}:
+	default:
+	}
+}
+
+// Observe reports a state, waiting until it is queued or ctx is done
+func (s *ExternalSource) Observe(ctx context.Context, state NetworkState) error {
+	select {
+	case s.signals <- externalSignal{state: &state}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
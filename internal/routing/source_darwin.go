@@ -0,0 +1,21 @@
+package routing
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// platformSources returns the event sources for real-time route change notifications on macOS
+func platformSources() []EventSource {
+	return []EventSource{&socketSource{name: "route_socket", open: openRouteSocket}}
+}
+
+// openRouteSocket opens a route socket, which receives a message for every route and address change
+func openRouteSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_ROUTE, unix.SOCK_RAW, unix.AF_UNSPEC)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create route socket: %w", err)
+	}
+	return fd, nil
+}
@@ -0,0 +1,30 @@
+package routing
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlinkGroups are the rtnetlink multicast groups that signal link, address and route changes
+const netlinkGroups = unix.RTMGRP_LINK |
+	unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV4_ROUTE |
+	unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV6_ROUTE
+
+// platformSources returns the event sources for real-time route change notifications on Linux
+func platformSources() []EventSource {
+	return []EventSource{&socketSource{name: "netlink", open: openNetlinkSocket}}
+}
+
+// openNetlinkSocket opens an rtnetlink socket subscribed to link, address and route changes
+func openNetlinkSocket() (int, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create netlink socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: netlinkGroups}); err != nil {
+		unix.Close(fd)
+		return -1, fmt.Errorf("failed to subscribe to netlink route changes: %w", err)
+	}
+	return fd, nil
+}
@@ -0,0 +1,8 @@
+//go:build !darwin && !linux
+
+package routing
+
+// platformSources returns no real-time source; the monitor polls instead
+func platformSources() []EventSource {
+	return nil
+}
@@ -0,0 +1,82 @@
+//go:build darwin || linux
+
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// maxSocketErrors is how many consecutive read errors make a socket source give up
+	maxSocketErrors = 5
+	// readTimeout bounds how long a socket source takes to notice cancellation
+	readTimeout = time.Second
+)
+
+// socketSource triggers a state check for every message on a kernel routing socket:
+// the BSD route socket on macOS, rtnetlink on Linux
+type socketSource struct {
+	name string
+	open func() (int, error)
+}
+
+// Name implements EventSource
+func (s *socketSource) Name() string {
+	return s.name
+}
+
+// Run implements EventSource
+func (s *socketSource) Run(ctx context.Context, sink EventSink) error {
+	fd, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	// Closing the socket does not interrupt a blocked read on every platform,
+	// so reads time out regularly to notice cancellation
+	timeout := unix.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		return fmt.Errorf("%s: failed to set read timeout: %w", s.name, err)
+	}
+
+	buffer := make([]byte, 8192)
+	failures := 0
+	for {
+		n, err := unix.Read(fd, buffer)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if !isSocketError(err) {
+				continue
+			}
+			failures++
+			if failures >= maxSocketErrors {
+				return fmt.Errorf("%s: %d consecutive read errors: %w", s.name, failures, err)
+			}
+			// Short delay before retrying to avoid busy waiting
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		failures = 0
+		if n > 0 {
+			sink.Trigger(s.name)
+		}
+	}
+}
+
+// isSocketError reports whether a read error is serious; temporary errors are ignored
+func isSocketError(err error) bool {
+	return err != unix.EAGAIN &&
+		err != unix.EWOULDBLOCK &&
+		err != unix.EINTR &&
+		err != unix.ECONNRESET &&
+		err != unix.EPIPE &&
+		err != unix.ENOBUFS // rtnetlink overrun; messages were lost, but the next check reads the full state
+}
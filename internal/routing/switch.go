@@ -1,12 +1,15 @@
 package routing
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"time"
 
 	"github.com/wesleywu/smart-route/internal/config"
 	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
 	"github.com/wesleywu/smart-route/internal/routing/types"
 	"github.com/wesleywu/smart-route/internal/utils"
 )
@@ -15,7 +18,54 @@ import (
 type RouteSwitch struct {
 	rm           types.RouteManager
 	managedIPSet *config.IPSet
+	knownIPSet   *config.IPSet     // every network any managed set may hold, nil if it is managedIPSet
+	prober       *GatewayProber    // nil disables gateway probing
+	retryPolicy  batch.RetryPolicy // retries failed batch routes, none by default
 	logger       *logger.Logger
+
+	// Multiple uplinks: lists routed through specific uplinks, and the uplinks available to them
+	assignments         []UplinkAssignment
+	assignedIPSet       *config.IPSet   // every network of the uplink lists, nil without lists
+	uplinks             []types.Uplink  // available physical uplinks, most preferred first
+	uplinkListsDisabled bool            // the uplink lists are left without routes
+	failedOver          map[string]bool // uplink lists routed through another than their preferred uplink
+}
+
+// UplinkAssignment routes the networks of a named list through the first of its preferred
+// uplinks that is available, and through the primary uplink if none is
+type UplinkAssignment struct {
+	Name       string
+	IPSet      *config.IPSet
+	Interfaces []string // preferred uplink interfaces, most preferred first
+}
+
+// LoadUplinkAssignments loads the route files of uplink lists
+func LoadUplinkAssignments(lists []config.UplinkList) ([]UplinkAssignment, error) {
+	if err := config.ValidateUplinkLists(lists); err != nil {
+		return nil, err
+	}
+	assignments := make([]UplinkAssignment, 0, len(lists))
+	for _, list := range lists {
+		ipSet, err := config.LoadChnRoutes(list.RouteFile)
+		if err != nil {
+			return nil, fmt.Errorf("uplink list %q: %w", list.Name, err)
+		}
+		assignments = append(assignments, UplinkAssignment{Name: list.Name, IPSet: ipSet, Interfaces: list.Uplinks})
+	}
+	return assignments, nil
+}
+
+// ListUplinks returns every active physical uplink of the route manager, most preferred first.
+// Route managers that cannot enumerate uplinks report their physical gateway as the only one.
+func ListUplinks(ctx context.Context, rm types.RouteManager) ([]types.Uplink, error) {
+	if lister, ok := rm.(types.UplinkLister); ok {
+		return lister.ListPhysicalUplinks(ctx)
+	}
+	gateway, iface, err := rm.GetPhysicalGateway(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []types.Uplink{{Gateway: gateway, Interface: iface}}, nil
 }
 
 // NewRouteSwitch creates a new route switch handler
@@ -27,11 +77,273 @@ func NewRouteSwitch(rm types.RouteManager, managedIPSet *config.IPSet, logger *l
 	}, nil
 }
 
+// SetManagedIPSet changes the networks routes are set up for. Routes to networks that were
+// managed before are only cleaned up if they are in the known set. It must not be called
+// while a route operation is running.
+func (rs *RouteSwitch) SetManagedIPSet(managedIPSet *config.IPSet) {
+	rs.managedIPSet = managedIPSet
+}
+
+// SetKnownIPSet sets every network a managed set may hold. Installed routes to any of them
+// count as managed routes, so switching between managed sets leaves no stale route behind.
+func (rs *RouteSwitch) SetKnownIPSet(knownIPSet *config.IPSet) {
+	rs.knownIPSet = knownIPSet
+}
+
+// SetUplinkAssignments sets the lists routed through specific uplinks. Their networks are
+// managed networks too, the lists take precedence over the managed set.
+func (rs *RouteSwitch) SetUplinkAssignments(assignments []UplinkAssignment) {
+	rs.assignments = assignments
+	rs.assignedIPSet = nil
+	if len(assignments) > 0 {
+		rs.assignedIPSet = config.NewIPSet()
+		for _, assignment := range assignments {
+			rs.assignedIPSet.Merge(assignment.IPSet)
+		}
+	}
+}
+
+// SetUplinks sets the available physical uplinks, most preferred first, which the uplink lists
+// choose from and routes fail over to. It must not be called while a route operation is running.
+func (rs *RouteSwitch) SetUplinks(uplinks []types.Uplink) {
+	rs.uplinks = uplinks
+}
+
+// SetUplinkListsEnabled sets whether routes are set up for the uplink lists, e.g. to leave them to
+// the VPN on a network where split routing is disabled. It must not be called while a route
+// operation is running.
+func (rs *RouteSwitch) SetUplinkListsEnabled(enabled bool) {
+	rs.uplinkListsDisabled = !enabled
+}
+
+// cleanupIPSets returns the networks whose installed routes are managed by the route switch
+func (rs *RouteSwitch) cleanupIPSets() []*config.IPSet {
+	scope := rs.managedIPSet
+	if rs.knownIPSet != nil {
+		scope = rs.knownIPSet
+	}
+	if rs.assignedIPSet != nil {
+		return []*config.IPSet{scope, rs.assignedIPSet}
+	}
+	return []*config.IPSet{scope}
+}
+
+// SetGatewayProber makes route setup and drift repair confirm that the physical gateway answers
+// before routing through it
+func (rs *RouteSwitch) SetGatewayProber(prober *GatewayProber) {
+	rs.prober = prober
+}
+
+// SetRetryPolicy makes batch operations retry the routes that failed with an error the policy
+// considers retryable and that the route manager did not already retry
+func (rs *RouteSwitch) SetRetryPolicy(policy batch.RetryPolicy) {
+	rs.retryPolicy = policy
+}
+
+// ensureReachable probes the uplink's gateway and returns the uplink to route through. If it does
+// not answer, routes fail over to the next available uplink that does. If none does, the managed
+// routes are removed so the managed networks stay on the VPN, and the probe error is returned.
+func (rs *RouteSwitch) ensureReachable(ctx context.Context, uplink types.Uplink) (types.Uplink, error) {
+	if rs.prober == nil {
+		return uplink, nil
+	}
+
+	err := rs.prober.Probe(ctx, uplink)
+	if err == nil || !errors.Is(err, ErrGatewayUnreachable) {
+		return uplink, err
+	}
+
+	for _, next := range rs.uplinks {
+		if next.Equal(uplink) || next.Validate() != nil {
+			continue
+		}
+		if rs.prober.Probe(ctx, next) == nil {
+			rs.logger.Warn("Gateway unreachable, failing over to the next uplink",
+				"gateway", uplink.String(),
+				"interface", uplink.Interface,
+				"next_gateway", next.String(),
+				"next_interface", next.Interface,
+				"error", err)
+			return next, nil
+		}
+	}
+
+	rs.logger.Warn("Gateway unreachable, keeping VPN path",
+		"gateway", uplink.String(),
+		"interface", uplink.Interface,
+		"error", err)
+	if _, cleanErr := rs.CleanRoutes(ctx); cleanErr != nil {
+		return uplink, fmt.Errorf("%w (route cleanup failed: %w)", err, cleanErr)
+	}
+	return uplink, err
+}
+
+// assignedUplinks picks the uplink of every uplink list: the first of its preferred interfaces
+// that is available and whose gateway answers, or else primary
+func (rs *RouteSwitch) assignedUplinks(ctx context.Context, primary types.Uplink) map[string]types.Uplink {
+	if len(rs.assignments) == 0 || rs.uplinkListsDisabled {
+		return nil
+	}
+
+	available := make(map[string]types.Uplink, len(rs.uplinks))
+	for _, uplink := range rs.uplinks {
+		if _, ok := available[uplink.Interface]; !ok && uplink.Validate() == nil {
+			available[uplink.Interface] = uplink
+		}
+	}
+
+	answers := make(map[string]bool) // probe results by interface
+	answering := func(uplink types.Uplink) bool {
+		if rs.prober == nil || uplink.Equal(primary) {
+			return true // the primary uplink was probed already
+		}
+		if answered, ok := answers[uplink.Interface]; ok {
+			return answered
+		}
+		err := rs.prober.Probe(ctx, uplink)
+		answers[uplink.Interface] = !errors.Is(err, ErrGatewayUnreachable)
+		return answers[uplink.Interface]
+	}
+
+	assigned := make(map[string]types.Uplink, len(rs.assignments))
+	for _, assignment := range rs.assignments {
+		uplink := primary
+		for _, iface := range assignment.Interfaces {
+			if candidate, ok := available[iface]; ok && answering(candidate) {
+				uplink = candidate
+				break
+			}
+		}
+		rs.logFailover(assignment, uplink)
+		assigned[assignment.Name] = uplink
+	}
+	return assigned
+}
+
+// logFailover logs when an uplink list fails over from its preferred uplink, or returns to it
+func (rs *RouteSwitch) logFailover(assignment UplinkAssignment, uplink types.Uplink) {
+	if len(assignment.Interfaces) == 0 {
+		return
+	}
+	failedOver := uplink.Interface != assignment.Interfaces[0]
+	if failedOver == rs.failedOver[assignment.Name] {
+		return
+	}
+	if rs.failedOver == nil {
+		rs.failedOver = make(map[string]bool)
+	}
+	rs.failedOver[assignment.Name] = failedOver
+
+	if failedOver {
+		rs.logger.Warn("Preferred uplink unavailable, failing over",
+			"list", assignment.Name,
+			"preferred_interface", assignment.Interfaces[0],
+			"gateway", uplink.String(),
+			"interface", uplink.Interface)
+	} else {
+		rs.logger.Info("Preferred uplink available again",
+			"list", assignment.Name,
+			"gateway", uplink.String(),
+			"interface", uplink.Interface)
+	}
+}
+
+// plannedRoutes returns the wanted managed routes: the managed networks through primary and the
+// networks of every uplink list through its assigned uplink, except the withdrawn networks
+func (rs *RouteSwitch) plannedRoutes(primary types.Uplink, assigned map[string]types.Uplink, withdrawn map[string]bool) []*types.Route {
+	routes := buildRoutesFromIPSet(rs.managedIPSet, primary, withdrawn)
+	if len(assigned) == 0 {
+		return routes
+	}
+
+	// A network on an uplink list goes through the list's uplink, the last list holding it wins
+	index := make(map[string]int, len(routes))
+	for i, route := range routes {
+		index[route.Destination.String()] = i
+	}
+	for _, assignment := range rs.assignments {
+		uplink, ok := assigned[assignment.Name]
+		if !ok {
+			continue
+		}
+		for _, route := range buildRoutesFromIPSet(assignment.IPSet, uplink, withdrawn) {
+			if i, ok := index[route.Destination.String()]; ok {
+				routes[i] = route
+				continue
+			}
+			index[route.Destination.String()] = len(routes)
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// DesiredState describes the managed route state the daemon wants to converge to
+type DesiredState struct {
+	VPNConnected      bool
+	PhysicalGateway   net.IP
+	PhysicalInterface string
+	VPNInterface      string
+	Profile           string         // network profile selecting the managed networks, empty for the default
+	Uplinks           []types.Uplink // every available physical uplink, most preferred first; nil without uplink lists
+
+	// Direct path health: managed routes are withdrawn so that traffic to them stays on the VPN
+	DirectPathDegraded bool        // The direct path is degraded as a whole, no managed routes are wanted
+	Withdrawn          []net.IPNet // Managed networks whose direct path is degraded
+}
+
+// Equal reports whether two desired states would result in the same managed routes
+func (ds DesiredState) Equal(other DesiredState) bool {
+	if ds.VPNConnected != other.VPNConnected {
+		return false
+	}
+	if !ds.VPNConnected {
+		// Without VPN the desired state is always "no managed routes"
+		return true
+	}
+	return ds.PhysicalGateway.Equal(other.PhysicalGateway) && ds.PhysicalInterface == other.PhysicalInterface &&
+		ds.Profile == other.Profile && types.SameUplinks(ds.Uplinks, other.Uplinks) && ds.DirectPathDegraded == other.DirectPathDegraded && sameNetworks(ds.Withdrawn, other.Withdrawn)
+}
+
+// RoutesWanted reports whether managed routes should be installed: the VPN is connected
+// and the direct path has not been withdrawn as a whole
+func (ds DesiredState) RoutesWanted() bool {
+	return ds.VPNConnected && !ds.DirectPathDegraded
+}
+
+// sameNetworks reports whether a and b hold the same networks, in any order
+func sameNetworks(a, b []net.IPNet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := networkSet(a)
+	for _, network := range b {
+		if !set[network.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// networkSet indexes networks by their CIDR notation
+func networkSet(networks []net.IPNet) map[string]bool {
+	set := make(map[string]bool, len(networks))
+	for _, network := range networks {
+		set[network.String()] = true
+	}
+	return set
+}
+
+// Uplink returns the physical uplink managed routes should use
+func (ds DesiredState) Uplink() types.Uplink {
+	return types.Uplink{Gateway: ds.PhysicalGateway, Interface: ds.PhysicalInterface}
+}
+
 // InitRoutes sets up initial routes only if VPN is already connected, or clean up routes if VPN is not connected
-func (rs *RouteSwitch) InitRoutes() error {
+func (rs *RouteSwitch) InitRoutes(ctx context.Context) error {
 
 	// Check current VPN state - only setup routes if VPN is connected
-	currentGW, currentIface, err := rs.rm.GetSystemDefaultRoute()
+	currentGW, currentIface, err := rs.rm.GetSystemDefaultRoute(ctx)
 	if err != nil {
 		rs.logger.Error("failed to check VPN state during initial setup", "error", err)
 		return fmt.Errorf("failed to check VPN state: %w", err)
@@ -44,7 +356,8 @@ func (rs *RouteSwitch) InitRoutes() error {
 		rs.logger.Info("VPN not connected - skipping route setup",
 			"current_interface", currentIface,
 			"current_gateway", currentGW.String())
-		return rs.CleanRoutes()
+		_, err := rs.CleanRoutes(ctx)
+		return err
 	}
 
 	rs.logger.Info("VPN detected - setting up routes",
@@ -52,92 +365,217 @@ func (rs *RouteSwitch) InitRoutes() error {
 		"physical_gateway", currentGW.String())
 
 	// VPN is connected, use physical gateway for route setup
-	physicalGateway, _, err := rs.rm.GetPhysicalGateway()
+	physicalGateway, physicalIface, err := rs.rm.GetPhysicalGateway(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get physical gateway: %w", err)
 	}
-	return rs.SetupRoutes(physicalGateway)
+	if len(rs.assignments) > 0 {
+		uplinks, err := ListUplinks(ctx, rs.rm)
+		if err != nil {
+			return fmt.Errorf("failed to list physical uplinks: %w", err)
+		}
+		rs.uplinks = uplinks
+	}
+	_, err = rs.SetupRoutes(ctx, types.Uplink{Gateway: physicalGateway, Interface: physicalIface})
+	return err
 }
 
 // SetupRoutes performs complete route reset - used by both one-time and daemon modes
 // This is the unified logic: always cleanup ALL managed routes, then setup for current gateway
-func (rs *RouteSwitch) SetupRoutes(physicalGateway net.IP) error {
-	if physicalGateway == nil {
-		return fmt.Errorf("gateway cannot be nil")
+// The context is checked between phases so that a superseded setup can be abandoned early.
+// An uplink without a gateway gets interface routes (`dev <iface>`) instead of `via <gateway>` routes.
+// Withdrawn managed networks are left to the VPN. The networks of uplink lists go through their own uplinks.
+func (rs *RouteSwitch) SetupRoutes(ctx context.Context, uplink types.Uplink, withdrawn ...net.IPNet) (RouteDiff, error) {
+	if err := uplink.Validate(); err != nil {
+		return RouteDiff{}, err
 	}
+	uplink, err := rs.ensureReachable(ctx, uplink)
+	if err != nil {
+		return RouteDiff{}, err
+	}
+	plannedRoutes := rs.plannedRoutes(uplink, rs.assignedUplinks(ctx, uplink), networkSet(withdrawn))
 
 	rs.logger.Debug("Route reset started",
-		"physical_gateway", physicalGateway.String())
+		"physical_gateway", uplink.String())
+	if !uplink.HasGateway() {
+		rs.logger.Info("Physical uplink has no gateway, using interface routes", "interface", uplink.Interface)
+	}
 
 	// Phase 1: Clean up ALL managed routes (completely gateway-independent)
 	rs.logger.Debug("Phase 1: cleaning up system routes within managed routes")
 
-	systemRoutes, err := rs.rm.ListSystemRoutes()
+	systemRoutes, err := rs.rm.ListSystemRoutes(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch current system routes: %w", err)
+		return RouteDiff{}, fmt.Errorf("failed to fetch current system routes: %w", err)
 	}
 	rs.logger.Debug("Retrieved system routes", "total_count", len(systemRoutes))
 
-	existingRoutes := findMatchingRoute(systemRoutes, rs.managedIPSet)
+	existingRoutes := findMatchingRoute(systemRoutes, rs.cleanupIPSets()...)
+
+	if err := ctx.Err(); err != nil {
+		return RouteDiff{}, fmt.Errorf("route setup interrupted: %w", err)
+	}
+
+	// A backend that can replace routes switches each destination in place,
+	// so traffic never falls back to the VPN between the two phases
+	if rs.capabilities().Replace {
+		if err := rs.replaceRoutes(ctx, existingRoutes, plannedRoutes); err != nil {
+			rs.logger.Error("failed to replace routes for current gateway", "gateway", uplink.String(), "error", err)
+			return RouteDiff{}, fmt.Errorf("failed to replace routes for current gateway: %w", err)
+		}
+		rs.logger.Info("Smart routing configured",
+			"gateway", uplink.String())
+		return RouteDiff{Added: len(plannedRoutes), Removed: len(existingRoutes)}, nil
+	}
 
-	if err := rs.cleanRoutes(existingRoutes); err != nil {
+	if err := rs.cleanRoutes(ctx, existingRoutes); err != nil {
 		rs.logger.Error("failed to cleanup managed routes", "error", err)
-		return fmt.Errorf("failed to cleanup managed routes: %w", err)
+		return RouteDiff{}, fmt.Errorf("failed to cleanup managed routes: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return RouteDiff{}, fmt.Errorf("route setup interrupted: %w", err)
 	}
 
 	// Phase 2: Set up routes for current gateway
 	rs.logger.Debug("Phase 2: setting up routes for current gateway")
 
-	routesToAdd := buildRoutesFromIPSet(rs.managedIPSet, physicalGateway)
+	routesToAdd := plannedRoutes
 
-	if err := rs.addRoutes(routesToAdd); err != nil {
-		rs.logger.Error("failed to setup routes for current gateway", "gateway", physicalGateway.String(), "error", err)
-		return fmt.Errorf("failed to setup routes for current gateway: %w", err)
+	if err := rs.addRoutes(ctx, routesToAdd); err != nil {
+		rs.logger.Error("failed to setup routes for current gateway", "gateway", uplink.String(), "error", err)
+		return RouteDiff{}, fmt.Errorf("failed to setup routes for current gateway: %w", err)
 	}
 
 	rs.logger.Info("Smart routing configured",
-		"gateway", physicalGateway.String())
+		"gateway", uplink.String())
 
-	return nil
+	return RouteDiff{Added: len(routesToAdd), Removed: len(existingRoutes)}, nil
 }
 
 // CleanRoutes cleans up all routes that are managed by the route switch
-func (rs *RouteSwitch) CleanRoutes() error {
+func (rs *RouteSwitch) CleanRoutes(ctx context.Context) (RouteDiff, error) {
 	rs.logger.Debug("Starting complete route cleanup")
 
-	systemRoutes, err := rs.rm.ListSystemRoutes()
+	systemRoutes, err := rs.rm.ListSystemRoutes(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch current system routes: %w", err)
+		return RouteDiff{}, fmt.Errorf("failed to fetch current system routes: %w", err)
 	}
 	rs.logger.Debug("Retrieved system routes", "total_count", len(systemRoutes))
 
-	existingRoutes := findMatchingRoute(systemRoutes, rs.managedIPSet)
+	existingRoutes := findMatchingRoute(systemRoutes, rs.cleanupIPSets()...)
+
+	if err := ctx.Err(); err != nil {
+		return RouteDiff{}, fmt.Errorf("route cleanup interrupted: %w", err)
+	}
+	if err := rs.cleanRoutes(ctx, existingRoutes); err != nil {
+		return RouteDiff{}, err
+	}
+	return RouteDiff{Removed: len(existingRoutes)}, nil
+}
 
-	return rs.cleanRoutes(existingRoutes)
+// RouteDiff counts the managed routes an operation installed and removed.
+// A route switched to another gateway in place counts as both.
+type RouteDiff struct {
+	Added   int
+	Removed int
 }
 
-// addRoutes adds all managed routes for the specified gateway
-func (rs *RouteSwitch) addRoutes(routesToAdd []*types.Route) error {
-	start := time.Now()
+// Changed reports whether any route was touched
+func (d RouteDiff) Changed() bool {
+	return d.Added > 0 || d.Removed > 0
+}
 
-	rs.logger.Debug("Setting up routes", "routes to setup:", len(routesToAdd))
+// DriftReport describes the difference between the desired and the actual managed routes
+type DriftReport struct {
+	Missing  int  // Managed routes that should be installed but are absent
+	Stale    int  // Managed routes that are installed but should not be (or use a wrong gateway)
+	Repaired bool // True if the drift was repaired successfully
+}
+
+// HasDrift returns true if any managed route deviates from the desired state
+func (dr *DriftReport) HasDrift() bool {
+	return dr.Missing > 0 || dr.Stale > 0
+}
 
-	err := rs.rm.BatchAddRoutes(routesToAdd, rs.logger)
-	duration := time.Since(start).Milliseconds()
+// Reconcile compares the managed routes in the system table with the desired state and repairs the difference.
+// When vpnConnected is false the desired state is "no managed routes"; otherwise every managed network
+// should be routed through uplink, except the withdrawn ones and those of uplink lists, which go through their own
+// uplinks. Unlike SetupRoutes, only the deviating routes are touched.
+func (rs *RouteSwitch) Reconcile(ctx context.Context, uplink types.Uplink, vpnConnected bool, withdrawn ...net.IPNet) (*DriftReport, error) {
+	var plannedRoutes []*types.Route
+	if vpnConnected {
+		if err := uplink.Validate(); err != nil {
+			return nil, err
+		}
+		var err error
+		uplink, err = rs.ensureReachable(ctx, uplink)
+		if err != nil {
+			return nil, err
+		}
+		plannedRoutes = rs.plannedRoutes(uplink, rs.assignedUplinks(ctx, uplink), networkSet(withdrawn))
+	}
 
+	systemRoutes, err := rs.rm.ListSystemRoutes(ctx)
 	if err != nil {
-		rs.logger.Error("failed to setup routes", "error", err, "duration_ms", duration)
-		return err
+		return nil, fmt.Errorf("failed to fetch current system routes: %w", err)
+	}
+
+	existingRoutes := findMatchingRoute(systemRoutes, rs.cleanupIPSets()...)
+	staleRoutes, missingRoutes := diffRoutes(existingRoutes, plannedRoutes, vpnConnected)
+
+	report := &DriftReport{
+		Missing: len(missingRoutes),
+		Stale:   len(staleRoutes),
+	}
+	if !report.HasDrift() {
+		rs.logger.Debug("No route drift detected", "managed_routes", len(existingRoutes))
+		return report, nil
 	}
 
-	rs.logger.Debug("Routes added", "count", len(routesToAdd), "duration_ms", duration)
+	rs.logger.Info("Route drift detected",
+		"missing", report.Missing,
+		"stale", report.Stale,
+		"vpn_connected", vpnConnected)
+
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("drift repair interrupted: %w", err)
+	}
+	if rs.capabilities().Replace {
+		if err := rs.replaceRoutes(ctx, staleRoutes, missingRoutes); err != nil {
+			return report, fmt.Errorf("failed to repair routes: %w", err)
+		}
+		report.Repaired = true
+		return report, nil
+	}
+	if err := rs.cleanRoutes(ctx, staleRoutes); err != nil {
+		return report, fmt.Errorf("failed to remove stale routes: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("drift repair interrupted: %w", err)
+	}
+	if len(missingRoutes) > 0 {
+		if err := rs.addRoutes(ctx, missingRoutes); err != nil {
+			return report, fmt.Errorf("failed to restore missing routes: %w", err)
+		}
+	}
+
+	report.Repaired = true
+	return report, nil
+}
+
+// addRoutes adds all managed routes for the specified gateway
+func (rs *RouteSwitch) addRoutes(ctx context.Context, routesToAdd []*types.Route) error {
+	rs.logger.Debug("Setting up routes", "routes to setup:", len(routesToAdd))
+
+	if err := rs.runBatch(ctx, routesToAdd, rs.rm.BatchAddRoutes); err != nil {
+		return fmt.Errorf("failed to setup routes: %w", err)
+	}
 	return nil
 }
 
 // CleanRoutes removes all routes for networks defined in Chinese DNS and route files
-func (rs *RouteSwitch) cleanRoutes(routesToDelete []*types.Route) error {
-	start := time.Now()
-
+func (rs *RouteSwitch) cleanRoutes(ctx context.Context, routesToDelete []*types.Route) error {
 	rs.logger.Debug("Starting complete route cleanup", "routes to delete: ", len(routesToDelete))
 
 	if len(routesToDelete) == 0 {
@@ -145,33 +583,153 @@ func (rs *RouteSwitch) cleanRoutes(routesToDelete []*types.Route) error {
 		return nil
 	}
 
-	err := rs.rm.BatchDeleteRoutes(routesToDelete, rs.logger)
-	if err != nil {
-		rs.logger.Error("failed to delete routes", "error", err)
+	if err := rs.runBatch(ctx, routesToDelete, rs.rm.BatchDeleteRoutes); err != nil {
 		return fmt.Errorf("failed to delete routes: %w", err)
 	}
-
-	rs.logger.Debug("Routes deleted", "count", len(routesToDelete), "duration_ms", time.Since(start).Milliseconds())
 	return nil
 }
 
-func findMatchingRoute(systemRoutes []*types.Route, managedRouteSet *config.IPSet) []*types.Route {
+// capabilities returns the optional features of the route manager backend
+func (rs *RouteSwitch) capabilities() types.Capabilities {
+	if reporter, ok := rs.rm.(types.CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return types.Capabilities{}
+}
+
+// replaceRoutes installs routesToSet with replace semantics, then deletes the stale routes
+// whose destination was not overwritten by the replace
+func (rs *RouteSwitch) replaceRoutes(ctx context.Context, staleRoutes, routesToSet []*types.Route) error {
+	replacer, ok := rs.rm.(types.RouteReplacer)
+	if !ok {
+		return fmt.Errorf("route manager does not support replacing routes")
+	}
+
+	if len(routesToSet) > 0 {
+		rs.logger.Debug("Replacing routes", "routes to replace:", len(routesToSet))
+		if err := rs.runBatch(ctx, routesToSet, replacer.BatchReplaceRoutes); err != nil {
+			return fmt.Errorf("failed to replace routes: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("route replace interrupted: %w", err)
+	}
+	return rs.cleanRoutes(ctx, notReplaced(staleRoutes, routesToSet))
+}
+
+// notReplaced returns the stale routes whose destination is not among the replaced routes
+func notReplaced(staleRoutes, replaced []*types.Route) []*types.Route {
+	destinations := make(map[string]bool, len(replaced))
+	for _, route := range replaced {
+		destinations[route.Destination.String()] = true
+	}
+
+	var remaining []*types.Route
+	for _, route := range staleRoutes {
+		if !destinations[route.Destination.String()] {
+			remaining = append(remaining, route)
+		}
+	}
+	return remaining
+}
+
+// batchFunc is a RouteManager batch method such as BatchAddRoutes
+type batchFunc func(context.Context, []*types.Route, *logger.Logger) (*types.BatchResult, error)
+
+// runBatch runs a batch operation, retries the retryable failures and logs the outcome
+func (rs *RouteSwitch) runBatch(ctx context.Context, routes []*types.Route, fn batchFunc) error {
+	result, err := fn(ctx, routes, rs.logger)
+	if err != nil {
+		return err
+	}
+
+	var m *metrics.Metrics
+	if reporter, ok := rs.rm.(types.MetricsReporter); ok {
+		m = reporter.Metrics()
+	}
+	result, err = batch.RetryFailed(ctx, result, func(ctx context.Context, routes []*types.Route) (*types.BatchResult, error) {
+		return fn(ctx, routes, rs.logger)
+	}, rs.retryPolicy, rs.logger, m)
+	if err != nil {
+		return err
+	}
+
+	rs.logBatchResult(result)
+	return result.Err()
+}
+
+// logBatchResult logs the counts of a batch and one example per failure type
+func (rs *RouteSwitch) logBatchResult(result *types.BatchResult) {
+	rs.logger.BatchOperation(result.Action, result.Total, result.Succeeded, result.Skipped, result.Failed,
+		result.Duration.Milliseconds())
+
+	for errorType, failures := range result.Failures {
+		rs.logger.Warn("Batch route failures",
+			"action", result.Action,
+			"error_type", errorType.String(),
+			"count", len(failures),
+			"example", failures[0].Err)
+	}
+}
+
+func findMatchingRoute(systemRoutes []*types.Route, managedRouteSets ...*config.IPSet) []*types.Route {
 	matchingRoutes := make([]*types.Route, 0)
 	for _, route := range systemRoutes {
-		if managedRouteSet.ContainsIPNet(route.Destination) {
-			matchingRoutes = append(matchingRoutes, route)
+		for _, managedRouteSet := range managedRouteSets {
+			if managedRouteSet.ContainsIPNet(route.Destination) {
+				matchingRoutes = append(matchingRoutes, route)
+				break
+			}
 		}
 	}
 	return matchingRoutes
 }
 
-func buildRoutesFromIPSet(ipSet *config.IPSet, gateway net.IP) []*types.Route {
+// diffRoutes splits the installed managed routes into stale ones and computes the missing ones.
+// Routes to networks without a planned route are stale wherever they point, as are routes
+// through another uplink than the planned one.
+func diffRoutes(existingRoutes, plannedRoutes []*types.Route, vpnConnected bool) (stale, missing []*types.Route) {
+	if !vpnConnected {
+		return existingRoutes, nil
+	}
+
+	planned := make(map[string]*types.Route, len(plannedRoutes))
+	for _, route := range plannedRoutes {
+		planned[route.Destination.String()] = route
+	}
+
+	installed := make(map[string]bool, len(existingRoutes))
+	for _, route := range existingRoutes {
+		plan, ok := planned[route.Destination.String()]
+		if ok && routeUplink(plan).Carries(route) {
+			installed[route.Destination.String()] = true
+		} else {
+			stale = append(stale, route)
+		}
+	}
+
+	for _, route := range plannedRoutes {
+		if !installed[route.Destination.String()] {
+			missing = append(missing, route)
+		}
+	}
+
+	return stale, missing
+}
+
+// routeUplink returns the uplink a route built by Uplink.Route goes through
+func routeUplink(route *types.Route) types.Uplink {
+	return types.Uplink{Gateway: route.Gateway, Interface: route.Interface}
+}
+
+func buildRoutesFromIPSet(ipSet *config.IPSet, uplink types.Uplink, withdrawn map[string]bool) []*types.Route {
 	routes := make([]*types.Route, 0)
 	for _, network := range ipSet.IPNets() {
-		routes = append(routes, &types.Route{
-			Destination: *network,
-			Gateway:     gateway,
-		})
+		if withdrawn[network.String()] {
+			continue
+		}
+		routes = append(routes, uplink.Route(*network))
 	}
 	return routes
 }
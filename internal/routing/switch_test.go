@@ -0,0 +1,319 @@
+package routing
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/wesleywu/smart-route/internal/config"
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/batch"
+	"github.com/wesleywu/smart-route/internal/routing/platform"
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+func mustRoute(t *testing.T, cidr, gateway string) *types.Route {
+	t.Helper()
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		t.Fatalf("invalid CIDR %s: %v", cidr, err)
+	}
+	return &types.Route{Destination: *network, Gateway: net.ParseIP(gateway)}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	ipSet := config.NewIPSet()
+	for _, cidr := range []string{"1.0.1.0/24", "1.0.2.0/23", "114.114.114.114/32"} {
+		ipSet.Add(&mustRoute(t, cidr, "0.0.0.0").Destination)
+	}
+	gateway := net.ParseIP("192.168.1.1")
+
+	existing := []*types.Route{
+		mustRoute(t, "1.0.1.0/24", "192.168.1.1"),  // correct
+		mustRoute(t, "1.0.2.0/23", "192.168.32.1"), // old gateway
+	}
+
+	t.Run("vpn connected", func(t *testing.T) {
+		stale, missing := diffRoutes(existing, buildRoutesFromIPSet(ipSet, types.Uplink{Gateway: gateway}, nil), true)
+
+		if len(stale) != 1 || stale[0].Destination.String() != "1.0.2.0/23" {
+			t.Errorf("Expected 1.0.2.0/23 to be stale, got %v", stale)
+		}
+
+		got := make(map[string]bool)
+		for _, route := range missing {
+			got[route.Destination.String()] = true
+			if !route.Gateway.Equal(gateway) {
+				t.Errorf("Missing route %s should use gateway %s, got %s", route.Destination.String(), gateway, route.Gateway)
+			}
+		}
+		if len(got) != 2 || !got["1.0.2.0/23"] || !got["114.114.114.114/32"] {
+			t.Errorf("Expected 1.0.2.0/23 and 114.114.114.114/32 to be missing, got %v", got)
+		}
+	})
+
+	t.Run("gateway-less uplink", func(t *testing.T) {
+		uplink := types.Uplink{Interface: "ppp0"}
+		interfaceRoute := &types.Route{Destination: existing[0].Destination, Interface: "ppp0"}
+		stale, missing := diffRoutes([]*types.Route{interfaceRoute, existing[1]}, buildRoutesFromIPSet(ipSet, uplink, nil), true)
+
+		if len(stale) != 1 || stale[0] != existing[1] {
+			t.Errorf("Expected only the gateway route to be stale, got %v", stale)
+		}
+		if len(missing) != 2 {
+			t.Fatalf("Expected 2 missing routes, got %d", len(missing))
+		}
+		for _, route := range missing {
+			if route.Gateway != nil || route.Interface != "ppp0" {
+				t.Errorf("Missing route %s should be an interface route on ppp0, got %+v", route.Destination.String(), route)
+			}
+		}
+	})
+
+	t.Run("withdrawn networks", func(t *testing.T) {
+		withdrawn := networkSet([]net.IPNet{existing[0].Destination, mustRoute(t, "114.114.114.114/32", "0.0.0.0").Destination})
+		stale, missing := diffRoutes(existing, buildRoutesFromIPSet(ipSet, types.Uplink{Gateway: gateway}, withdrawn), true)
+
+		if len(stale) != 2 {
+			t.Errorf("Expected the withdrawn and the old gateway route to be stale, got %v", stale)
+		}
+		if len(missing) != 1 || missing[0].Destination.String() != "1.0.2.0/23" {
+			t.Errorf("Expected only 1.0.2.0/23 to be missing, got %v", missing)
+		}
+	})
+
+	t.Run("vpn disconnected", func(t *testing.T) {
+		stale, missing := diffRoutes(existing, nil, false)
+
+		if len(stale) != len(existing) {
+			t.Errorf("Expected all %d managed routes to be stale, got %d", len(existing), len(stale))
+		}
+		if len(missing) != 0 {
+			t.Errorf("Expected no missing routes, got %d", len(missing))
+		}
+	})
+}
+
+func TestSetupRoutesReplacesWithCapableBackend(t *testing.T) {
+	ctx := context.Background()
+	ipSet := config.NewIPSet()
+	for _, cidr := range []string{"1.0.1.0/24", "1.0.2.0/23"} {
+		ipSet.Add(&mustRoute(t, cidr, "0.0.0.0").Destination)
+	}
+
+	rm, err := platform.NewRouteManager("fake", config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create fake backend: %v", err)
+	}
+	for _, route := range []*types.Route{
+		mustRoute(t, "1.0.1.0/24", "192.168.32.1"), // old gateway, replaced in place
+		mustRoute(t, "1.0.2.0/23", "192.168.32.1"), // old gateway, replaced in place
+		mustRoute(t, "10.0.0.0/8", "192.168.32.1"), // not managed, left alone
+	} {
+		if err := rm.AddRoute(ctx, &route.Destination, route.Gateway, nil); err != nil {
+			t.Fatalf("Failed to seed route: %v", err)
+		}
+	}
+
+	rs, _ := NewRouteSwitch(rm, ipSet, logger.New("error"))
+	if _, err := rs.SetupRoutes(ctx, types.Uplink{Gateway: net.ParseIP("192.168.1.1")}); err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	routes, _ := rm.ListSystemRoutes(ctx)
+	if len(routes) != 3 {
+		t.Fatalf("Expected 2 managed routes and 1 unmanaged route, got %d", len(routes))
+	}
+	for _, route := range routes {
+		want := "192.168.1.1"
+		if route.Destination.String() == "10.0.0.0/8" {
+			want = "192.168.32.1"
+		}
+		if !route.Gateway.Equal(net.ParseIP(want)) {
+			t.Errorf("Expected %s via %s, got %s", route.Destination.String(), want, route.Gateway)
+		}
+	}
+}
+
+func TestSetupRoutesWithoutGateway(t *testing.T) {
+	ctx := context.Background()
+	ipSet := config.NewIPSet()
+	ipSet.Add(&mustRoute(t, "1.0.1.0/24", "0.0.0.0").Destination)
+
+	rm, err := platform.NewRouteManager("fake", config.NewConfig())
+	if err != nil {
+		t.Fatalf("Failed to create fake backend: %v", err)
+	}
+	rs, _ := NewRouteSwitch(rm, ipSet, logger.New("error"))
+
+	if _, err := rs.SetupRoutes(ctx, types.Uplink{}); err == nil {
+		t.Error("Expected an error for an uplink without gateway and interface")
+	}
+	if _, err := rs.SetupRoutes(ctx, types.Uplink{Interface: "ppp0"}); err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+
+	routes, _ := rm.ListSystemRoutes(ctx)
+	if len(routes) != 1 || routes[0].Gateway != nil || routes[0].Interface != "ppp0" {
+		t.Fatalf("Expected one interface route on ppp0, got %v", routes)
+	}
+
+	report, err := rs.Reconcile(ctx, types.Uplink{Interface: "ppp0"}, true)
+	if err != nil || report.HasDrift() {
+		t.Errorf("Expected no drift for the installed interface route, got %+v, %v", report, err)
+	}
+}
+
+// flakyRouteManager fails the first add of every route with a retryable error
+type flakyRouteManager struct {
+	*platform.FakeRouteManager
+	attempts map[string]int
+}
+
+func (rm *flakyRouteManager) BatchAddRoutes(ctx context.Context, routes []*types.Route, log *logger.Logger) (*types.BatchResult, error) {
+	var fresh, known []*types.Route
+	for _, route := range routes {
+		rm.attempts[route.Destination.String()]++
+		if rm.attempts[route.Destination.String()] == 1 {
+			fresh = append(fresh, route)
+		} else {
+			known = append(known, route)
+		}
+	}
+
+	result, err := rm.FakeRouteManager.BatchAddRoutes(ctx, known, log)
+	if err != nil {
+		return nil, err
+	}
+	outcomes := result.Outcomes
+	for _, route := range fresh {
+		outcomes = append(outcomes, types.NewRouteOutcome(route,
+			&types.RouteOperationError{ErrorType: types.RouteErrNetwork, Destination: route.Destination, Gateway: route.Gateway}, 0))
+	}
+	return types.NewBatchResult("add", outcomes, 0), nil
+}
+
+func TestSetupRoutesRetriesRetryableFailures(t *testing.T) {
+	ctx := context.Background()
+	ipSet := config.NewIPSet()
+	ipSet.Add(&mustRoute(t, "1.0.1.0/24", "0.0.0.0").Destination)
+	ipSet.Add(&mustRoute(t, "1.0.2.0/23", "0.0.0.0").Destination)
+	uplink := types.Uplink{Gateway: net.ParseIP("192.168.1.1")}
+
+	rm := &flakyRouteManager{FakeRouteManager: platform.NewFakeRouteManager(config.NewConfig()), attempts: make(map[string]int)}
+	rs, _ := NewRouteSwitch(rm, ipSet, logger.New("error"))
+	if _, err := rs.SetupRoutes(ctx, uplink); err == nil {
+		t.Fatal("Expected the failures to be reported without a retry policy")
+	}
+
+	rm = &flakyRouteManager{FakeRouteManager: platform.NewFakeRouteManager(config.NewConfig()), attempts: make(map[string]int)}
+	rs, _ = NewRouteSwitch(rm, ipSet, logger.New("error"))
+	rs.SetRetryPolicy(batch.RetryPolicy{MaxAttempts: 2})
+	if _, err := rs.SetupRoutes(ctx, uplink); err != nil {
+		t.Fatalf("Expected the retry to install the routes, got %v", err)
+	}
+	if routes, _ := rm.ListSystemRoutes(ctx); len(routes) != 2 {
+		t.Errorf("Expected 2 routes after the retry, got %v", routes)
+	}
+}
+
+func TestUplinkLists(t *testing.T) {
+	ctx := context.Background()
+	ipSet := config.NewIPSet()
+	for _, cidr := range []string{"1.0.1.0/24", "1.0.2.0/23"} {
+		ipSet.Add(&mustRoute(t, cidr, "0.0.0.0").Destination)
+	}
+	edu := config.NewIPSet()
+	for _, cidr := range []string{"1.0.2.0/23", "202.112.0.0/13"} {
+		edu.Add(&mustRoute(t, cidr, "0.0.0.0").Destination)
+	}
+
+	wired := types.Uplink{Gateway: net.ParseIP("192.168.1.1"), Interface: "eth0"}
+	second := types.Uplink{Gateway: net.ParseIP("10.0.0.1"), Interface: "eth1"}
+	rm := platform.NewFakeRouteManager(config.NewConfig())
+	rs, _ := NewRouteSwitch(rm, ipSet, logger.New("error"))
+	rs.SetUplinkAssignments([]UplinkAssignment{{Name: "edu", IPSet: edu, Interfaces: []string{"eth1"}}})
+
+	routesVia := func() map[string]string {
+		routes, _ := rm.ListSystemRoutes(ctx)
+		via := make(map[string]string, len(routes))
+		for _, route := range routes {
+			via[route.Destination.String()] = route.Gateway.String()
+		}
+		return via
+	}
+	expectRoutes := func(step string, want map[string]string) {
+		t.Helper()
+		got := routesVia()
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected routes %v, got %v", step, want, got)
+		}
+		for destination, gateway := range want {
+			if got[destination] != gateway {
+				t.Errorf("%s: expected %s via %s, got %q", step, destination, gateway, got[destination])
+			}
+		}
+	}
+
+	// The list takes precedence over the managed set
+	rm.SetPhysicalUplinks(wired, second)
+	rs.SetUplinks([]types.Uplink{wired, second})
+	if _, err := rs.SetupRoutes(ctx, wired); err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+	expectRoutes("both uplinks up", map[string]string{
+		"1.0.1.0/24":     "192.168.1.1",
+		"1.0.2.0/23":     "10.0.0.1",
+		"202.112.0.0/13": "10.0.0.1",
+	})
+
+	// The list fails over to the preferred uplink when its own goes down, and returns when it comes back
+	rs.SetUplinks([]types.Uplink{wired})
+	if _, err := rs.Reconcile(ctx, wired, true); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	expectRoutes("second uplink down", map[string]string{
+		"1.0.1.0/24":     "192.168.1.1",
+		"1.0.2.0/23":     "192.168.1.1",
+		"202.112.0.0/13": "192.168.1.1",
+	})
+
+	rs.SetUplinks([]types.Uplink{wired, second})
+	report, err := rs.Reconcile(ctx, wired, true)
+	if err != nil || report.Stale != 2 {
+		t.Fatalf("Expected 2 stale routes after the second uplink came back, got %+v, %v", report, err)
+	}
+	expectRoutes("second uplink back", map[string]string{
+		"1.0.1.0/24":     "192.168.1.1",
+		"1.0.2.0/23":     "10.0.0.1",
+		"202.112.0.0/13": "10.0.0.1",
+	})
+
+	// Disabled lists are cleaned up like any managed route
+	rs.SetUplinkListsEnabled(false)
+	if _, err := rs.SetupRoutes(ctx, wired); err != nil {
+		t.Fatalf("SetupRoutes failed: %v", err)
+	}
+	expectRoutes("lists disabled", map[string]string{
+		"1.0.1.0/24": "192.168.1.1",
+		"1.0.2.0/23": "192.168.1.1",
+	})
+	if _, err := rs.CleanRoutes(ctx); err != nil {
+		t.Fatalf("CleanRoutes failed: %v", err)
+	}
+	expectRoutes("cleaned", nil)
+}
+
+func TestOrderUplinks(t *testing.T) {
+	wifi := types.Uplink{Gateway: net.ParseIP("192.168.1.1"), Interface: "wlan0"}
+	wired := types.Uplink{Gateway: net.ParseIP("192.168.2.1"), Interface: "eth0"}
+	lte := types.Uplink{Interface: "wwan0"}
+
+	ordered := types.OrderUplinks([]types.Uplink{wifi, lte, wired}, []string{"eth0", "wlan0"})
+	if !types.SameUplinks(ordered, []types.Uplink{wired, wifi, lte}) {
+		t.Errorf("Expected eth0, wlan0, wwan0, got %v", ordered)
+	}
+	if unordered := types.OrderUplinks([]types.Uplink{wifi, lte}, nil); !types.SameUplinks(unordered, []types.Uplink{wifi, lte}) {
+		t.Errorf("Expected the order to be kept without priorities, got %v", unordered)
+	}
+}
@@ -0,0 +1,243 @@
+package routing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/wesleywu/smart-route/internal/routing/types"
+)
+
+// Trace entry kinds
+const (
+	TraceKindInit    = "init"    // State of the monitor when recording started
+	TraceKindTrigger = "trigger" // A source reported that the network may have changed
+	TraceKindCheck   = "check"   // The route manager was queried for the current state
+	TraceKindObserve = "observe" // A source reported a state directly
+	TraceKindEvent   = "event"   // The monitor published an event
+	TraceKindApply   = "apply"   // The daemon applied a desired state
+)
+
+// TraceEntry is one line of a trace
+type TraceEntry struct {
+	Offset        time.Duration `json:"offset"` // Since recording started
+	Kind          string        `json:"kind"`
+	Source        string        `json:"source,omitempty"`
+	State         *NetworkState `json:"state,omitempty"`
+	PhysicalError string        `json:"physical_error,omitempty"` // GetPhysicalGateway failed during a check
+	DefaultError  string        `json:"default_error,omitempty"`  // GetSystemDefaultRoute failed during a check
+	Duration      time.Duration `json:"duration,omitempty"`       // Time the check or apply took
+	Event         *TraceEvent   `json:"event,omitempty"`
+	Apply         *TraceApply   `json:"apply,omitempty"`
+}
+
+// TraceEvent is a published network event
+type TraceEvent struct {
+	Type              string `json:"type"`
+	VPNConnected      bool   `json:"vpn_connected"`
+	PhysicalGateway   net.IP `json:"physical_gateway,omitempty"`
+	PhysicalInterface string `json:"physical_interface,omitempty"`
+	VPNInterface      string `json:"vpn_interface,omitempty"`
+}
+
+// String formats the event for comparing a replay with its recording
+func (e TraceEvent) String() string {
+	return fmt.Sprintf("event %s vpn=%t physical=%s via %s vpn_interface=%s",
+		e.Type, e.VPNConnected, e.PhysicalInterface, e.PhysicalGateway, e.VPNInterface)
+}
+
+// TraceApply is an apply decision of the daemon and its outcome
+type TraceApply struct {
+	Reason            string `json:"reason"`
+	VerifyOnly        bool   `json:"verify,omitempty"`
+	VPNConnected      bool   `json:"vpn_connected"`
+	PhysicalGateway   net.IP `json:"physical_gateway,omitempty"`
+	PhysicalInterface string `json:"physical_interface,omitempty"`
+	VPNInterface      string `json:"vpn_interface,omitempty"`
+	RoutesAdded       int    `json:"routes_added,omitempty"`
+	RoutesRemoved     int    `json:"routes_removed,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// String formats the decision for comparing a replay with its recording; the outcome depends on the
+// routing table and is left out
+func (a TraceApply) String() string {
+	return fmt.Sprintf("apply %s verify=%t vpn=%t physical=%s via %s vpn_interface=%s",
+		a.Reason, a.VerifyOnly, a.VPNConnected, a.PhysicalInterface, a.PhysicalGateway, a.VPNInterface)
+}
+
+// TraceRecorder writes trace entries as JSON lines. A nil recorder records nothing.
+type TraceRecorder struct {
+	mutex   sync.Mutex
+	start   time.Time
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewTraceRecorder creates a recorder writing to w
+func NewTraceRecorder(w io.Writer) *TraceRecorder {
+	writer := bufio.NewWriter(w)
+	return &TraceRecorder{
+		start:   time.Now(),
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// CreateTraceRecorder creates a recorder writing to a new file at path
+func CreateTraceRecorder(path string) (*TraceRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %w", err)
+	}
+	r := NewTraceRecorder(f)
+	r.closer = f
+	return r, nil
+}
+
+// Record writes an entry, setting its offset. Entries are flushed right away so that a trace
+// survives a crash, which is when it is needed most.
+func (r *TraceRecorder) Record(entry TraceEntry) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entry.Offset = time.Since(r.start)
+	if err := r.encoder.Encode(entry); err == nil {
+		r.writer.Flush()
+	}
+}
+
+// RecordApply records an apply decision of the daemon
+func (r *TraceRecorder) RecordApply(apply TraceApply, duration time.Duration) {
+	r.Record(TraceEntry{Kind: TraceKindApply, Apply: &apply, Duration: duration})
+}
+
+// Close flushes the trace and closes the file it was created with
+func (r *TraceRecorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		return err
+	}
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// ReadTrace reads the entries of a trace
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid trace entry on line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// StateSetter is updated with the replayed routing state, normally a fake route manager,
+// so that code querying the route manager during a replay sees the recorded state
+type StateSetter interface {
+	SetPhysicalGateway(gateway net.IP, iface string)
+	SetDefaultRoute(gateway net.IP, iface string)
+}
+
+// ReplaySource feeds the checks and observations of a trace to the monitor at their recorded
+// offsets. Check results, including failed queries, are applied as if the monitor had queried
+// the route manager itself; triggers are skipped as the check that followed them is replayed.
+type ReplaySource struct {
+	Entries []TraceEntry
+	Router  StateSetter // optional
+	// Done is closed once every entry has been replayed, if set
+	Done chan struct{}
+}
+
+// Name implements EventSource
+func (s *ReplaySource) Name() string {
+	return "replay"
+}
+
+// Run implements EventSource
+func (s *ReplaySource) Run(ctx context.Context, sink EventSink) error {
+	start := time.Now()
+	for _, entry := range s.Entries {
+		if entry.State == nil || (entry.Kind != TraceKindCheck && entry.Kind != TraceKindObserve) {
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(start.Add(entry.Offset)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		physicalKnown := entry.PhysicalError == ""
+		defaultKnown := entry.DefaultError == ""
+		s.setState(*entry.State, physicalKnown, defaultKnown)
+
+		monitor, ok := sink.(*NetworkMonitor)
+		switch {
+		case entry.Kind == TraceKindCheck && ok:
+			monitor.observe(observation{source: s.Name(), state: *entry.State, physicalKnown: physicalKnown, defaultKnown: defaultKnown})
+		case physicalKnown && defaultKnown:
+			sink.Observe(s.Name(), *entry.State)
+		}
+	}
+	if s.Done != nil {
+		close(s.Done)
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// SetReplayedUplinks updates router with the physical uplinks of a replayed state, all of them
+// if they were recorded and the router takes them
+func SetReplayedUplinks(router StateSetter, state NetworkState) {
+	if setter, ok := router.(UplinkSetter); ok && state.Uplinks != nil {
+		setter.SetPhysicalUplinks(state.Uplinks...)
+		return
+	}
+	router.SetPhysicalGateway(state.PhysicalGateway, state.PhysicalInterface)
+}
+
+// UplinkSetter is implemented by state setters that also take every physical uplink
+type UplinkSetter interface {
+	SetPhysicalUplinks(uplinks ...types.Uplink)
+}
+
+// setState updates the router with the known parts of a replayed state
+func (s *ReplaySource) setState(state NetworkState, physicalKnown, defaultKnown bool) {
+	if s.Router == nil {
+		return
+	}
+	if physicalKnown {
+		SetReplayedUplinks(s.Router, state)
+	}
+	if defaultKnown {
+		s.Router.SetDefaultRoute(state.DefaultGateway, state.DefaultInterface)
+	}
+}
@@ -0,0 +1,46 @@
+package routing
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTraceRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewTraceRecorder(&buf)
+	state := &NetworkState{PhysicalGateway: net.ParseIP("192.168.1.1"), PhysicalInterface: "en0", DefaultInterface: "utun3"}
+	recorder.Record(TraceEntry{Kind: TraceKindCheck, State: state, DefaultError: "no route", Duration: time.Millisecond})
+	recorder.RecordApply(TraceApply{Reason: "vpn_connected", VPNConnected: true, RoutesAdded: 3}, time.Second)
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A nil recorder is a no-op
+	var disabled *TraceRecorder
+	disabled.Record(TraceEntry{Kind: TraceKindTrigger})
+	if err := disabled.Close(); err != nil {
+		t.Fatalf("Close of a nil recorder failed: %v", err)
+	}
+
+	entries, err := ReadTrace(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrace failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	check := entries[0]
+	if check.Kind != TraceKindCheck || !check.State.PhysicalGateway.Equal(state.PhysicalGateway) || check.DefaultError != "no route" {
+		t.Errorf("Unexpected check entry %+v", check)
+	}
+	apply := entries[1]
+	if apply.Apply == nil || apply.Apply.RoutesAdded != 3 || apply.Duration != time.Second || apply.Offset < check.Offset {
+		t.Errorf("Unexpected apply entry %+v", apply)
+	}
+
+	if _, err := ReadTrace(bytes.NewBufferString("{\"kind\":\"check\"}\nnot json\n")); err == nil {
+		t.Error("Expected an invalid line to be rejected")
+	}
+}
@@ -0,0 +1,171 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RouteOutcomeStatus is the final status of a single route within a batch
+type RouteOutcomeStatus int
+
+// Route outcome status constants
+const (
+	// OutcomeSucceeded indicates the route operation took effect
+	OutcomeSucceeded RouteOutcomeStatus = iota
+	// OutcomeSkipped indicates there was nothing to do (e.g. deleting a route that is already gone)
+	OutcomeSkipped
+	// OutcomeFailed indicates the route operation failed
+	OutcomeFailed
+)
+
+// String returns a string representation of the outcome status
+func (s RouteOutcomeStatus) String() string {
+	switch s {
+	case OutcomeSucceeded:
+		return "Succeeded"
+	case OutcomeSkipped:
+		return "Skipped"
+	case OutcomeFailed:
+		return "Failed"
+	default:
+		return "Unknown"
+	}
+}
+
+// RouteOutcome is the result of a single route operation within a batch
+type RouteOutcome struct {
+	Route     *Route
+	Status    RouteOutcomeStatus
+	ErrorType RouteErrorType // Only meaningful when Err is not nil
+	Err       error
+	Duration  time.Duration
+}
+
+// Retryable returns true if the outcome is a failure that isRetryable considers worth another attempt
+func (ro *RouteOutcome) Retryable(isRetryable func(error) bool) bool {
+	return ro.Status == OutcomeFailed && isRetryable(ro.Err)
+}
+
+// NewRouteOutcome classifies the error returned by a route operation into an outcome
+func NewRouteOutcome(route *Route, err error, duration time.Duration) RouteOutcome {
+	outcome := RouteOutcome{
+		Route:    route,
+		Status:   OutcomeSucceeded,
+		Err:      err,
+		Duration: duration,
+	}
+	if err == nil {
+		return outcome
+	}
+
+	outcome.Status = OutcomeFailed
+	outcome.ErrorType = RouteErrSystemCall
+
+	var routeErr *RouteOperationError
+	if errors.As(err, &routeErr) {
+		outcome.ErrorType = routeErr.ErrorType
+		if routeErr.ErrorType == RouteErrNotFound {
+			outcome.Status = OutcomeSkipped
+		}
+	}
+	return outcome
+}
+
+// BatchResult collects the per-route outcomes of a batch operation
+type BatchResult struct {
+	Action    string // "add" or "delete"
+	Total     int
+	Succeeded int
+	Skipped   int
+	Failed    int
+	Duration  time.Duration
+	Outcomes  []RouteOutcome
+	// Failures groups the failed outcomes by error type
+	Failures map[RouteErrorType][]RouteOutcome
+}
+
+// NewBatchResult builds a batch result from individual outcomes
+func NewBatchResult(action string, outcomes []RouteOutcome, duration time.Duration) *BatchResult {
+	br := &BatchResult{
+		Action:   action,
+		Duration: duration,
+		Failures: make(map[RouteErrorType][]RouteOutcome),
+	}
+	br.add(outcomes)
+	return br
+}
+
+// add accounts for the given outcomes
+func (br *BatchResult) add(outcomes []RouteOutcome) {
+	for _, outcome := range outcomes {
+		br.Total++
+		switch outcome.Status {
+		case OutcomeSucceeded:
+			br.Succeeded++
+		case OutcomeSkipped:
+			br.Skipped++
+		case OutcomeFailed:
+			br.Failed++
+			br.Failures[outcome.ErrorType] = append(br.Failures[outcome.ErrorType], outcome)
+		}
+	}
+	br.Outcomes = append(br.Outcomes, outcomes...)
+}
+
+// RetryableRoutes returns the routes whose failures isRetryable considers worth another attempt
+func (br *BatchResult) RetryableRoutes(isRetryable func(error) bool) []*Route {
+	var routes []*Route
+	for i := range br.Outcomes {
+		if br.Outcomes[i].Retryable(isRetryable) {
+			routes = append(routes, br.Outcomes[i].Route)
+		}
+	}
+	return routes
+}
+
+// Merge replaces the outcomes of retried routes with the outcomes from a retry batch
+func (br *BatchResult) Merge(retry *BatchResult) {
+	if retry == nil || len(retry.Outcomes) == 0 {
+		return
+	}
+
+	retried := make(map[*Route]bool, len(retry.Outcomes))
+	for _, outcome := range retry.Outcomes {
+		retried[outcome.Route] = true
+	}
+
+	kept := make([]RouteOutcome, 0, len(br.Outcomes))
+	for _, outcome := range br.Outcomes {
+		if !retried[outcome.Route] {
+			kept = append(kept, outcome)
+		}
+	}
+
+	duration := br.Duration + retry.Duration
+	*br = *NewBatchResult(br.Action, kept, duration)
+	br.add(retry.Outcomes)
+}
+
+// Err returns an error summarizing the failures, or nil if no route failed
+func (br *BatchResult) Err() error {
+	if br.Failed == 0 {
+		return nil
+	}
+
+	errorTypes := make([]RouteErrorType, 0, len(br.Failures))
+	for errorType := range br.Failures {
+		errorTypes = append(errorTypes, errorType)
+	}
+	sort.Slice(errorTypes, func(i, j int) bool { return errorTypes[i] < errorTypes[j] })
+
+	parts := make([]string, 0, len(errorTypes))
+	for _, errorType := range errorTypes {
+		parts = append(parts, fmt.Sprintf("%s=%d", errorType.String(), len(br.Failures[errorType])))
+	}
+
+	return fmt.Errorf("batch %s failed for %d of %d routes (%s): %w",
+		br.Action, br.Failed, br.Total, strings.Join(parts, ", "), br.Failures[errorTypes[0]][0].Err)
+}
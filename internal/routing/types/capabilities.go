@@ -0,0 +1,59 @@
+package types
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wesleywu/smart-route/internal/logger"
+	"github.com/wesleywu/smart-route/internal/routing/metrics"
+)
+
+// Capabilities describes the optional features of a RouteManager backend
+type Capabilities struct {
+	Replace         bool // Atomically replace an existing route for the same destination
+	Tables          bool // Program routes into routing tables other than the main one
+	IPv6            bool // Program IPv6 routes
+	ProtocolTagging bool // Tag routes with a protocol so ours can be told apart from others
+}
+
+// List returns the names of the supported capabilities
+func (c Capabilities) List() []string {
+	var names []string
+	if c.Replace {
+		names = append(names, "replace")
+	}
+	if c.Tables {
+		names = append(names, "tables")
+	}
+	if c.IPv6 {
+		names = append(names, "ipv6")
+	}
+	if c.ProtocolTagging {
+		names = append(names, "protocol")
+	}
+	return names
+}
+
+// String returns the supported capabilities as a comma separated list
+func (c Capabilities) String() string {
+	if names := c.List(); len(names) > 0 {
+		return strings.Join(names, ",")
+	}
+	return "none"
+}
+
+// CapabilityReporter is implemented by route managers that know which backend they run on
+type CapabilityReporter interface {
+	Backend() string
+	Capabilities() Capabilities
+}
+
+// MetricsReporter is implemented by route managers that keep route operation metrics
+type MetricsReporter interface {
+	Metrics() *metrics.Metrics
+}
+
+// RouteReplacer is implemented by backends with the Replace capability
+type RouteReplacer interface {
+	BatchReplaceRoutes(ctx context.Context, routes []*Route, logger *logger.Logger) (*BatchResult, error)
+}
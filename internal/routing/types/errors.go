@@ -1,8 +1,11 @@
 package types
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"syscall"
 )
 
 // RouteOperationError represents an error that occurred during route operations
@@ -30,6 +33,12 @@ const (
 	RouteErrTimeout
 	// RouteErrNotFound indicates route not found in system table
 	RouteErrNotFound
+	// RouteErrCanceled indicates the operation was abandoned because its context was cancelled
+	RouteErrCanceled
+	// RouteErrExists indicates a conflicting route for the destination is already installed
+	RouteErrExists
+	// RouteErrGatewayUnreachable indicates the gateway is not reachable on any attached network
+	RouteErrGatewayUnreachable
 )
 
 // String returns a string representation of the route error type
@@ -47,6 +56,12 @@ func (e RouteErrorType) String() string {
 		return "Timeout"
 	case RouteErrNotFound:
 		return "NotFound"
+	case RouteErrCanceled:
+		return "Canceled"
+	case RouteErrExists:
+		return "Exists"
+	case RouteErrGatewayUnreachable:
+		return "GatewayUnreachable"
 	default:
 		return "UnknownError"
 	}
@@ -61,12 +76,43 @@ func (roe *RouteOperationError) Error() string {
 		roe.Cause)
 }
 
+// Unwrap returns the underlying error so errors.Is can match the cause
+func (roe *RouteOperationError) Unwrap() error {
+	return roe.Cause
+}
+
 // IsRetryable returns true if the error condition might be temporary
 func (roe *RouteOperationError) IsRetryable() bool {
-	return roe.ErrorType == RouteErrNetwork || roe.ErrorType == RouteErrTimeout
+	return roe.ErrorType == RouteErrNetwork || roe.ErrorType == RouteErrTimeout ||
+		roe.ErrorType == RouteErrGatewayUnreachable
 }
 
 // IsPermissionError returns true if the error is due to insufficient privileges
 func (roe *RouteOperationError) IsPermissionError() bool {
 	return roe.ErrorType == RouteErrPermission
-}
\ No newline at end of file
+}
+
+// IsThrottled returns true if err means the kernel is temporarily out of resources (ENOBUFS, EAGAIN)
+// and the caller should slow down rather than give up
+func IsThrottled(err error) bool {
+	return errors.Is(err, syscall.ENOBUFS) || errors.Is(err, syscall.EAGAIN)
+}
+
+// NewContextError converts a context error into a RouteOperationError.
+// An expired deadline is reported as RouteErrTimeout, a cancellation as RouteErrCanceled.
+func NewContextError(err error, destination *net.IPNet, gateway net.IP) *RouteOperationError {
+	errorType := RouteErrCanceled
+	if errors.Is(err, context.DeadlineExceeded) {
+		errorType = RouteErrTimeout
+	}
+
+	roe := &RouteOperationError{
+		ErrorType: errorType,
+		Gateway:   gateway,
+		Cause:     err,
+	}
+	if destination != nil {
+		roe.Destination = *destination
+	}
+	return roe
+}
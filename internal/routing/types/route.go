@@ -9,6 +9,62 @@ type Route struct {
 	Destination net.IPNet // Destination network
 	Gateway     net.IP    // Gateway IP address
 	Metric      int       // Route metric/priority
+	Interface   string    // Outgoing interface name, empty if unknown
+	Flags       uint32    // Kernel route flags (RTF_*), platform specific
+	Protocol    string    // What installed the route (RouteProtocol*), empty if unknown
+	Table       int       // Routing table ID, 0 on platforms with a single table
+}
+
+// Route protocols, i.e. what installed a route
+const (
+	RouteProtocolKernel   = "kernel"   // Added by the kernel, e.g. for a connected network
+	RouteProtocolStatic   = "static"   // Added by an administrator or a program such as this one
+	RouteProtocolRedirect = "redirect" // Learned from an ICMP redirect
+	RouteProtocolRA       = "ra"       // Learned from an IPv6 router advertisement
+)
+
+// RouteOptions holds the optional attributes of a route being added or deleted
+type RouteOptions struct {
+	Metric    int    // Route metric, 0 for the platform default
+	Interface string // Outgoing interface, empty to let the kernel choose
+}
+
+// RouteOption sets an optional route attribute
+type RouteOption func(*RouteOptions)
+
+// WithMetric sets the route metric
+func WithMetric(metric int) RouteOption {
+	return func(o *RouteOptions) {
+		o.Metric = metric
+	}
+}
+
+// WithInterface scopes the route to an interface, e.g. the physical interface
+func WithInterface(name string) RouteOption {
+	return func(o *RouteOptions) {
+		o.Interface = name
+	}
+}
+
+// NewRouteOptions applies opts to empty RouteOptions
+func NewRouteOptions(opts ...RouteOption) RouteOptions {
+	var options RouteOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// Options returns the optional attributes set on the route, for passing to AddRoute and DeleteRoute
+func (r *Route) Options() []RouteOption {
+	var opts []RouteOption
+	if r.Metric != 0 {
+		opts = append(opts, WithMetric(r.Metric))
+	}
+	if r.Interface != "" {
+		opts = append(opts, WithInterface(r.Interface))
+	}
+	return opts
 }
 
 // RouteAction represents the type of operation to be performed on a route
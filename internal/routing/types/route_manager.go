@@ -1,28 +1,32 @@
 package types
 
 import (
+	"context"
 	"net"
 
 	"github.com/wesleywu/smart-route/internal/logger"
 )
 
-// RouteManager defines the interface for system routing table management
+// RouteManager defines the interface for system routing table management.
+// Every operation honours the context; implementations additionally bound each
+// operation by the configured route timeout and report expiry as RouteErrTimeout.
 type RouteManager interface {
-	// Single route operations
-	AddRoute(destination *net.IPNet, gateway net.IP, logger *logger.Logger) error
-	DeleteRoute(destination *net.IPNet, gateway net.IP, logger *logger.Logger) error
+	// Single route operations; opts optionally set the metric and scope the route to an interface
+	AddRoute(ctx context.Context, destination *net.IPNet, gateway net.IP, logger *logger.Logger, opts ...RouteOption) error
+	DeleteRoute(ctx context.Context, destination *net.IPNet, gateway net.IP, logger *logger.Logger, opts ...RouteOption) error
 
-	// Batch route operations for performance
-	BatchAddRoutes(routes []*Route, logger *logger.Logger) error
-	BatchDeleteRoutes(routes []*Route, logger *logger.Logger) error
+	// Batch route operations for performance, processed in chunks that can be cancelled in between
+	BatchAddRoutes(ctx context.Context, routes []*Route, logger *logger.Logger) (*BatchResult, error)
+	BatchDeleteRoutes(ctx context.Context, routes []*Route, logger *logger.Logger) (*BatchResult, error)
 
-	// GetPhysicalGateway returns the underlying physical network gateway (for route management)
-	GetPhysicalGateway() (gateway net.IP, interfaceName string, err error)
+	// GetPhysicalGateway returns the underlying physical network gateway (for route management).
+	// A gateway-less point-to-point uplink is reported as a nil gateway with the interface name.
+	GetPhysicalGateway(ctx context.Context) (gateway net.IP, interfaceName string, err error)
 	// GetSystemDefaultRoute returns the current system default route (may include VPN)
-	GetSystemDefaultRoute() (gateway net.IP, interfaceName string, err error)
+	GetSystemDefaultRoute(ctx context.Context) (gateway net.IP, interfaceName string, err error)
 
 	// Route table query operations
-	ListSystemRoutes() (routes []*Route, err error)
+	ListSystemRoutes(ctx context.Context) (routes []*Route, err error)
 
 	// Resource management
 	Close() error
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Uplink is the path managed routes take out of the physical network. Point-to-point
+// links such as PPPoE, tethered phones and some LTE modems have no next-hop address,
+// so Gateway is nil and traffic is sent straight out of Interface.
+type Uplink struct {
+	Gateway   net.IP `json:"gateway,omitempty"` // Next hop, nil for a gateway-less uplink
+	Interface string `json:"interface"`         // Outgoing interface, required when Gateway is nil
+}
+
+// HasGateway reports whether routes go via a next-hop address rather than only an interface
+func (u Uplink) HasGateway() bool {
+	return u.Gateway != nil
+}
+
+// Validate returns an error if the uplink has neither a gateway nor an interface
+func (u Uplink) Validate() error {
+	if u.Gateway == nil && u.Interface == "" {
+		return fmt.Errorf("uplink needs a gateway or an interface")
+	}
+	return nil
+}
+
+// Equal reports whether two uplinks route traffic the same way
+func (u Uplink) Equal(other Uplink) bool {
+	return u.Gateway.Equal(other.Gateway) && u.Interface == other.Interface
+}
+
+// Route builds the route to destination through the uplink. Gateway routes are left
+// unscoped; gateway-less routes are bound to the interface.
+func (u Uplink) Route(destination net.IPNet) *Route {
+	route := &Route{Destination: destination, Gateway: u.Gateway}
+	if !u.HasGateway() {
+		route.Interface = u.Interface
+	}
+	return route
+}
+
+// Carries reports whether an installed route sends traffic through the uplink
+func (u Uplink) Carries(route *Route) bool {
+	if u.HasGateway() {
+		return u.Gateway.Equal(route.Gateway)
+	}
+	return route.Gateway == nil && route.Interface == u.Interface
+}
+
+// String returns the gateway, or "dev <interface>" for a gateway-less uplink
+func (u Uplink) String() string {
+	if u.HasGateway() {
+		return u.Gateway.String()
+	}
+	return "dev " + u.Interface
+}
+
+// UplinkLister is implemented by route managers that can enumerate every active physical uplink,
+// e.g. Ethernet and Wi-Fi that are both up
+type UplinkLister interface {
+	// ListPhysicalUplinks returns the active physical uplinks, most preferred first;
+	// GetPhysicalGateway reports the first of them
+	ListPhysicalUplinks(ctx context.Context) ([]Uplink, error)
+}
+
+// OrderUplinks sorts uplinks by the position of their interface in priority. Uplinks whose
+// interface is not listed follow the listed ones in their original order.
+func OrderUplinks(uplinks []Uplink, priority []string) []Uplink {
+	rank := make(map[string]int, len(priority))
+	for i, iface := range priority {
+		if _, ok := rank[iface]; !ok {
+			rank[iface] = i
+		}
+	}
+	position := func(uplink Uplink) int {
+		if i, ok := rank[uplink.Interface]; ok {
+			return i
+		}
+		return len(priority)
+	}
+
+	ordered := append([]Uplink(nil), uplinks...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return position(ordered[i]) < position(ordered[j])
+	})
+	return ordered
+}
+
+// SameUplinks reports whether a and b hold the same uplinks in the same order
+func SameUplinks(a, b []Uplink) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,14 +1,16 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
 	"strings"
 )
 
-// GetPhysicalGatewayBSD gets the physical gateway for macOS/BSD/Linux systems
-func GetPhysicalGatewayBSD() (net.IP, string, error) {
+// GetPhysicalGatewayBSD gets the physical gateway for macOS/BSD/Linux systems.
+// For a gateway-less point-to-point uplink the gateway is nil and only the interface is returned.
+func GetPhysicalGatewayBSD(ctx context.Context) (net.IP, string, error) {
 	// Strategy 1: First try to get gateway from active network interface (most reliable for detecting changes)
 	gateway, iface, err := GetGatewayFromInterfaces()
 	if err == nil {
@@ -16,7 +18,7 @@ func GetPhysicalGatewayBSD() (net.IP, string, error) {
 	}
 
 	// Strategy 2: If interface method fails, fall back to route table analysis
-	cmd := exec.Command("netstat", "-rn")
+	cmd := exec.CommandContext(ctx, "netstat", "-rn")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to get routing table: %w", err)
@@ -70,7 +72,8 @@ func GetPhysicalGatewayBSD() (net.IP, string, error) {
 	return nil, "", fmt.Errorf("no physical gateway found")
 }
 
-// GetGatewayFromInterfaces gets the gateway from interfaces
+// GetGatewayFromInterfaces gets the gateway from interfaces. A point-to-point physical
+// interface is returned with a nil gateway.
 func GetGatewayFromInterfaces() (net.IP, string, error) {
 	// Get active physical interfaces
 	ifaces, err := net.Interfaces()
@@ -86,6 +89,11 @@ func GetGatewayFromInterfaces() (net.IP, string, error) {
 				continue
 			}
 
+			// A point-to-point uplink has no next hop; traffic goes straight out of the interface
+			if iface.Flags&net.FlagPointToPoint != 0 && hasIPv4(addrs) {
+				return nil, iface.Name, nil
+			}
+
 			// Check if this interface has a valid IP
 			for _, addr := range addrs {
 				if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
@@ -105,6 +113,16 @@ func GetGatewayFromInterfaces() (net.IP, string, error) {
 	return nil, "", fmt.Errorf("no physical gateway found")
 }
 
+// hasIPv4 reports whether any of the interface addresses is IPv4
+func hasIPv4(addrs []net.Addr) bool {
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // calculateGatewayFromSubnet calculates the gateway from the subnet
 func calculateGatewayFromSubnet(ipnet *net.IPNet) net.IP {
 	ip := ipnet.IP.To4()
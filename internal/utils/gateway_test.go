@@ -1,9 +1,12 @@
 package utils
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestGetPhysicalGatewayBSD(t *testing.T) {
-	gateway, iface, err := GetPhysicalGatewayBSD()
+	gateway, iface, err := GetPhysicalGatewayBSD(context.Background())
 	if err != nil {
 		t.Fatalf("failed to get physical gateway: %v", err)
 	}
@@ -1,9 +1,21 @@
 package utils
 
-import "strings"
+import (
+	"runtime"
+	"strings"
+)
+
+// pppIsVPN tells whether ppp interfaces are VPN tunnels on this platform. On macOS they are the
+// L2TP and PPTP VPNs; elsewhere they are usually PPPoE or dial-up uplinks.
+var pppIsVPN = runtime.GOOS == "darwin"
 
 // IsVPNInterface checks if the given interface name is a VPN interface
 func IsVPNInterface(interfaceName string) bool {
+	return isVPNInterface(interfaceName, pppIsVPN)
+}
+
+// isVPNInterface checks if the given interface name is a VPN interface, counting ppp interfaces if pppVPN is set
+func isVPNInterface(interfaceName string, pppVPN bool) bool {
 	// Common VPN interface patterns
 	if len(interfaceName) >= 4 {
 		prefix := interfaceName[:4]
@@ -17,8 +29,10 @@ func IsVPNInterface(interfaceName string) bool {
 	if len(interfaceName) >= 3 {
 		prefix := interfaceName[:3]
 		switch prefix {
-		case "tun", "tap", "ppp":
+		case "tun", "tap":
 			return true
+		case "ppp":
+			return pppVPN
 		}
 	}
 
@@ -27,16 +41,32 @@ func IsVPNInterface(interfaceName string) bool {
 
 // IsPhysicalInterface checks if the interface is a physical interface
 func IsPhysicalInterface(iface string) bool {
+	return isPhysicalInterface(iface, pppIsVPN)
+}
+
+// isPhysicalInterface checks if the interface is a physical interface, leaving out ppp interfaces if pppVPN is set
+func isPhysicalInterface(iface string, pppVPN bool) bool {
 	// Physical interfaces: en0, en1, eth0, eth1, etc.
-	// Skip VPN: utun, tun, tap, ppp, ipsec, etc.
+	// Skip VPN: utun, tun, tap, ipsec, wg, and ppp where it is a VPN, etc.
 	// Skip system: lo, awdl, bridge, etc.
 
 	if strings.HasPrefix(iface, "en") || strings.HasPrefix(iface, "eth") {
 		return true
 	}
+	if pppVPN && strings.HasPrefix(iface, "ppp") {
+		return false
+	}
+
+	// Cellular modems, PPPoE or dial-up links and USB tethering, often point-to-point links without a gateway
+	uplinkPrefixes := []string{"wwan", "wwp", "rmnet", "ppp", "usb", "rndis"}
+	for _, prefix := range uplinkPrefixes {
+		if strings.HasPrefix(iface, prefix) {
+			return true
+		}
+	}
 
 	// Skip VPN interfaces
-	vpnPrefixes := []string{"utun", "tun", "tap", "ppp", "ipsec", "wg"}
+	vpnPrefixes := []string{"utun", "tun", "tap", "ipsec", "wg"}
 	for _, prefix := range vpnPrefixes {
 		if strings.HasPrefix(iface, prefix) {
 			return false
@@ -0,0 +1,37 @@
+package utils
+
+import "testing"
+
+func TestInterfaceClassification(t *testing.T) {
+	tests := []struct {
+		iface    string
+		pppVPN   bool // ppp interfaces are VPNs, as on macOS
+		vpn      bool
+		physical bool
+	}{
+		{"eth0", false, false, true},
+		{"en0", true, false, true},
+		{"wwan0", false, false, true},
+		{"ppp0", false, false, true},      // PPPoE or dial-up uplink
+		{"pppoe-wan", false, false, true}, // OpenWrt PPPoE
+		{"ppp0", true, true, false},       // macOS L2TP or PPTP VPN
+		{"usb0", false, false, true},      // USB tethering
+		{"rndis0", false, false, true},    // USB tethering
+		{"utun3", true, true, false},
+		{"tun0", false, true, false},
+		{"tap1", false, true, false},
+		{"wg0", false, false, false},
+		{"lo0", true, false, false},
+		{"bridge100", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.iface, func(t *testing.T) {
+			if got := isVPNInterface(tt.iface, tt.pppVPN); got != tt.vpn {
+				t.Errorf("isVPNInterface(%q, %t) = %t, want %t", tt.iface, tt.pppVPN, got, tt.vpn)
+			}
+			if got := isPhysicalInterface(tt.iface, tt.pppVPN); got != tt.physical {
+				t.Errorf("isPhysicalInterface(%q, %t) = %t, want %t", tt.iface, tt.pppVPN, got, tt.physical)
+			}
+		})
+	}
+}